@@ -3,3 +3,7 @@ module frontend
 go 1.21
 
 require github.com/gorilla/websocket v1.5.3
+
+require tlsserver v0.0.0
+
+replace tlsserver => ../tlsserver
@@ -2,12 +2,12 @@
  * HTTP Client for Backend API
  */
 
-import config from '../config.js';
+import appConfig from '../config.js';
 
 class APIClient {
     constructor(baseURL = '') {
         // Use relative URLs (will work with Nginx proxy)
-        this.baseURL = baseURL || config.apiBaseURL;
+        this.baseURL = baseURL || appConfig.apiBaseURL;
     }
 
     async request(endpoint, options = {}) {
@@ -27,6 +27,15 @@ class APIClient {
             delete config.headers['Content-Type'];
         }
 
+        // State-changing requests repeat the session's CSRF token from its cookie
+        const method = (options.method || 'GET').toUpperCase();
+        if (!['GET', 'HEAD', 'OPTIONS'].includes(method)) {
+            const csrfToken = this.getCSRFToken();
+            if (csrfToken) {
+                config.headers['X-CSRF-Token'] = csrfToken;
+            }
+        }
+
         try {
             const response = await fetch(url, config);
 
@@ -40,6 +49,11 @@ class APIClient {
                 data = await response.text();
             }
 
+            // The server re-issues the CSRF cookie when it is missing (e.g. older sessions): retry once with it
+            if (response.status === 403 && data && data.error === 'Invalid CSRF token' && !options.csrfRetried) {
+                return this.request(endpoint, { ...options, csrfRetried: true });
+            }
+
             if (!response.ok) {
                 const error = new Error(data.error || data || 'Request failed');
                 error.status = response.status;
@@ -57,6 +71,18 @@ class APIClient {
         }
     }
 
+    // Read the CSRF token the backend stores in a readable cookie
+    // (named "__Host-<name>" when the backend runs in production over HTTPS)
+    getCSRFToken() {
+        for (const name of [`__Host-${appConfig.csrfCookieName}`, appConfig.csrfCookieName]) {
+            const match = document.cookie.match(new RegExp(`(?:^|; )${name}=([^;]*)`));
+            if (match) {
+                return decodeURIComponent(match[1]);
+            }
+        }
+        return '';
+    }
+
     // Normalize backend response to frontend format
     normalizeResponse(data) {
         // If response has success and data fields, extract data
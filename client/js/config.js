@@ -8,6 +8,8 @@ export const config = {
     
     // API
     apiBaseURL: '/api',
+    // Cookie with the CSRF token (must match CSRF_COOKIE_NAME on the backend)
+    csrfCookieName: 'forum_csrf',
     
     // WebSocket
     wsProtocol: window.location.protocol === 'https:' ? 'wss:' : 'ws:',
@@ -1,4 +1,5 @@
 import CONSTANTS from './constants.js';
+import { escapeHtml } from './sanitize.js';
 /**
  * Get user initials from username
  * @param {string} username - The username
@@ -151,3 +152,36 @@ export function debounce(func, delay = 300) {
         }, delay);
     };
 }
+
+/**
+ * Render link preview cards for a post, comment or message
+ * @param {Array} previews - link_previews from the API or a link_previews WebSocket event
+ * @returns {string} HTML of the cards ('' when there are none)
+ */
+export function renderLinkPreviews(previews) {
+    if (!previews || previews.length === 0) {
+        return '';
+    }
+
+    return previews.map(preview => {
+        let site = preview.site_name;
+        if (!site) {
+            try {
+                site = new URL(preview.url).hostname;
+            } catch {
+                site = '';
+            }
+        }
+
+        return `
+            <a class="link-preview" href="${escapeHtml(preview.url)}" target="_blank" rel="nofollow noopener noreferrer">
+                ${preview.image_url ? `<img class="link-preview-image" src="${escapeHtml(preview.image_url)}" alt="" loading="lazy" referrerpolicy="no-referrer">` : ''}
+                <div class="link-preview-body">
+                    <div class="link-preview-site">${escapeHtml(site)}</div>
+                    <div class="link-preview-title">${escapeHtml(preview.title || preview.url)}</div>
+                    ${preview.description ? `<div class="link-preview-description">${escapeHtml(preview.description)}</div>` : ''}
+                </div>
+            </a>
+        `;
+    }).join('');
+}
@@ -106,7 +106,7 @@ export default {
                         ${categories ? `<span>•</span>${categories}` : ''}
                     </div>
                     <div class="post-content">
-                        ${post.content}
+                        ${post.content_html}
                     </div>
                     <div class="post-stats">
                         <span>💬 ${post.comment_count || 0} comments</span>
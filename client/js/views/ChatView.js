@@ -1,7 +1,7 @@
 import apiClient from '../api/client.js';
 import state from '../state.js';
 import wsManager from '../websocket/WebSocketManager.js';
-import { getInitials, showImageLightbox, showToast, formatTime, throttle } from '../utils/helpers.js';
+import { getInitials, showImageLightbox, showToast, formatTime, throttle, renderLinkPreviews } from '../utils/helpers.js';
 import { sanitizeUsername, sanitizeContent } from '../utils/sanitize.js';
 import CONSTANTS from '../utils/constants.js';
 
@@ -360,6 +360,7 @@ export default {
                         ${sanitizeContent(message.content)}
                         ${imageHTML}
                     </div>
+                    <div class="link-previews" data-link-previews="message:${message.message_id}">${renderLinkPreviews(message.link_previews)}</div>
                     <div class="message-time">${time}</div>
                 </div>
             </div>
@@ -583,6 +584,7 @@ export default {
         // Create listener functions
         this.messageReceivedListener = async (message) => {
             const normalizedMessage = {
+                message_id: message.message_id,
                 sender_id: message.sender_id,
                 sender_username: message.sender_name,
                 content: message.content,
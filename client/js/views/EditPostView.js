@@ -237,6 +237,9 @@ export default {
 
             if (selectedCategories.length > 0) {
                 selectedCategories.forEach(cat => requestData.append('categories', cat));
+            } else {
+                // Categories that are not sent are kept, so send an empty one to clear them
+                requestData.append('categories', '');
             }
 
             const imageFile = formData.get('image');
@@ -124,7 +124,7 @@ export default {
                         ${categories ? `<span>•</span>${categories}` : ''}
                     </div>
                     <div class="post-content">
-                        ${post.content}
+                        ${post.content_html}
                     </div>
                     <div class="post-footer">
                         <div class="post-stats">
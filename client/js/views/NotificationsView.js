@@ -77,7 +77,10 @@ export default {
         let icon = '<i class="fas fa-bell"></i>';
         let actionText = notif.action;
 
-        if (notif.action.includes('liked')) {
+        if (notif.action.includes('mentioned')) {
+            // "mentioned you in a post/comment/message" is shown as sent
+            icon = '<i class="fas fa-at"></i>';
+        } else if (notif.action.includes('liked')) {
             icon = '<i class="fas fa-thumbs-up"></i>';
             actionText = 'liked your post';
         } else if (notif.action.includes('comment')) {
@@ -144,8 +147,8 @@ export default {
                     console.error('[NotificationsView] Error marking as read:', error);
                 }
 
-                // Navigate to post
-                navigate(`/post/${postId}`);
+                // Navigate to post; mentions in a message have no post and open the chat
+                navigate(postId ? `/post/${postId}` : '/chat');
             }
         });
     },
@@ -2,6 +2,7 @@
 
 import apiClient from '../api/client.js';
 import state from '../state.js';
+import { renderLinkPreviews } from '../utils/helpers.js';
 
 export default {
     postId: null,
@@ -142,9 +143,11 @@ export default {
                 </div>
 
                 <div class="post-content">
-                    ${post.content}
+                    ${post.content_html}
                 </div>
 
+                <div class="link-previews" data-link-previews="post:${post.post_id}">${renderLinkPreviews(post.link_previews)}</div>
+
                 ${imagesHTML ? `
                     <div class="post-images" style="margin-top: var(--space-2xl);">
                         ${imagesHTML}
@@ -240,9 +243,11 @@ export default {
                 </div>
 
                 <div class="post-content">
-                    ${comment.content}
+                    ${comment.content_html}
                 </div>
 
+                <div class="link-previews" data-link-previews="comment:${comment.comment_id}">${renderLinkPreviews(comment.link_previews)}</div>
+
                 <div class="comment-actions-container">
                     <div class="comment-reaction-buttons">
                         <button class="reaction-btn like-btn btn-sm" onclick="handleCommentReaction('${comment.comment_id}', 'like')">
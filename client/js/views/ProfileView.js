@@ -3,6 +3,7 @@
 import apiClient from '../api/client.js';
 import state from '../state.js';
 import { getInitials } from '../utils/helpers.js';
+import { sanitizeContent } from '../utils/sanitize.js';
 
 export default {
     profile: null,
@@ -264,7 +265,7 @@ export default {
                     </div>
                 </div>
                 <div class="profile-post-content">
-                    ${post.content.substring(0, 200)}${post.content.length > 200 ? '...' : ''}
+                    ${sanitizeContent(post.content.substring(0, 200))}${post.content.length > 200 ? '...' : ''}
                 </div>
                 ${categories ? `
                     <div class="profile-post-categories">
@@ -2,6 +2,7 @@ import state from '../state.js';
 import logger from '../utils/logger.js';
 import CONSTANTS from '../utils/constants.js';
 import config from '../config.js';
+import { renderLinkPreviews } from '../utils/helpers.js';
 
 class WebSocketManager {
     constructor() {
@@ -110,6 +111,14 @@ class WebSocketManager {
                 state.emit('message:read', payload);
                 break;
 
+            case 'link_previews':
+                // Fill in the previews wherever the post, comment or message is on screen
+                document.querySelectorAll(`[data-link-previews="${payload.source}:${CSS.escape(payload.source_id)}"]`)
+                    .forEach(container => {
+                        container.innerHTML = renderLinkPreviews(payload.previews);
+                    });
+                break;
+
             case 'notification':
                 state.emit('notification:received', payload);
                 state.incrementUnreadCount();
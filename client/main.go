@@ -8,13 +8,22 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"tlsserver"
 )
 
 var (
 	PORT        = getEnv("PORT", ":3000")
 	BACKEND_URL = getEnv("BACKEND_URL", "http://localhost:8080")
+
+	// Native TLS: set both files to serve HTTPS with HTTP/2
+	TLS_CERT_FILE       = getEnv("TLS_CERT_FILE", "")
+	TLS_KEY_FILE        = getEnv("TLS_KEY_FILE", "")
+	TLS_RELOAD_INTERVAL = getEnv("TLS_RELOAD_INTERVAL", "30s")
+	HTTP_REDIRECT_PORT  = getEnv("HTTP_REDIRECT_PORT", "") // plain HTTP port redirected to HTTPS
+	BACKEND_CA_FILE     = getEnv("BACKEND_CA_FILE", "")    // extra CA trusted for an https:// backend
 )
 
 func main() {
@@ -24,8 +33,17 @@ func main() {
 		log.Fatal("Invalid backend URL:", err)
 	}
 
+	// TLS settings for talking to an https:// backend
+	backendTLS, err := backendTLSConfig(BACKEND_CA_FILE)
+	if err != nil {
+		log.Fatal("Invalid BACKEND_CA_FILE:", err)
+	}
+
 	// Create reverse proxy with WebSocket support
 	proxy := httputil.NewSingleHostReverseProxy(backendURL)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = backendTLS.Clone()
+	proxy.Transport = transport
 
 	// Configure the proxy to handle WebSocket upgrades
 	proxy.Director = func(req *http.Request) {
@@ -58,6 +76,11 @@ func main() {
 		},
 	}
 
+	// WebSocket dialer for backend connections (wss:// when the backend uses https).
+	// It gets its own TLS config: the HTTP transport adds "h2" to the one it is given.
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = backendTLS.Clone()
+
 	// WebSocket proxy handler
 	wsProxy := func(w http.ResponseWriter, r *http.Request) {
 		// Check if this is a WebSocket upgrade request
@@ -100,7 +123,7 @@ func main() {
 			}
 
 			// Connect to backend WebSocket
-			backendConn, _, err := websocket.DefaultDialer.Dial(backendWSURL, backendHeaders)
+			backendConn, _, err := dialer.Dial(backendWSURL, backendHeaders)
 			if err != nil {
 				log.Printf("Backend WebSocket connection error: %v", err)
 				clientConn.WriteMessage(websocket.CloseMessage, []byte("Backend connection failed"))
@@ -188,10 +211,49 @@ func main() {
 		fs.ServeHTTP(w, r)
 	})
 
-	log.Printf("🚀 Frontend server running at http://localhost%s", PORT)
+	server := &http.Server{
+		Addr:              PORT,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if TLS_CERT_FILE == "" && TLS_KEY_FILE == "" {
+		log.Printf("🚀 Frontend server running at http://localhost%s", PORT)
+		log.Printf("🔄 Proxying /api/* and /ws to %s", BACKEND_URL)
+		log.Printf("📁 Serving static files from: ./client")
+		log.Fatal(server.ListenAndServe())
+	}
+	if TLS_CERT_FILE == "" || TLS_KEY_FILE == "" {
+		log.Fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	// Native TLS: certificates are reloaded on change, HTTP/2 is negotiated via ALPN
+	reloadInterval, err := time.ParseDuration(TLS_RELOAD_INTERVAL)
+	if err != nil || reloadInterval <= 0 {
+		log.Fatal("Invalid TLS_RELOAD_INTERVAL:", TLS_RELOAD_INTERVAL)
+	}
+	reloader, err := tlsserver.NewCertReloader(TLS_CERT_FILE, TLS_KEY_FILE)
+	if err != nil {
+		log.Fatal("Failed to load TLS certificate:", err)
+	}
+	go reloader.Watch(reloadInterval)
+	server.TLSConfig = tlsserver.TLSConfig(reloader)
+
+	if HTTP_REDIRECT_PORT != "" {
+		redirectServer := &http.Server{
+			Addr:              listenAddr(HTTP_REDIRECT_PORT),
+			Handler:           tlsserver.RedirectHandler(portOf(PORT)),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		log.Printf("↪️  Redirecting HTTP on %s to HTTPS", redirectServer.Addr)
+		go func() {
+			log.Fatal(redirectServer.ListenAndServe())
+		}()
+	}
+
+	log.Printf("🚀 Frontend server running at https://localhost%s", PORT)
 	log.Printf("🔄 Proxying /api/* and /ws to %s", BACKEND_URL)
 	log.Printf("📁 Serving static files from: ./client")
-	log.Fatal(http.ListenAndServe(PORT, nil))
+	log.Fatal(server.ListenAndServeTLS("", ""))
 }
 
 func getEnv(key, fallback string) string {
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/repository"
+)
+
+const adminUsage = `Usage: go run ./cmd [command]
+
+Without a command the API server is started.
+
+Commands:
+  backup                  create an online backup in BACKUP_DIR
+  restore <archive>       replace the database and uploads with a backup (stop the server first)
+  prune-backups           remove backups outside the BACKUP_KEEP / BACKUP_MAX_AGE retention policy
+  set-role <user> <role>  make a user "admin" or a regular "user"`
+
+// runAdminCommand executes a maintenance subcommand and returns its exit code
+func runAdminCommand(args []string) int {
+	var err error
+	switch args[0] {
+	case "backup":
+		err = backupCommand()
+	case "restore":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, adminUsage)
+			return 2
+		}
+		err = restoreCommand(args[1])
+	case "prune-backups":
+		err = pruneBackupsCommand()
+	case "set-role":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, adminUsage)
+			return 2
+		}
+		err = setRoleCommand(args[1], args[2])
+	default:
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func backupCommand() error {
+	db, err := database.InitDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	backup, err := database.CreateBackup(db, config.Config.BackupDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s (%d bytes)\n", backup.Name, backup.Size)
+	return nil
+}
+
+func restoreCommand(archive string) error {
+	manifest, err := database.RestoreBackup(archive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored backup from %s (schema version %d, %d uploaded files)\n",
+		manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), manifest.SchemaVersion, manifest.Uploads)
+	if manifest.SchemaVersion < database.SchemaVersion {
+		fmt.Printf("The schema will be migrated to version %d on the next start\n", database.SchemaVersion)
+	}
+	return nil
+}
+
+func pruneBackupsCommand() error {
+	removed, err := database.PruneBackups(config.Config.BackupDir, config.Config.BackupKeep, config.Config.BackupMaxAge)
+	for _, name := range removed {
+		fmt.Printf("Removed %s\n", name)
+	}
+	return err
+}
+
+func setRoleCommand(username, role string) error {
+	db, err := database.InitDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := repository.NewUserRepository(db).SetUserRole(username, role); err != nil {
+		return err
+	}
+	fmt.Printf("%s is now %s\n", username, role)
+	return nil
+}
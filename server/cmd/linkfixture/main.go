@@ -0,0 +1,46 @@
+// Command linkfixture serves the pages of internal/unfurl/linkfixture for trying out link
+// previews locally: pages with OpenGraph, Twitter card or only plain HTML metadata, and pages the
+// unfurler must refuse or give up on (redirects to private addresses, endless bodies, slow
+// answers, non-HTML content).
+//
+//	go run ./cmd/linkfixture -addr :9300
+//
+// The fixture runs on this machine, which link previews never fetch from, so the server's .env
+// has to allow it:
+//
+//	LINK_PREVIEW_ALLOWED_HOSTS=127.0.0.1:9300
+//
+// Then post a link like http://127.0.0.1:9300/article and watch for the link_previews event.
+// (Links to localhost count as links to the forum itself when the frontend runs there, and
+// get no preview.)
+// Every request is logged, so blocked redirects can be seen never reaching their target.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"real-time-forum/internal/unfurl/linkfixture"
+)
+
+func main() {
+	var addr string
+	var opts linkfixture.Options
+	flag.StringVar(&addr, "addr", ":9300", "listen address")
+	flag.DurationVar(&opts.Delay, "delay", 30*time.Second, "how long /slow waits before answering")
+	flag.IntVar(&opts.LargeSize, "large-size", 2<<20, "bytes of padding before the metadata of /large")
+	flag.Parse()
+
+	log.Printf("Link fixture listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, logRequests(linkfixture.NewHandler(opts))))
+}
+
+// logRequests logs every request with the User-Agent, so fetches by the forum stand out
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s (%s)", r.Method, r.URL.Path, r.UserAgent())
+		next.ServeHTTP(w, r)
+	})
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"real-time-forum/config"
 	"real-time-forum/database"
 	"real-time-forum/internal/routes"
+	"tlsserver"
 )
 
 func main() {
@@ -17,6 +20,11 @@ func main() {
 		log.Panic(err)
 	}
 
+	// Maintenance commands run instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(runAdminCommand(os.Args[1:]))
+	}
+
 	// Initialize the database
 	db, err := database.InitDB()
 	if err != nil {
@@ -24,13 +32,45 @@ func main() {
 	}
 	defer db.Close()
 
-	// Setup API routes
-	apiRoutes := routes.SetupRoutes(db)
+	// Setup API routes and start the background jobs
+	apiRoutes, backgroundJobs := routes.SetupRoutes(db)
+	backgroundJobs.Start()
 
 	// Create server using config values
 	serverAddr := fmt.Sprintf("%s:%s", config.Config.ServerHost, config.Config.ServerPort)
 
-	fmt.Printf("Starting API server on %s\n", serverAddr)
+	server := &http.Server{
+		Addr:              serverAddr,
+		Handler:           apiRoutes,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if config.Config.TLSCertFile == "" {
+		fmt.Printf("Starting API server on %s\n", serverAddr)
+		log.Fatal(server.ListenAndServe())
+	}
+
+	// Native TLS: certificates are reloaded on change, HTTP/2 is negotiated via ALPN
+	reloader, err := tlsserver.NewCertReloader(config.Config.TLSCertFile, config.Config.TLSKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificate: %v", err)
+	}
+	go reloader.Watch(config.Config.TLSReloadInterval)
+	server.TLSConfig = tlsserver.TLSConfig(reloader)
+
+	if config.Config.HTTPRedirectPort != "" {
+		redirectAddr := fmt.Sprintf("%s:%s", config.Config.ServerHost, config.Config.HTTPRedirectPort)
+		redirectServer := &http.Server{
+			Addr:              redirectAddr,
+			Handler:           tlsserver.RedirectHandler(config.Config.ServerPort),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		fmt.Printf("Redirecting HTTP on %s to HTTPS\n", redirectAddr)
+		go func() {
+			log.Fatal(redirectServer.ListenAndServe())
+		}()
+	}
 
-	log.Fatal(http.ListenAndServe(serverAddr, apiRoutes))
+	fmt.Printf("Starting API server on %s (TLS)\n", serverAddr)
+	log.Fatal(server.ListenAndServeTLS("", ""))
 }
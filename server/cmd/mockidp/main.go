@@ -0,0 +1,59 @@
+// Command mockidp runs the minimal OpenID Connect provider of internal/oauth/mockidp for trying
+// out the OAuth login locally. It approves every authorization request as one fixed user, so
+// no account or browser interaction is needed:
+//
+//	go run ./cmd/mockidp -addr :9000 -email alice@example.com
+//
+// and in the server's .env:
+//
+//	OAUTH_PROVIDERS=mock
+//	OAUTH_MOCK_ISSUER=http://localhost:9000
+//	OAUTH_MOCK_CLIENT_ID=forum
+//	OAUTH_MOCK_CLIENT_SECRET=secret
+//
+// ID tokens are signed with a key generated at startup (RS256, or ES256 with -alg ES256). PKCE
+// is checked when the client sends a challenge, and required with -require-pkce.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"real-time-forum/internal/oauth/mockidp"
+)
+
+func main() {
+	var addr string
+	var opts mockidp.Options
+	flag.StringVar(&addr, "addr", ":9000", "listen address")
+	flag.StringVar(&opts.Issuer, "issuer", "", "issuer URL (default http://localhost<addr>)")
+	flag.StringVar(&opts.ClientID, "client-id", "forum", "accepted client ID")
+	flag.StringVar(&opts.ClientSecret, "client-secret", "secret", "accepted client secret")
+	flag.StringVar(&opts.Alg, "alg", "RS256", "ID token algorithm: RS256 or ES256")
+	flag.StringVar(&opts.Subject, "sub", "mock-user-1", "subject of the signed-in user")
+	flag.StringVar(&opts.Email, "email", "mock.user@example.com", "email of the signed-in user")
+	flag.BoolVar(&opts.EmailVerified, "email-verified", true, "whether the email is reported as verified")
+	flag.StringVar(&opts.Username, "username", "mockuser", "preferred_username of the signed-in user")
+	flag.StringVar(&opts.Name, "name", "Mock User", "display name of the signed-in user")
+	flag.StringVar(&opts.Picture, "picture", "", "profile picture URL of the signed-in user")
+	flag.BoolVar(&opts.RequirePKCE, "require-pkce", false, "reject authorization requests without a PKCE S256 challenge")
+	flag.Parse()
+
+	if opts.Issuer == "" {
+		host := addr
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		opts.Issuer = "http://" + host
+	}
+
+	idp, err := mockidp.New(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock OpenID Connect provider %s listening on %s (client %q, user %q)", strings.TrimSuffix(opts.Issuer, "/"), addr, opts.ClientID, opts.Email)
+	log.Fatal(http.ListenAndServe(addr, idp.Handler()))
+}
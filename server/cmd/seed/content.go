@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Word lists used to build realistic looking users and forum content
+
+var firstNames = []string{
+	"Alice", "Bruno", "Chiara", "Dmitri", "Elena", "Farid", "Grace", "Hiro", "Ines", "Jonas",
+	"Kofi", "Lena", "Mateo", "Nadia", "Oscar", "Priya", "Quentin", "Rosa", "Sven", "Tara",
+	"Umar", "Vera", "Wei", "Ximena", "Yusuf", "Zoe",
+}
+
+var lastNames = []string{
+	"Anders", "Baker", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hansen", "Ivanova", "Jensen",
+	"Kowalski", "Larsen", "Moreau", "Nakamura", "Okafor", "Petrov", "Quinn", "Rossi", "Silva", "Tanaka",
+}
+
+var genders = []string{"Male", "Female", "Other"}
+
+// topics are the things people post about, keyed by category name
+var topics = map[string][]string{
+	"General Discussion":  {"remote work", "tech conferences", "side projects", "home office setups", "learning resources"},
+	"Programming":         {"Go generics", "error handling in Go", "Rust ownership", "unit testing", "code reviews", "refactoring legacy code"},
+	"Web Development":     {"CSS grid", "single page apps", "web accessibility", "REST API design", "WebSockets"},
+	"Networking":          {"IPv6 adoption", "DNS caching", "home lab routers", "TCP tuning", "VPN setups"},
+	"Game Development":    {"entity component systems", "Godot", "pixel art tools", "game jams", "physics engines"},
+	"Database Management": {"SQLite indexes", "query plans", "schema migrations", "connection pooling", "backups"},
+	"DevOps":              {"CI pipelines", "Docker images", "Kubernetes", "infrastructure as code", "monitoring alerts"},
+	"Cloud Computing":     {"serverless functions", "object storage", "cloud costs", "multi-region setups", "managed databases"},
+	"Mobile Development":  {"Flutter", "Kotlin coroutines", "SwiftUI", "offline sync", "push notifications"},
+	"Machine Learning":    {"fine-tuning models", "feature engineering", "model evaluation", "vector databases", "training on GPUs"},
+	"Cybersecurity":       {"password hashing", "CSRF protection", "rate limiting", "phishing awareness", "TLS certificates"},
+	"AI & Data Science":   {"data cleaning", "pandas vs polars", "notebooks", "dashboards", "A/B testing"},
+}
+
+var postOpeners = []string{
+	"I've been spending a lot of time on %s lately and I'm curious how others approach it.",
+	"Quick question about %s: what is the one thing you wish you had known earlier?",
+	"Just finished a weekend project built around %s and wanted to share a few lessons.",
+	"Is anyone else struggling with %s? The documentation only gets me halfway there.",
+	"Hot take: %s is overrated for small teams. Change my mind.",
+	"Looking for good resources on %s, books, talks or blog posts all welcome.",
+}
+
+var postFollowUps = []string{
+	"The first attempt worked but felt fragile.",
+	"Performance was fine until the data grew.",
+	"Our team is split on the best approach.",
+	"I ended up rewriting most of it twice.",
+	"The trade-offs are not obvious at first.",
+	"Testing it properly took longer than building it.",
+	"Happy to post a write-up if people are interested.",
+}
+
+var commentTemplates = []string{
+	"Great post, thanks for sharing!",
+	"We ran into the same issue with %s.",
+	"Have you tried a simpler approach first?",
+	"This matches my experience with %s.",
+	"Could you share a code sample?",
+	"Strongly agree, especially about %s.",
+	"I disagree a bit, it depends on the scale.",
+	"Bookmarked, very useful.",
+}
+
+var messageTemplates = []string{
+	"Hey, saw your post about %s, got a minute?",
+	"Thanks for the tip on %s!",
+	"Are you going to the meetup next week?",
+	"Sending you the link we talked about.",
+	"How did the %s experiment go?",
+	"Let's pair on this tomorrow.",
+}
+
+// generator builds all random values from a single seeded source so runs are reproducible
+type generator struct {
+	rnd *rand.Rand
+}
+
+func newGenerator(seed int64) *generator {
+	return &generator{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rnd.Intn(len(values))]
+}
+
+// topic picks something to talk about in a category, falling back to the category name itself
+func (g *generator) topic(category string) string {
+	if list, ok := topics[category]; ok {
+		return g.pick(list)
+	}
+	return strings.ToLower(category)
+}
+
+// username is unique per index and stays within the username length limits
+func (g *generator) username(i int) string {
+	return fmt.Sprintf("%s_%03d", strings.ToLower(g.pick(firstNames)), i)
+}
+
+// postContent writes a few sentences about a topic, trimmed to maxLen
+func (g *generator) postContent(topic string, maxLen int) string {
+	sentences := []string{fmt.Sprintf(g.pick(postOpeners), topic)}
+	for n := g.rnd.Intn(3); n > 0; n-- {
+		sentences = append(sentences, g.pick(postFollowUps))
+	}
+	return truncate(strings.Join(sentences, " "), maxLen)
+}
+
+func (g *generator) postTitle(topic string) string {
+	return "Thoughts on " + topic
+}
+
+func (g *generator) commentContent(topic string, maxLen int) string {
+	return truncate(fillTemplate(g.pick(commentTemplates), topic), maxLen)
+}
+
+func (g *generator) messageContent(topic string) string {
+	return fillTemplate(g.pick(messageTemplates), topic)
+}
+
+// fillTemplate formats templates that contain a %s placeholder and returns the rest unchanged
+func fillTemplate(template, topic string) string {
+	if strings.Contains(template, "%s") {
+		return fmt.Sprintf(template, topic)
+	}
+	return template
+}
+
+func truncate(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	return strings.TrimSpace(text[:maxLen])
+}
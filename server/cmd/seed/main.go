@@ -0,0 +1,238 @@
+// Command seed fills the forum database with a deterministic demo dataset.
+//
+// The same -seed always produces the same users, posts, comments, reactions and messages
+// (including their IDs), which makes it usable for demos, load tests and bug reports:
+//
+//	go run ./cmd/seed -seed 42 -users 50 -posts 500
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+)
+
+// seedOptions holds the command line flags
+type seedOptions struct {
+	seed             int64
+	users            int
+	posts            int
+	maxComments      int
+	maxReactions     int
+	messages         int
+	password         string
+	dbPath           string
+	draftsPercentage int
+}
+
+// seedRepositories groups the repositories used to insert data
+type seedRepositories struct {
+	users            *repository.UserRepository
+	categories       *repository.CategoryRepository
+	posts            *repository.PostsRepository
+	comments         *repository.CommentRepository
+	postReactions    *repository.PostReactionRepository
+	commentReactions *repository.CommentReactionRepository
+	bookmarks        *repository.BookmarkRepository
+	messages         *repository.MessageRepository
+}
+
+// seededPost remembers what a post is about so comments can stay on topic
+type seededPost struct {
+	id    string
+	topic string
+}
+
+func main() {
+	opts := seedOptions{}
+	flag.Int64Var(&opts.seed, "seed", 1, "random seed; the same seed produces the same dataset")
+	flag.IntVar(&opts.users, "users", 20, "number of users to create")
+	flag.IntVar(&opts.posts, "posts", 100, "number of posts to create")
+	flag.IntVar(&opts.maxComments, "comments", 5, "maximum comments per post")
+	flag.IntVar(&opts.maxReactions, "reactions", 10, "maximum reactions per post and per comment")
+	flag.IntVar(&opts.messages, "messages", 200, "number of private messages to create")
+	flag.IntVar(&opts.draftsPercentage, "drafts", 5, "percentage of posts saved as drafts")
+	flag.StringVar(&opts.password, "password", "Seed123!", "password for every seeded user")
+	flag.StringVar(&opts.dbPath, "db", "", "database path (defaults to DB_PATH)")
+	flag.Parse()
+
+	if err := config.LoadConfig(); err != nil {
+		log.Fatal(err)
+	}
+	if opts.dbPath != "" {
+		config.Config.DBPath = opts.dbPath
+	}
+	if opts.users < 2 {
+		log.Fatal("at least 2 users are required")
+	}
+
+	db, err := database.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Make generated UUIDs reproducible as well
+	uuid.SetRand(rand.New(rand.NewSource(opts.seed)))
+
+	messageImageRepo := repository.NewMessageImageRepository(db)
+	repos := seedRepositories{
+		users:            repository.NewUserRepository(db),
+		categories:       repository.NewCategoryRepository(db),
+		posts:            repository.NewPostsRepository(db),
+		comments:         repository.NewCommentRepository(db),
+		postReactions:    repository.NewPostReactionRepository(db),
+		commentReactions: repository.NewCommentReactionRepository(db),
+		bookmarks:        repository.NewBookmarkRepository(db),
+		messages:         repository.NewMessageRepository(db, messageImageRepo),
+	}
+
+	if err := seed(repos, opts); err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+}
+
+// seed creates the whole dataset in a fixed order so the random sequence is always the same
+func seed(repos seedRepositories, opts seedOptions) error {
+	gen := newGenerator(opts.seed)
+
+	categories, err := repos.categories.GetAllCategories()
+	if err != nil {
+		return fmt.Errorf("loading categories: %v", err)
+	}
+	if len(categories) == 0 {
+		return fmt.Errorf("no categories found")
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+
+	// 1. Users
+	users := make([]*models.User, 0, opts.users)
+	for i := 1; i <= opts.users; i++ {
+		username := gen.username(i)
+		user, err := repos.users.CreateUser(models.UserRegistration{
+			Username:  username,
+			Age:       18 + gen.rnd.Intn(50),
+			Gender:    gen.pick(genders),
+			FirstName: gen.pick(firstNames),
+			LastName:  gen.pick(lastNames),
+			Email:     fmt.Sprintf("%s@example.com", username),
+			Password:  opts.password,
+		})
+		if err != nil {
+			return fmt.Errorf("creating user %s: %v (seed into an empty database)", username, err)
+		}
+		users = append(users, user)
+	}
+
+	// 2. Posts with comments and reactions
+	var posts []seededPost
+	var commentCount, reactionCount, drafts int
+	for i := 0; i < opts.posts; i++ {
+		author := users[gen.rnd.Intn(len(users))]
+
+		// One to three distinct categories; the first one picks the topic
+		perm := gen.rnd.Perm(len(categories))
+		categoryCount := 1 + gen.rnd.Intn(min(3, len(categories)))
+		categoryIDs := make([]string, 0, categoryCount)
+		for _, idx := range perm[:categoryCount] {
+			categoryIDs = append(categoryIDs, categories[idx].ID)
+		}
+		topic := gen.topic(categories[perm[0]].Name)
+
+		publishing := models.PostPublishing{Status: models.PostStatusPublished}
+		if gen.rnd.Intn(2) == 0 {
+			publishing.Title = gen.postTitle(topic)
+		}
+		if gen.rnd.Intn(100) < opts.draftsPercentage {
+			publishing.Status = models.PostStatusDraft
+		}
+
+		content := gen.postContent(topic, config.Config.MaxPostContentLength)
+		created, err := repos.posts.CreatePost(author.ID, content, categoryIDs, nil, publishing)
+		if err != nil {
+			return fmt.Errorf("creating post: %v", err)
+		}
+		if publishing.Status == models.PostStatusDraft {
+			drafts++
+			continue
+		}
+		posts = append(posts, seededPost{id: created.PostID, topic: topic})
+
+		// Reactions on the post from distinct users
+		for _, idx := range gen.rnd.Perm(len(users))[:gen.rnd.Intn(min(opts.maxReactions, len(users))+1)] {
+			if _, err := repos.postReactions.TogglePostReaction(users[idx].ID, created.PostID, reactionType(gen)); err != nil {
+				return fmt.Errorf("reacting to post: %v", err)
+			}
+			reactionCount++
+		}
+
+		// Comments, each with its own reactions
+		for c := gen.rnd.Intn(opts.maxComments + 1); c > 0; c-- {
+			commenter := users[gen.rnd.Intn(len(users))]
+			comment, err := repos.comments.CreateComment(created.PostID, commenter.ID, gen.commentContent(topic, config.Config.MaxCommentLength))
+			if err != nil {
+				return fmt.Errorf("creating comment: %v", err)
+			}
+			commentCount++
+
+			for _, idx := range gen.rnd.Perm(len(users))[:gen.rnd.Intn(min(opts.maxReactions, len(users))/2+1)] {
+				if _, err := repos.commentReactions.ToggleCommentReaction(users[idx].ID, comment.CommentID, reactionType(gen)); err != nil {
+					return fmt.Errorf("reacting to comment: %v", err)
+				}
+				reactionCount++
+			}
+		}
+	}
+
+	// 3. A few bookmarks per user
+	bookmarkCount := 0
+	if len(posts) > 0 {
+		for _, user := range users {
+			for _, idx := range gen.rnd.Perm(len(posts))[:gen.rnd.Intn(min(3, len(posts))+1)] {
+				if _, err := repos.bookmarks.ToggleBookmark(user.ID, posts[idx].id); err != nil {
+					return fmt.Errorf("bookmarking post: %v", err)
+				}
+				bookmarkCount++
+			}
+		}
+	}
+
+	// 4. Private messages between random pairs of users
+	for i := 0; i < opts.messages; i++ {
+		sender := users[gen.rnd.Intn(len(users))]
+		recipient := users[gen.rnd.Intn(len(users))]
+		for recipient.ID == sender.ID {
+			recipient = users[gen.rnd.Intn(len(users))]
+		}
+
+		topic := "the forum"
+		if len(posts) > 0 {
+			topic = posts[gen.rnd.Intn(len(posts))].topic
+		}
+		if _, err := repos.messages.SaveMessage(sender.ID, recipient.ID, gen.messageContent(topic)); err != nil {
+			return fmt.Errorf("sending message: %v", err)
+		}
+	}
+
+	fmt.Printf("Seeded %d users, %d posts (%d drafts), %d comments, %d reactions, %d bookmarks and %d messages (seed %d)\n",
+		len(users), opts.posts, drafts, commentCount, reactionCount, bookmarkCount, opts.messages, opts.seed)
+	fmt.Printf("All users share the password %q\n", opts.password)
+	return nil
+}
+
+// reactionType returns a like most of the time and sometimes a dislike
+func reactionType(gen *generator) int {
+	if gen.rnd.Intn(5) == 0 {
+		return 2
+	}
+	return 1
+}
@@ -17,13 +17,22 @@ type AppConfig struct {
 	FrontendBaseURL string
 	BackendBaseURL  string
 
+	// Native TLS (both files set enables HTTPS with HTTP/2)
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSReloadInterval time.Duration // how often the certificate files are checked for changes
+	HTTPRedirectPort  string        // plain HTTP port redirected to HTTPS, empty disables it
+
 	// Database configuration
 	DBPath           string
 	DBMaxConnections int
 
 	// Security configuration (Session-based)
-	SessionName string // ADDED: Session cookie name
-	Environment string
+	SessionName         string // ADDED: Session cookie name
+	CSRFCookieName      string // readable cookie holding the session's CSRF token
+	OAuthCookieName     string // binds OAuth logins to the browser that started them
+	TwoFactorCookieName string // carries the pending login of an OAuth sign-in to the 2FA prompt
+	Environment         string
 
 	// Authentication configuration
 	SessionDuration time.Duration
@@ -33,15 +42,98 @@ type AppConfig struct {
 	MaxUsernameLen  int
 	MinUsernameLen  int
 
+	// Login brute-force protection
+	TrustedProxies          string        // comma-separated proxy IPs/CIDRs whose forwarding headers are honored
+	LoginBackoffThreshold   int           // failed logins before each further attempt is delayed
+	LoginBackoffBase        time.Duration // first delay, doubled with every further failure
+	LoginBackoffMax         time.Duration
+	LoginLockoutThreshold   int // failed logins of one account before it is locked
+	LoginIPLockoutThreshold int // failed logins from one IP before it is locked
+	LoginLockoutDuration    time.Duration
+	LoginFailureWindow      time.Duration // failure counters reset after this long without failures
+
 	// Content configuration
 	MaxPostContentLength int
 	MinPostContentLength int
+	MaxPostTitleLength   int
 	MaxCommentLength     int
 	MinCommentLength     int
+	MaxBioLength         int
+	MaxLocationLength    int
+	MaxMentionsPerItem   int // @username mentions recorded per post, comment or message
+
+	// Soft delete configuration
+	DeletedContentGracePeriod   time.Duration // how long authors can restore deleted posts/comments
+	DeletedContentPurgeInterval time.Duration // how often expired deleted content is purged
+
+	// Email configuration
+	MailDriver   string // "log" writes emails to MailLogFile (or the server log), "smtp" sends them
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// Password reset and email verification tokens
+	PasswordResetTokenTTL     time.Duration
+	EmailVerificationTokenTTL time.Duration
+	EmailTokenRateLimit       int // tokens per address and purpose within EmailTokenRateWindow
+	EmailTokenRateWindow      time.Duration
+
+	// Two-factor authentication
+	TOTPIssuer             string        // shown next to the account name in authenticator apps
+	TwoFactorLoginTTL      time.Duration // how long a password-verified login waits for the second factor
+	TwoFactorMaxAttempts   int           // wrong codes allowed per pending login
+	TwoFactorRecoveryCodes int           // recovery codes issued at a time
+
+	// Personal access tokens
+	APITokenDefaultTTL time.Duration // lifetime when the request does not choose one
+	APITokenMaxTTL     time.Duration
+	APITokenMaxPerUser int
+
+	// Outgoing webhooks
+	WebhookPollInterval      time.Duration // how often the delivery queue is checked
+	WebhookTimeout           time.Duration // per request
+	WebhookMaxAttempts       int
+	WebhookRetryBase         time.Duration // delay before the first retry, doubled after each further failure
+	WebhookRetryMax          time.Duration
+	WebhookDeliveryRetention time.Duration // finished deliveries older than this are removed from the log
+
+	// Link previews
+	LinkPreviewEnabled      bool
+	LinkPreviewTimeout      time.Duration // per page, including redirects and reading the body
+	LinkPreviewMaxBytes     int64         // only this much of a page is read
+	LinkPreviewTTL          time.Duration // how long a fetched preview is reused before the page is fetched again
+	LinkPreviewMaxPerItem   int           // links previewed per post, comment or message
+	LinkPreviewAllowedHosts string        // comma-separated host:port pairs that may be private addresses (local test servers)
+
+	// Account deletion configuration
+	AccountDeletionPolicy string // "anonymize" keeps public content under an anonymous account, "delete" removes it
+
+	// Backup configuration
+	BackupDir      string
+	BackupInterval time.Duration // 0 disables scheduled backups
+	BackupKeep     int           // number of most recent backups always kept
+	BackupMaxAge   time.Duration // older backups beyond BackupKeep are removed, 0 keeps them
 
 	// Rate limiting configuration
 	RateLimitRequests int
 	RateLimitWindow   int // in minutes
+	// Routes with their own budget (requests per window, per user or per IP when anonymous)
+	RateLimitAuthRequests     int // login, registration, password reset, 2FA login
+	RateLimitAuthWindow       time.Duration
+	RateLimitContentRequests  int // creating posts and comments
+	RateLimitContentWindow    time.Duration
+	RateLimitReactionRequests int // reactions and bookmarks
+	RateLimitReactionWindow   time.Duration
+	RateLimitMessageRequests  int // sending private messages
+	RateLimitMessageWindow    time.Duration
+	// WebSocket frames, per user
+	WSTypingRateLimitRequests int
+	WSTypingRateLimitWindow   time.Duration
+	WSSendRateLimitRequests   int
+	WSSendRateLimitWindow     time.Duration
 
 	// Pagination configuration
 	DefaultPageSize int
@@ -53,25 +145,24 @@ type AppConfig struct {
 	MinCategories int
 
 	// CORS configuration (for frontend communication)
-	AllowedOrigins string // comma-separated origins
+	AllowedOrigins string // comma-separated origins, "https://*.example.com" allows all subdomains
 	AllowedMethods string
 	AllowedHeaders string
 
-	// NEW: OAuth Configuration
-	GitHubClientID     string
-	GitHubClientSecret string
-	GitHubRedirectURI  string
+	// Derived security settings (origins, CSP, cookies, HSTS), see security.go
+	Security SecurityConfig
 
-	// google OAuth Configuration
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleRedirectURI  string
+	// OAuth / OpenID Connect sign-in providers, see oauth.go
+	OAuthProviders []OAuthProviderConfig
+	OAuthTokenKey  []byte // AES-256 key for provider access tokens stored in the database
 
 	// Image configuration
 	UploadDir           string
 	MaxImagesPerPost    int
 	MaxImagesPerMessage int
 	MaxMessageImageSize int64
+	AvatarSize          int // avatars are cropped to a square of AvatarSize x AvatarSize pixels
+	MaxAvatarFileSize   int64
 }
 
 // Global configuration instance
@@ -90,12 +181,30 @@ func LoadConfig() error {
 	Config.FrontendBaseURL = getEnv("FRONTEND_BASE_URL", "http://localhost:3000")
 	Config.BackendBaseURL = getEnv("BACKEND_BASE_URL", "http://localhost:8080")
 
+	// Native TLS
+	Config.TLSCertFile = getEnv("TLS_CERT_FILE", "")
+	Config.TLSKeyFile = getEnv("TLS_KEY_FILE", "")
+	Config.TLSReloadInterval = getEnvAsDuration("TLS_RELOAD_INTERVAL", 30*time.Second)
+	Config.HTTPRedirectPort = strings.TrimPrefix(getEnv("HTTP_REDIRECT_PORT", ""), ":")
+	if (Config.TLSCertFile == "") != (Config.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if Config.HTTPRedirectPort != "" && Config.TLSCertFile == "" {
+		return fmt.Errorf("HTTP_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if Config.TLSReloadInterval <= 0 {
+		return fmt.Errorf("TLS_RELOAD_INTERVAL must be positive")
+	}
+
 	// Database configuration
 	Config.DBPath = getEnv("DB_PATH", "./DBPath/forum.db")
 	Config.DBMaxConnections = getEnvAsInt("DB_MAX_CONNECTIONS", 10)
 
 	// Security configuration (Session-based)
 	Config.SessionName = getEnv("SESSION_NAME", "forum_session")
+	Config.CSRFCookieName = getEnv("CSRF_COOKIE_NAME", "forum_csrf")
+	Config.OAuthCookieName = getEnv("OAUTH_COOKIE_NAME", "forum_oauth")
+	Config.TwoFactorCookieName = getEnv("TWO_FACTOR_COOKIE_NAME", "forum_2fa")
 	Config.Environment = getEnv("ENVIRONMENT", "development")
 
 	// Authentication configuration
@@ -106,17 +215,114 @@ func LoadConfig() error {
 	Config.MaxPasswordLen = getEnvAsInt("MAX_PASSWORD_LENGTH", 15)
 	Config.MinPasswordLen = getEnvAsInt("MIN_PASSWORD_LENGTH", 3)
 
+	// Login brute-force protection
+	Config.TrustedProxies = getEnv("TRUSTED_PROXIES", "")
+	Config.LoginBackoffThreshold = getEnvAsInt("LOGIN_BACKOFF_THRESHOLD", 3)
+	Config.LoginBackoffBase = getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second)
+	Config.LoginBackoffMax = getEnvAsDuration("LOGIN_BACKOFF_MAX", 5*time.Minute)
+	Config.LoginLockoutThreshold = getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 10)
+	Config.LoginIPLockoutThreshold = getEnvAsInt("LOGIN_IP_LOCKOUT_THRESHOLD", 50)
+	Config.LoginLockoutDuration = getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	Config.LoginFailureWindow = getEnvAsDuration("LOGIN_FAILURE_WINDOW", time.Hour)
+
 	// Content configuration - Posts
 	Config.MaxPostContentLength = getEnvAsInt("MAX_POST_CONTENT_LENGTH", 500)
 	Config.MinPostContentLength = getEnvAsInt("MIN_POST_CONTENT_LENGTH", 10)
+	Config.MaxPostTitleLength = getEnvAsInt("MAX_POST_TITLE_LENGTH", 100)
 
 	// Content configuration - Comments
 	Config.MaxCommentLength = getEnvAsInt("MAX_COMMENT_LENGTH", 150)
 	Config.MinCommentLength = getEnvAsInt("MIN_COMMENT_LENGTH", 5)
 
+	// Content configuration - Profiles
+	Config.MaxBioLength = getEnvAsInt("MAX_BIO_LENGTH", 500)
+	Config.MaxLocationLength = getEnvAsInt("MAX_LOCATION_LENGTH", 100)
+
+	// Content configuration - Mentions
+	Config.MaxMentionsPerItem = getEnvAsInt("MAX_MENTIONS_PER_ITEM", 10)
+
+	// Soft delete configuration
+	Config.DeletedContentGracePeriod = getEnvAsDuration("DELETED_CONTENT_GRACE_PERIOD", 7*24*time.Hour)
+	Config.DeletedContentPurgeInterval = getEnvAsDuration("DELETED_CONTENT_PURGE_INTERVAL", time.Hour)
+
+	// Email configuration
+	Config.MailDriver = getEnv("MAIL_DRIVER", "log")
+	Config.MailFrom = getEnv("MAIL_FROM", "Real-Time Forum <no-reply@localhost>")
+	Config.MailLogFile = getEnv("MAIL_LOG_FILE", "")
+	Config.SMTPHost = getEnv("SMTP_HOST", "localhost")
+	Config.SMTPPort = getEnv("SMTP_PORT", "1025") // MailHog default
+	Config.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	Config.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	if Config.MailDriver != "log" && Config.MailDriver != "smtp" {
+		return fmt.Errorf("invalid MAIL_DRIVER %q: must be \"log\" or \"smtp\"", Config.MailDriver)
+	}
+
+	// Password reset and email verification tokens
+	Config.PasswordResetTokenTTL = getEnvAsDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour)
+	Config.EmailVerificationTokenTTL = getEnvAsDuration("EMAIL_VERIFICATION_TOKEN_TTL", 48*time.Hour)
+	Config.EmailTokenRateLimit = getEnvAsInt("EMAIL_TOKEN_RATE_LIMIT", 3)
+	Config.EmailTokenRateWindow = getEnvAsDuration("EMAIL_TOKEN_RATE_WINDOW", time.Hour)
+
+	// Two-factor authentication
+	Config.TOTPIssuer = getEnv("TOTP_ISSUER", "Real-Time Forum")
+	Config.TwoFactorLoginTTL = getEnvAsDuration("TWO_FACTOR_LOGIN_TTL", 5*time.Minute)
+	Config.TwoFactorMaxAttempts = getEnvAsInt("TWO_FACTOR_MAX_ATTEMPTS", 5)
+	Config.TwoFactorRecoveryCodes = getEnvAsInt("TWO_FACTOR_RECOVERY_CODES", 10)
+
+	// Personal access tokens
+	Config.APITokenDefaultTTL = getEnvAsDuration("API_TOKEN_DEFAULT_TTL", 90*24*time.Hour)
+	Config.APITokenMaxTTL = getEnvAsDuration("API_TOKEN_MAX_TTL", 365*24*time.Hour)
+	Config.APITokenMaxPerUser = getEnvAsInt("API_TOKEN_MAX_PER_USER", 20)
+	if Config.APITokenDefaultTTL <= 0 || Config.APITokenDefaultTTL > Config.APITokenMaxTTL {
+		return fmt.Errorf("API_TOKEN_DEFAULT_TTL must be positive and not exceed API_TOKEN_MAX_TTL")
+	}
+
+	// Outgoing webhooks
+	Config.WebhookPollInterval = getEnvAsDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second)
+	Config.WebhookTimeout = getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	Config.WebhookMaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	Config.WebhookRetryBase = getEnvAsDuration("WEBHOOK_RETRY_BASE", 30*time.Second)
+	Config.WebhookRetryMax = getEnvAsDuration("WEBHOOK_RETRY_MAX", 6*time.Hour)
+	Config.WebhookDeliveryRetention = getEnvAsDuration("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour)
+	if Config.WebhookPollInterval <= 0 || Config.WebhookMaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_POLL_INTERVAL must be positive and WEBHOOK_MAX_ATTEMPTS at least 1")
+	}
+
+	// Link previews
+	Config.LinkPreviewEnabled = getEnvAsBool("LINK_PREVIEW_ENABLED", true)
+	Config.LinkPreviewTimeout = getEnvAsDuration("LINK_PREVIEW_TIMEOUT", 5*time.Second)
+	Config.LinkPreviewMaxBytes = int64(getEnvAsInt("LINK_PREVIEW_MAX_BYTES", 512<<10))
+	Config.LinkPreviewTTL = getEnvAsDuration("LINK_PREVIEW_TTL", 24*time.Hour)
+	Config.LinkPreviewMaxPerItem = getEnvAsInt("LINK_PREVIEW_MAX_PER_ITEM", 3)
+	Config.LinkPreviewAllowedHosts = getEnv("LINK_PREVIEW_ALLOWED_HOSTS", "")
+	if Config.LinkPreviewTimeout <= 0 || Config.LinkPreviewMaxBytes <= 0 {
+		return fmt.Errorf("LINK_PREVIEW_TIMEOUT and LINK_PREVIEW_MAX_BYTES must be positive")
+	}
+
+	// Account deletion configuration
+	Config.AccountDeletionPolicy = getEnv("ACCOUNT_DELETION_POLICY", "anonymize")
+
+	// Backup configuration
+	Config.BackupDir = getEnv("BACKUP_DIR", "./backups/")
+	Config.BackupInterval = getEnvAsDuration("BACKUP_INTERVAL", 0)
+	Config.BackupKeep = getEnvAsInt("BACKUP_KEEP", 7)
+	Config.BackupMaxAge = getEnvAsDuration("BACKUP_MAX_AGE", 30*24*time.Hour)
+
 	// Rate limiting configuration
 	Config.RateLimitRequests = getEnvAsInt("RATE_LIMIT_REQUESTS", 100000) // for development it will change in production
 	Config.RateLimitWindow = getEnvAsInt("RATE_LIMIT_WINDOW", 60)         // minutes
+	Config.RateLimitAuthRequests = getEnvAsInt("RATE_LIMIT_AUTH_REQUESTS", 20)
+	Config.RateLimitAuthWindow = getEnvAsDuration("RATE_LIMIT_AUTH_WINDOW", time.Minute)
+	Config.RateLimitContentRequests = getEnvAsInt("RATE_LIMIT_CONTENT_REQUESTS", 10)
+	Config.RateLimitContentWindow = getEnvAsDuration("RATE_LIMIT_CONTENT_WINDOW", time.Minute)
+	Config.RateLimitReactionRequests = getEnvAsInt("RATE_LIMIT_REACTION_REQUESTS", 60)
+	Config.RateLimitReactionWindow = getEnvAsDuration("RATE_LIMIT_REACTION_WINDOW", time.Minute)
+	Config.RateLimitMessageRequests = getEnvAsInt("RATE_LIMIT_MESSAGE_REQUESTS", 30)
+	Config.RateLimitMessageWindow = getEnvAsDuration("RATE_LIMIT_MESSAGE_WINDOW", time.Minute)
+	Config.WSTypingRateLimitRequests = getEnvAsInt("WS_TYPING_RATE_LIMIT_REQUESTS", 20)
+	Config.WSTypingRateLimitWindow = getEnvAsDuration("WS_TYPING_RATE_LIMIT_WINDOW", 10*time.Second)
+	Config.WSSendRateLimitRequests = getEnvAsInt("WS_SEND_RATE_LIMIT_REQUESTS", 30)
+	Config.WSSendRateLimitWindow = getEnvAsDuration("WS_SEND_RATE_LIMIT_WINDOW", time.Minute)
 
 	// Pagination configuration
 	Config.DefaultPageSize = getEnvAsInt("DEFAULT_PAGE_SIZE", 20)
@@ -130,24 +336,21 @@ func LoadConfig() error {
 	// CORS configuration (for frontend communication)
 	Config.AllowedOrigins = getEnv("ALLOWED_ORIGINS", "http://localhost:3000")
 	Config.AllowedMethods = getEnv("ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS")
-	Config.AllowedHeaders = getEnv("ALLOWED_HEADERS", "Content-Type,Authorization,X-Requested-With")
+	Config.AllowedHeaders = getEnv("ALLOWED_HEADERS", "Content-Type,Authorization,X-Requested-With,X-CSRF-Token")
 
-	// NEW: OAuth Configuration
-	Config.GitHubClientID = getEnv("GITHUB_CLIENT_ID", "")
-	Config.GitHubClientSecret = getEnv("GITHUB_CLIENT_SECRET", "")
-	Config.GitHubRedirectURI = getEnv("GITHUB_REDIRECT_URI", "http://localhost:8080/api/auth/github/callback")
-
-	// GOOGLE OAUTH CONFIG LINES:
-	Config.GoogleClientID = getEnv("GOOGLE_CLIENT_ID", "")
-	Config.GoogleClientSecret = getEnv("GOOGLE_CLIENT_SECRET", "")
-	Config.GoogleRedirectURI = getEnv("GOOGLE_REDIRECT_URI", "http://localhost:8080/api/auth/google/callback")
+	// OAuth / OpenID Connect providers
+	if err := loadOAuthProviders(); err != nil {
+		return err
+	}
 
 	Config.UploadDir = getEnv("UPLOAD_DIR", "./uploads/")
 	Config.MaxImagesPerPost = getEnvAsInt("MAX_IMAGES_PER_POST", 5)
 	Config.MaxImagesPerMessage = getEnvAsInt("MAX_IMAGES_PER_MESSAGE", 3)
 	Config.MaxMessageImageSize = int64(getEnvAsInt("MAX_MESSAGE_IMAGE_SIZE", 5*1024*1024)) // 5MB default
+	Config.AvatarSize = getEnvAsInt("AVATAR_SIZE", 256)
+	Config.MaxAvatarFileSize = int64(getEnvAsInt("MAX_AVATAR_FILE_SIZE", 5*1024*1024)) // 5MB default
 
-	return nil
+	return loadSecurityConfig()
 }
 
 // LoadEnv function manually loads .env file into environment variables
@@ -225,3 +428,12 @@ func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// OAuth provider types
+const (
+	OAuthTypeOIDC   = "oidc"   // OpenID Connect: endpoints from discovery, identity from a verified ID token
+	OAuthTypeOAuth2 = "oauth2" // plain OAuth 2.0: identity from a JSON user info endpoint
+	OAuthTypeGitHub = "github" // GitHub (or GitHub Enterprise), which also needs the emails endpoint
+)
+
+// OAuthProviderConfig is one sign-in provider from OAUTH_PROVIDERS. The name is part of the
+// login and callback URLs and is stored with every linked account, so it must not change
+// once users have signed in with the provider.
+type OAuthProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Type         string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+
+	// OIDC providers only need the issuer; explicitly set endpoints override discovered ones
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+
+	// Claims (or user info fields) the account is built from
+	IDClaim       string
+	EmailClaim    string
+	UsernameClaim string
+	AvatarClaim   string
+
+	// TrustEmail treats the address of an oauth2 provider without an email_verified field as verified
+	TrustEmail bool
+}
+
+// oauthProviderNamePattern keeps provider names safe to use in URL paths and env var names
+var oauthProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// oauthPresets are the defaults of well-known providers; every field can still be overridden
+var oauthPresets = map[string]OAuthProviderConfig{
+	"github": {
+		DisplayName:   "GitHub",
+		Type:          OAuthTypeGitHub,
+		AuthURL:       "https://github.com/login/oauth/authorize",
+		TokenURL:      "https://github.com/login/oauth/access_token",
+		UserInfoURL:   "https://api.github.com/user",
+		Scopes:        []string{"user:email"},
+		IDClaim:       "id",
+		EmailClaim:    "email",
+		UsernameClaim: "login",
+		AvatarClaim:   "avatar_url",
+	},
+	"google": {
+		DisplayName: "Google",
+		Type:        OAuthTypeOIDC,
+		Issuer:      "https://accounts.google.com",
+	},
+	"gitlab": {
+		DisplayName:   "GitLab",
+		Type:          OAuthTypeOIDC,
+		Issuer:        "https://gitlab.com",
+		UsernameClaim: "nickname",
+	},
+}
+
+// loadOAuthProviders reads OAUTH_PROVIDERS and the OAUTH_<NAME>_* variables of every listed provider.
+// Without OAUTH_PROVIDERS, GitHub and Google are enabled when GITHUB_CLIENT_ID / GOOGLE_CLIENT_ID are set.
+func loadOAuthProviders() error {
+	var names []string
+	if list := getEnv("OAUTH_PROVIDERS", ""); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	} else {
+		for _, name := range []string{"github", "google"} {
+			if legacyOAuthEnv(name, "CLIENT_ID") != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	Config.OAuthProviders = nil
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !oauthProviderNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid OAuth provider name %q: use lowercase letters, digits and dashes", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("OAuth provider %q is listed twice in OAUTH_PROVIDERS", name)
+		}
+		seen[name] = true
+
+		provider, err := loadOAuthProvider(name)
+		if err != nil {
+			return err
+		}
+		Config.OAuthProviders = append(Config.OAuthProviders, provider)
+	}
+	return loadOAuthTokenKey()
+}
+
+// loadOAuthTokenKey reads the AES-256 key that encrypts provider access tokens in the database.
+// Without OAUTH_TOKEN_KEY a random key is used outside production; tokens stored with it cannot
+// be read after a restart, which only matters once something uses them besides sign-in.
+func loadOAuthTokenKey() error {
+	encoded := getEnv("OAUTH_TOKEN_KEY", "")
+	if encoded == "" {
+		if Config.IsProduction() && len(Config.OAuthProviders) > 0 {
+			return fmt.Errorf("OAUTH_TOKEN_KEY is required in production when OAuth providers are configured")
+		}
+		Config.OAuthTokenKey = make([]byte, 32)
+		if _, err := rand.Read(Config.OAuthTokenKey); err != nil {
+			return fmt.Errorf("failed to generate OAuth token key: %w", err)
+		}
+		if len(Config.OAuthProviders) > 0 {
+			log.Println("OAUTH_TOKEN_KEY is not set, using a random key: stored provider tokens are unreadable after a restart")
+		}
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("OAUTH_TOKEN_KEY must be 32 bytes encoded as base64 (openssl rand -base64 32)")
+	}
+	Config.OAuthTokenKey = key
+	return nil
+}
+
+// loadOAuthProvider builds one provider from its preset (if any) and its environment variables
+func loadOAuthProvider(name string) (OAuthProviderConfig, error) {
+	p := oauthPresets[name]
+	p.Name = name
+	prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+	env := func(key, fallback string) string {
+		if value := getEnv(prefix+key, ""); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	p.ClientID = env("CLIENT_ID", legacyOAuthEnv(name, "CLIENT_ID"))
+	p.ClientSecret = env("CLIENT_SECRET", legacyOAuthEnv(name, "CLIENT_SECRET"))
+	p.Issuer = strings.TrimSuffix(env("ISSUER", p.Issuer), "/")
+	p.AuthURL = env("AUTH_URL", p.AuthURL)
+	p.TokenURL = env("TOKEN_URL", p.TokenURL)
+	p.UserInfoURL = env("USERINFO_URL", p.UserInfoURL)
+	p.JWKSURL = env("JWKS_URL", p.JWKSURL)
+	p.DisplayName = env("DISPLAY_NAME", p.DisplayName)
+	p.RedirectURI = env("REDIRECT_URI", strings.TrimSuffix(Config.BackendBaseURL, "/")+"/api/auth/"+name+"/callback")
+	p.TrustEmail = getEnvAsBool(prefix+"TRUST_EMAIL", false)
+
+	if p.Type == "" {
+		p.Type = OAuthTypeOAuth2
+		if p.Issuer != "" {
+			p.Type = OAuthTypeOIDC
+		}
+	}
+	p.Type = env("TYPE", p.Type)
+
+	defaultScopes := p.Scopes
+	if p.Type == OAuthTypeOIDC && defaultScopes == nil {
+		defaultScopes = []string{"openid", "email", "profile"}
+	}
+	p.Scopes = strings.Fields(strings.ReplaceAll(env("SCOPES", strings.Join(defaultScopes, " ")), ",", " "))
+
+	p.IDClaim = env("ID_CLAIM", defaultString(p.IDClaim, "sub"))
+	p.EmailClaim = env("EMAIL_CLAIM", defaultString(p.EmailClaim, "email"))
+	p.UsernameClaim = env("USERNAME_CLAIM", defaultString(p.UsernameClaim, "preferred_username"))
+	p.AvatarClaim = env("AVATAR_CLAIM", defaultString(p.AvatarClaim, "picture"))
+	if p.DisplayName == "" {
+		p.DisplayName = strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	if p.ClientID == "" {
+		return p, fmt.Errorf("OAuth provider %q: %sCLIENT_ID is required", name, prefix)
+	}
+	switch p.Type {
+	case OAuthTypeOIDC:
+		if p.Issuer == "" {
+			return p, fmt.Errorf("OAuth provider %q: %sISSUER is required for OpenID Connect", name, prefix)
+		}
+		if !slices.Contains(p.Scopes, "openid") {
+			p.Scopes = append([]string{"openid"}, p.Scopes...)
+		}
+	case OAuthTypeOAuth2, OAuthTypeGitHub:
+		if p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "" {
+			return p, fmt.Errorf("OAuth provider %q: set %sISSUER for OpenID Connect, or %sAUTH_URL, %sTOKEN_URL and %sUSERINFO_URL", name, prefix, prefix, prefix, prefix)
+		}
+	default:
+		return p, fmt.Errorf("OAuth provider %q: invalid %sTYPE %q: must be oidc, oauth2 or github", name, prefix, p.Type)
+	}
+	return p, nil
+}
+
+// legacyOAuthEnv reads the GITHUB_* and GOOGLE_* variables of earlier versions
+func legacyOAuthEnv(name, key string) string {
+	if name != "github" && name != "google" {
+		return ""
+	}
+	return os.Getenv(strings.ToUpper(name) + "_" + key)
+}
+
+// defaultString returns value, or fallback when value is empty
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
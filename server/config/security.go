@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SecurityConfig holds the security settings derived from the environment by LoadConfig
+type SecurityConfig struct {
+	AllowedOrigins        []OriginPattern // parsed ALLOWED_ORIGINS
+	ContentSecurityPolicy string
+	SecureCookies         bool          // cookies are only sent over HTTPS
+	HSTSMaxAge            time.Duration // 0 disables Strict-Transport-Security
+	HSTSIncludeSubdomains bool
+}
+
+// OriginPattern is one entry of ALLOWED_ORIGINS: an exact origin such as
+// "https://forum.example.com" or a wildcard like "https://*.example.com" that matches
+// any subdomain (but not example.com itself)
+type OriginPattern struct {
+	Scheme   string
+	Host     string // without the "*." of wildcard patterns
+	Port     string // empty for the scheme's default port
+	Wildcard bool
+}
+
+// IsProduction reports whether ENVIRONMENT is "production"
+func (c *AppConfig) IsProduction() bool {
+	return strings.EqualFold(c.Environment, "production")
+}
+
+// IsOriginAllowed reports whether a request Origin matches one of the allowed origins
+func IsOriginAllowed(origin string) bool {
+	for _, pattern := range Config.Security.AllowedOrigins {
+		if pattern.Matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOriginPattern parses an ALLOWED_ORIGINS entry
+func ParseOriginPattern(raw string) (OriginPattern, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return OriginPattern{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return OriginPattern{}, fmt.Errorf("origin %q must start with http:// or https://", raw)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return OriginPattern{}, fmt.Errorf("origin %q must be scheme://host[:port] without a path", raw)
+	}
+
+	pattern := OriginPattern{Scheme: u.Scheme, Host: strings.ToLower(u.Hostname()), Port: normalizePort(u.Scheme, u.Port())}
+	if strings.HasPrefix(pattern.Host, "*.") {
+		pattern.Wildcard = true
+		pattern.Host = strings.TrimPrefix(pattern.Host, "*.")
+	}
+	if pattern.Host == "" || strings.Contains(pattern.Host, "*") {
+		return OriginPattern{}, fmt.Errorf("origin %q: wildcards are only allowed as the first label (https://*.example.com)", raw)
+	}
+	return pattern, nil
+}
+
+// Matches reports whether origin (as sent by a browser) is covered by the pattern
+func (p OriginPattern) Matches(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != p.Scheme || normalizePort(u.Scheme, u.Port()) != p.Port {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if p.Wildcard {
+		return strings.HasSuffix(host, "."+p.Host)
+	}
+	return host == p.Host
+}
+
+// String formats the pattern as an origin, e.g. for Content-Security-Policy sources
+func (p OriginPattern) String() string {
+	host := p.Host
+	if p.Wildcard {
+		host = "*." + host
+	}
+	if p.Port != "" {
+		host = net.JoinHostPort(host, p.Port)
+	}
+	return p.Scheme + "://" + host
+}
+
+// normalizePort drops the default port of the scheme so "https://a.com:443" equals "https://a.com"
+func normalizePort(scheme, port string) string {
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return ""
+	}
+	return port
+}
+
+// loadSecurityConfig builds Config.Security. Production defaults to Secure cookies with the
+// __Host- prefix and HSTS; every setting can be overridden.
+func loadSecurityConfig() error {
+	production := Config.IsProduction()
+
+	var origins []OriginPattern
+	for _, entry := range strings.Split(Config.AllowedOrigins, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, err := ParseOriginPattern(entry)
+		if err != nil {
+			return fmt.Errorf("ALLOWED_ORIGINS: %w", err)
+		}
+		origins = append(origins, pattern)
+	}
+
+	security := SecurityConfig{
+		AllowedOrigins:        origins,
+		SecureCookies:         getEnvAsBool("COOKIE_SECURE", production),
+		HSTSIncludeSubdomains: getEnvAsBool("HSTS_INCLUDE_SUBDOMAINS", false),
+	}
+
+	hstsDefault := time.Duration(0)
+	if production {
+		hstsDefault = 365 * 24 * time.Hour
+	}
+	security.HSTSMaxAge = getEnvAsDuration("HSTS_MAX_AGE", hstsDefault)
+
+	// __Host- cookies must be Secure, have Path=/ and no Domain, so they cannot be set or
+	// overwritten by a subdomain or over plain HTTP
+	if getEnvAsBool("COOKIE_HOST_PREFIX", production) {
+		if !security.SecureCookies {
+			return fmt.Errorf("COOKIE_HOST_PREFIX requires COOKIE_SECURE")
+		}
+		Config.SessionName = withHostPrefix(Config.SessionName)
+		Config.CSRFCookieName = withHostPrefix(Config.CSRFCookieName)
+		Config.OAuthCookieName = withHostPrefix(Config.OAuthCookieName)
+		Config.TwoFactorCookieName = withHostPrefix(Config.TwoFactorCookieName)
+	}
+
+	security.ContentSecurityPolicy = getEnv("CONTENT_SECURITY_POLICY", buildContentSecurityPolicy(origins))
+
+	Config.Security = security
+	return nil
+}
+
+// buildContentSecurityPolicy returns the CSP for the JSON API: nothing may load except
+// connections from the API itself and the allowed frontends
+func buildContentSecurityPolicy(origins []OriginPattern) string {
+	connectSources := []string{"'self'"}
+	for _, origin := range origins {
+		connectSources = append(connectSources, origin.String())
+	}
+	if extra := strings.TrimSpace(getEnv("CSP_CONNECT_SRC", "")); extra != "" {
+		connectSources = append(connectSources, strings.Fields(extra)...)
+	}
+
+	return strings.Join([]string{
+		"default-src 'none'", // Block everything by default
+		"connect-src " + strings.Join(connectSources, " "), // Allow API calls
+		"frame-ancestors 'none'",                           // Prevent iframe embedding
+		"base-uri 'self'",                                  // Prevent base tag hijacking
+		"form-action 'self'",                               // Prevent form hijacking
+	}, "; ")
+}
+
+// withHostPrefix adds the __Host- cookie prefix once
+func withHostPrefix(name string) string {
+	if strings.HasPrefix(name, "__Host-") {
+		return name
+	}
+	return "__Host-" + name
+}
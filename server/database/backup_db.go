@@ -0,0 +1,387 @@
+package database
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+)
+
+// Backup archive layout: a gzipped tar with the manifest, a consistent copy of the
+// database and every file of the upload directory
+const (
+	backupPrefix       = "forum-backup-"
+	backupSuffix       = ".tar.gz"
+	backupTimeLayout   = "20060102-150405.000"
+	backupManifestName = "manifest.json"
+	backupDatabaseName = "forum.db"
+	backupUploadsDir   = "uploads"
+)
+
+// CreateBackup writes a new backup archive to dir while the database stays online.
+// VACUUM INTO produces a transactionally consistent snapshot without blocking writers.
+func CreateBackup(db *sql.DB, dir string) (*models.BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	name := backupPrefix + createdAt.Format(backupTimeLayout) + backupSuffix
+	archivePath := filepath.Join(dir, name)
+	snapshotPath := archivePath + ".db.tmp"
+	partialPath := archivePath + ".partial"
+	defer os.Remove(snapshotPath)
+	defer os.Remove(partialPath)
+
+	if _, err := db.Exec("VACUUM INTO ?", snapshotPath); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %v", err)
+	}
+
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %v", err)
+	}
+
+	manifest := models.BackupManifest{SchemaVersion: version, CreatedAt: createdAt}
+	if err := writeBackupArchive(file, snapshotPath, &manifest); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup file: %v", err)
+	}
+
+	// Only complete archives ever get the final name
+	if err := os.Rename(partialPath, archivePath); err != nil {
+		return nil, fmt.Errorf("failed to finalize backup: %v", err)
+	}
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BackupInfo{Name: name, Size: info.Size(), CreatedAt: createdAt}, nil
+}
+
+// writeBackupArchive streams the snapshot, the uploads and finally the manifest into w
+func writeBackupArchive(w io.Writer, snapshotPath string, manifest *models.BackupManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := addFileToArchive(tw, snapshotPath, backupDatabaseName); err != nil {
+		return err
+	}
+
+	// A missing upload directory simply means nothing was uploaded yet
+	uploadDir := filepath.Clean(config.Config.UploadDir)
+	err := filepath.WalkDir(uploadDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == uploadDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(uploadDir, path)
+		if err != nil {
+			return err
+		}
+		manifest.Uploads++
+		return addFileToArchive(tw, path, backupUploadsDir+"/"+filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive uploads: %v", err)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    backupManifestName,
+		Mode:    0o644,
+		Size:    int64(len(manifestJSON)),
+		ModTime: manifest.CreatedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFileToArchive(tw *tar.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// RestoreBackup replaces the database and the upload directory with the contents of an archive.
+// The server must be stopped. The current data is kept next to the originals with a
+// ".before-restore-<timestamp>" suffix, and older schema versions are migrated on the next start.
+func RestoreBackup(archivePath string) (*models.BackupManifest, error) {
+	dbPath := config.Config.DBPath
+	uploadDir := filepath.Clean(config.Config.UploadDir)
+	stamp := time.Now().UTC().Format("20060102-150405")
+
+	// Extract next to the database so the final rename stays on the same filesystem
+	stagingDir := filepath.Join(filepath.Dir(dbPath), ".restore-"+stamp)
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := extractBackupArchive(archivePath, stagingDir); err != nil {
+		return nil, err
+	}
+
+	manifest, err := validateStagedBackup(stagingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the current data aside, including WAL files that belong to the old database
+	suffix := ".before-restore-" + stamp
+	for _, path := range []string{dbPath, dbPath + "-wal", dbPath + "-shm", uploadDir} {
+		if err := os.Rename(path, path+suffix); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to move %s aside: %v", path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(filepath.Join(stagingDir, backupDatabaseName), dbPath); err != nil {
+		return nil, fmt.Errorf("failed to install database: %v", err)
+	}
+
+	stagedUploads := filepath.Join(stagingDir, backupUploadsDir)
+	if _, err := os.Stat(stagedUploads); os.IsNotExist(err) {
+		err = os.MkdirAll(uploadDir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := os.Rename(stagedUploads, uploadDir); err != nil {
+		return nil, fmt.Errorf("failed to install uploads: %v", err)
+	}
+
+	return manifest, nil
+}
+
+// extractBackupArchive unpacks regular files from the archive into dir, rejecting unsafe paths
+func extractBackupArchive(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("invalid backup archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid backup archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid backup archive: unsafe path %q", header.Name)
+		}
+
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// validateStagedBackup checks the manifest and the extracted database before anything is replaced
+func validateStagedBackup(dir string) (*models.BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, errors.New("invalid backup archive: manifest missing")
+	}
+	var manifest models.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+
+	if manifest.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("backup schema version %d is newer than supported version %d", manifest.SchemaVersion, SchemaVersion)
+	}
+
+	dbFile := filepath.Join(dir, backupDatabaseName)
+	if _, err := os.Stat(dbFile); err != nil {
+		return nil, errors.New("invalid backup archive: database missing")
+	}
+
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version != manifest.SchemaVersion {
+		return nil, fmt.Errorf("backup database is at schema version %d but the manifest says %d", version, manifest.SchemaVersion)
+	}
+
+	var integrity string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return nil, fmt.Errorf("failed to check backup integrity: %v", err)
+	}
+	if integrity != "ok" {
+		return nil, fmt.Errorf("backup database failed integrity check: %s", integrity)
+	}
+
+	return &manifest, nil
+}
+
+// ListBackups returns the backup archives in dir, newest first
+func ListBackups(dir string) ([]models.BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.BackupInfo{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []models.BackupInfo{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !isBackupName(name) || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		// The timestamp in the name is when the snapshot was taken; fall back to the file time
+		createdAt, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+		if err != nil {
+			createdAt = info.ModTime().UTC()
+		}
+		backups = append(backups, models.BackupInfo{Name: name, Size: info.Size(), CreatedAt: createdAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// PruneBackups applies the retention policy: the newest keep backups are always kept,
+// older ones are removed once they exceed maxAge (or right away when maxAge is 0)
+func PruneBackups(dir string, keep int, maxAge time.Duration) ([]string, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	cutoff := time.Now().Add(-maxAge)
+	for i, backup := range backups {
+		if i < keep {
+			continue
+		}
+		if maxAge > 0 && backup.CreatedAt.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, backup.Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Name)
+	}
+
+	return removed, nil
+}
+
+// BackupPath resolves a backup name from the API to a file inside dir
+func BackupPath(dir, name string) (string, error) {
+	if !isBackupName(name) || filepath.Base(name) != name {
+		return "", errors.New("invalid backup name")
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.New("backup not found")
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix)
+}
@@ -61,9 +61,20 @@ func InitDB() (*sql.DB, error) {
 			return nil, fmt.Errorf("failed to populate categories: %v", err)
 		}
 
+		if err := markSchemaCurrent(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+
 		fmt.Println("Database initialized successfully.")
 	} else {
 		fmt.Println("Database already exists. Skipping initialization.")
+
+		// Bring older databases up to the current schema
+		if err := migrateSchema(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %v", err)
+		}
 	}
 
 	return db, nil
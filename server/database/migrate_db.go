@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetSchemaVersion returns the schema version stored in the database file
+func GetSchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// setSchemaVersion stores the schema version inside the current transaction
+func setSchemaVersion(tx *sql.Tx, version int) error {
+	// PRAGMA does not accept bound parameters
+	_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	if err != nil {
+		return fmt.Errorf("failed to set schema version: %v", err)
+	}
+	return nil
+}
+
+// migrateSchema applies every migration newer than the database's current version
+func migrateSchema(db *sql.DB) error {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := applyMigration(db, version); err != nil {
+			return err
+		}
+		fmt.Printf("Migrated database schema to version %d\n", version+1)
+	}
+
+	return nil
+}
+
+// applyMigration runs a single migration step atomically
+func applyMigration(db *sql.DB, version int) error {
+	// Start a transaction for atomicity
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range SchemaMigrations[version] {
+		_, err = tx.Exec(stmt)
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %s: %v", version+1, stmt, err)
+		}
+	}
+
+	if err := setSchemaVersion(tx, version+1); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
+
+// markSchemaCurrent records that a freshly created database is already at the latest version
+func markSchemaCurrent(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := setSchemaVersion(tx, SchemaVersion); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -12,7 +12,13 @@ var TableCreationStatements = []string{
 		last_name VARCHAR(50) NOT NULL,
 		email TEXT NOT NULL UNIQUE,
 		password_hash TEXT NOT NULL, -- Store hashed password
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+		email_verified_at TIMESTAMP NULL,
+		avatar_url TEXT NOT NULL DEFAULT '', -- "/uploads/avatars/..." or empty
+		bio TEXT NOT NULL DEFAULT '',
+		location TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		deleted_at TIMESTAMP NULL -- account closed and anonymized
 	);`,
 
 	`CREATE TABLE IF NOT EXISTS oauth_user_accounts (
@@ -21,26 +27,20 @@ var TableCreationStatements = []string{
     provider_user_id TEXT NOT NULL,            -- GitHub user ID (e.g., "12345678")
     provider_email TEXT,                       -- Email from GitHub
     provider_username TEXT,                    -- GitHub username
-    access_token TEXT NOT NULL,                -- OAuth access token
+    access_token TEXT NOT NULL,                -- OAuth access token, encrypted with OAUTH_TOKEN_KEY
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (user_id, provider),
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
     UNIQUE(provider, provider_user_id)         -- One GitHub account = one forum user
 	);`,
 
-	`CREATE TABLE IF NOT EXISTS oauth_flow_states (
-    state_id TEXT PRIMARY KEY,
-    provider TEXT NOT NULL,                    -- 'github', 'google'
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    expires_at TIMESTAMP NOT NULL,             -- Expires in 15 minutes
-    
-    CHECK (provider IN ('github', 'google'))
-    );`,
+	createOAuthFlowStatesTable,
 
 	`CREATE TABLE IF NOT EXISTS sessions (
 		user_id TEXT PRIMARY KEY NOT NULL UNIQUE,
 		session_id TEXT NOT NULL UNIQUE,
 		ip_address TEXT,
+		csrf_token TEXT NOT NULL DEFAULT '', -- sent back in X-CSRF-Token on state-changing requests
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		expires_at TIMESTAMP NOT NULL,
 		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
@@ -54,9 +54,14 @@ var TableCreationStatements = []string{
 	`CREATE TABLE IF NOT EXISTS posts (
     post_id TEXT PRIMARY KEY NOT NULL UNIQUE,
     user_id TEXT NOT NULL,
+    title TEXT NULL,                   -- optional
     content TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'published')),
+    publish_at TIMESTAMP NULL,         -- scheduled publication; visible once due
+    announced_at TIMESTAMP NULL,       -- post.created webhook and mentions sent (once the post is live)
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,         -- soft delete; purged after the restore grace period
 
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );`,
@@ -76,6 +81,7 @@ var TableCreationStatements = []string{
 		content TEXT NOT NULL,
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP NULL,
+		deleted_at TIMESTAMP NULL,          -- soft delete; purged after the restore grace period
 		
 		FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
@@ -156,8 +162,212 @@ var TableCreationStatements = []string{
 		uploaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (message_id) REFERENCES messages(message_id) ON DELETE CASCADE
 	);`,
+
+	createBookmarksTable,
+	createPostRevisionsTable,
+	createCommentRevisionsTable,
+	createEmailTokensTable,
+	createUserTOTPTable,
+	createRecoveryCodesTable,
+	createPendingLoginsTable,
+	createLoginFailuresTable,
+	createAuditLogTable,
+	createAPITokensTable,
+	createWebhooksTable,
+	createWebhookDeliveriesTable,
+	createMentionsTable,
+	createLinkPreviewsTable,
 }
 
+// Tables added after the first release are kept in named constants
+// so SchemaMigrations can reuse the exact same statement for existing databases
+
+// Providers come from OAUTH_PROVIDERS, so the name is not limited to a fixed list
+const createOAuthFlowStatesTable = `CREATE TABLE IF NOT EXISTS oauth_flow_states (
+		state_id TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,                    -- configured provider name
+		browser_binding_hash TEXT NOT NULL,        -- SHA-256 of the OAuth cookie of the browser that started the login
+		code_verifier TEXT NOT NULL,               -- PKCE verifier, sent with the code exchange
+		nonce TEXT NOT NULL DEFAULT '',            -- expected in the ID token (OpenID Connect)
+		user_id TEXT NULL,                         -- signed-in user linking the provider, NULL for logins
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL              -- Expires in 15 minutes
+	);`
+
+const createBookmarksTable = `CREATE TABLE IF NOT EXISTS bookmarks (
+		user_id TEXT NOT NULL,
+		post_id TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+		-- Natural primary key - a post can be saved once per user
+		PRIMARY KEY (user_id, post_id),
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+		FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE
+	);`
+
+// Each row is one edit: the content as it was before the edit, who edited and when
+const createPostRevisionsTable = `CREATE TABLE IF NOT EXISTS post_revisions (
+		revision_id TEXT PRIMARY KEY NOT NULL UNIQUE,
+		post_id TEXT NOT NULL,
+		content TEXT NOT NULL,              -- previous content replaced by this edit
+		edited_by TEXT NOT NULL,            -- who made the edit
+		edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+		FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+		FOREIGN KEY (edited_by) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+const createCommentRevisionsTable = `CREATE TABLE IF NOT EXISTS comment_revisions (
+		revision_id TEXT PRIMARY KEY NOT NULL UNIQUE,
+		comment_id TEXT NOT NULL,
+		content TEXT NOT NULL,              -- previous content replaced by this edit
+		edited_by TEXT NOT NULL,            -- who made the edit
+		edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+		FOREIGN KEY (comment_id) REFERENCES comments(comment_id) ON DELETE CASCADE,
+		FOREIGN KEY (edited_by) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// Single-use tokens for password reset and email verification; only the SHA-256 hash is stored
+const createEmailTokensTable = `CREATE TABLE IF NOT EXISTS email_tokens (
+		token_hash TEXT PRIMARY KEY NOT NULL,
+		user_id TEXT NOT NULL,
+		email TEXT NOT NULL,                -- address the token was sent to
+		purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP NULL,
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// TOTP two-factor authentication; the secret only takes effect once confirmed_at is set
+const createUserTOTPTable = `CREATE TABLE IF NOT EXISTS user_totp (
+		user_id TEXT PRIMARY KEY NOT NULL,
+		secret TEXT NOT NULL,                       -- base32 TOTP secret
+		confirmed_at TIMESTAMP NULL,                -- NULL while enrollment is pending
+		last_used_step INTEGER NOT NULL DEFAULT 0,  -- time step of the last accepted code (replay protection)
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// Single-use two-factor recovery codes; only the SHA-256 hash is stored
+const createRecoveryCodesTable = `CREATE TABLE IF NOT EXISTS recovery_codes (
+		code_hash TEXT PRIMARY KEY NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		used_at TIMESTAMP NULL,
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// Logins that passed the password check and wait for the second factor; only the token hash is stored
+const createPendingLoginsTable = `CREATE TABLE IF NOT EXISTS pending_logins (
+		token_hash TEXT PRIMARY KEY NOT NULL,
+		user_id TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,  -- wrong codes entered so far
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// Failed login counters, keyed "user:<id>" (known accounts), "identifier:<name>" (unknown
+// usernames/emails) or "ip:<address>"
+const createLoginFailuresTable = `CREATE TABLE IF NOT EXISTS login_failures (
+		failure_key TEXT PRIMARY KEY NOT NULL,
+		failures INTEGER NOT NULL DEFAULT 0,
+		last_failure_at TIMESTAMP NOT NULL,
+		locked_until TIMESTAMP NULL             -- temporary lockout, NULL when not locked
+	);`
+
+// Security events (account and IP lockouts); user_id has no foreign key so entries outlive the account
+const createAuditLogTable = `CREATE TABLE IF NOT EXISTS audit_log (
+		event_id TEXT PRIMARY KEY NOT NULL,
+		event TEXT NOT NULL,
+		user_id TEXT NULL,
+		ip_address TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// Personal access tokens for "Authorization: Bearer"; only the SHA-256 hash is stored
+const createAPITokensTable = `CREATE TABLE IF NOT EXISTS api_tokens (
+		token_id TEXT PRIMARY KEY NOT NULL,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,                  -- comma-separated: read, write, messages
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		last_used_at TIMESTAMP NULL,
+		last_used_ip TEXT NOT NULL DEFAULT '',
+
+		FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+	);`
+
+// Outgoing webhooks registered by admins; the secret is kept in plain text because it signs deliveries
+const createWebhooksTable = `CREATE TABLE IF NOT EXISTS webhooks (
+		webhook_id TEXT PRIMARY KEY NOT NULL,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,                  -- comma-separated, e.g. post.created,comment.created
+		secret TEXT NOT NULL,                  -- HMAC-SHA256 key for X-Forum-Signature
+		active INTEGER NOT NULL DEFAULT 1,
+		created_by TEXT NOT NULL,              -- admin user ID, kept if the account is deleted
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// Delivery queue and log: one row per event and webhook, retried until it succeeds or runs out of attempts
+const createWebhookDeliveriesTable = `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		delivery_id TEXT PRIMARY KEY NOT NULL,
+		webhook_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,                 -- JSON body, identical on every attempt
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NULL,        -- NULL once the delivery succeeded or failed for good
+		last_status_code INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP NULL,
+
+		FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE CASCADE
+	);`
+
+// @username mentions; exactly one of post_id, comment_id and message_id says where the mention was written
+const createMentionsTable = `CREATE TABLE IF NOT EXISTS mentions (
+		mention_id TEXT PRIMARY KEY NOT NULL,
+		mentioned_user_id TEXT NOT NULL,
+		author_id TEXT NOT NULL,
+		post_id TEXT NULL,
+		comment_id TEXT NULL,
+		message_id TEXT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		removed_at TIMESTAMP NULL, -- an edit took the mention out; kept so putting it back does not notify again
+
+		CHECK ((post_id IS NOT NULL) + (comment_id IS NOT NULL) + (message_id IS NOT NULL) = 1),
+		FOREIGN KEY (mentioned_user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+		FOREIGN KEY (author_id) REFERENCES users(user_id) ON DELETE CASCADE,
+		FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+		FOREIGN KEY (comment_id) REFERENCES comments(comment_id) ON DELETE CASCADE,
+		FOREIGN KEY (message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+	);`
+
+// link_previews caches what was found at a URL; failed fetches are kept too so they are not retried right away
+const createLinkPreviewsTable = `CREATE TABLE IF NOT EXISTS link_previews (
+		url TEXT PRIMARY KEY NOT NULL,
+		status TEXT NOT NULL CHECK (status IN ('ok', 'failed')),
+		title TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		image_url TEXT NOT NULL DEFAULT '',
+		site_name TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		fetched_at TIMESTAMP NOT NULL
+	);`
+
 // IndexCreationStatements contains ESSENTIAL indexes only - what you'll actually use
 var IndexCreationStatements = []string{
 	// Authentication indexes (used every request)
@@ -181,7 +391,7 @@ var IndexCreationStatements = []string{
 	`CREATE INDEX IF NOT EXISTS idx_oauth_user_accounts_provider ON oauth_user_accounts(provider, provider_user_id);`,
 
 	// Cleanup expired states
-	`CREATE INDEX IF NOT EXISTS idx_oauth_flow_states_expires ON oauth_flow_states(expires_at);`,
+	createOAuthFlowStatesExpiresIndex,
 
 	// Quickly fetch images for a post
 	`CREATE INDEX IF NOT EXISTS idx_post_images_post_id ON post_images(post_id);`,
@@ -191,4 +401,165 @@ var IndexCreationStatements = []string{
 
 	// Quickly fetch images for a message
 	`CREATE INDEX IF NOT EXISTS idx_message_images_message_id ON message_images(message_id);`,
+
+	// User's saved posts ordered by time
+	createBookmarksIndex,
+
+	// Edit history of a post/comment in chronological order
+	createPostRevisionsIndex,
+	createCommentRevisionsIndex,
+
+	// Find soft-deleted content to purge
+	createPostsDeletedIndex,
+	createCommentsDeletedIndex,
+
+	// Author's drafts and scheduled posts
+	createPostsUserStatusIndex,
+
+	// Scheduled posts waiting to be announced
+	createPostsUnannouncedIndex,
+
+	// Per-address rate limit on email tokens
+	createEmailTokensEmailIndex,
+
+	// Recovery codes of a user (regenerate, count remaining)
+	createRecoveryCodesUserIndex,
+
+	// Audit log, newest first
+	createAuditLogCreatedIndex,
+
+	// A user's personal access tokens
+	createAPITokensUserIndex,
+
+	// Webhook deliveries that are due, and a webhook's delivery log
+	createWebhookDeliveriesDueIndex,
+	createWebhookDeliveriesLogIndex,
+
+	// One mention per user and post/comment/message, and a user's mentions newest first
+	createMentionsPostIndex,
+	createMentionsCommentIndex,
+	createMentionsMessageIndex,
+	createMentionsUserIndex,
+}
+
+const (
+	createBookmarksIndex         = `CREATE INDEX IF NOT EXISTS idx_bookmarks_user_created ON bookmarks(user_id, created_at DESC);`
+	createPostRevisionsIndex     = `CREATE INDEX IF NOT EXISTS idx_post_revisions_post_edited ON post_revisions(post_id, edited_at ASC);`
+	createCommentRevisionsIndex  = `CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_edited ON comment_revisions(comment_id, edited_at ASC);`
+	createPostsDeletedIndex      = `CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;`
+	createCommentsDeletedIndex   = `CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments(deleted_at) WHERE deleted_at IS NOT NULL;`
+	createPostsUserStatusIndex   = `CREATE INDEX IF NOT EXISTS idx_posts_user_status ON posts(user_id, status, publish_at);`
+	createPostsUnannouncedIndex  = `CREATE INDEX IF NOT EXISTS idx_posts_unannounced ON posts(publish_at) WHERE announced_at IS NULL AND publish_at IS NOT NULL;`
+	createEmailTokensEmailIndex  = `CREATE INDEX IF NOT EXISTS idx_email_tokens_email_purpose ON email_tokens(email, purpose, created_at);`
+	createRecoveryCodesUserIndex = `CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id);`
+	createAuditLogCreatedIndex   = `CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at DESC);`
+	createAPITokensUserIndex     = `CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id, created_at DESC);`
+
+	createWebhookDeliveriesDueIndex = `CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';`
+	createWebhookDeliveriesLogIndex = `CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);`
+
+	createOAuthFlowStatesExpiresIndex = `CREATE INDEX IF NOT EXISTS idx_oauth_flow_states_expires ON oauth_flow_states(expires_at);`
+
+	createMentionsPostIndex    = `CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_post_user ON mentions(post_id, mentioned_user_id) WHERE post_id IS NOT NULL;`
+	createMentionsCommentIndex = `CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_comment_user ON mentions(comment_id, mentioned_user_id) WHERE comment_id IS NOT NULL;`
+	createMentionsMessageIndex = `CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_message_user ON mentions(message_id, mentioned_user_id) WHERE message_id IS NOT NULL;`
+	createMentionsUserIndex    = `CREATE INDEX IF NOT EXISTS idx_mentions_user_created ON mentions(mentioned_user_id, created_at DESC);`
+)
+
+// SchemaMigrations upgrades databases created by older versions of the server.
+// Entry N brings the schema from version N to N+1 (tracked in PRAGMA user_version).
+// New databases are created directly at the latest version, so only append here.
+var SchemaMigrations = [][]string{
+	// 1: bookmarks / saved posts
+	{createBookmarksTable, createBookmarksIndex},
+	// 2: post and comment edit history
+	{createPostRevisionsTable, createCommentRevisionsTable, createPostRevisionsIndex, createCommentRevisionsIndex},
+	// 3: soft delete for posts and comments
+	{
+		`ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP NULL;`,
+		`ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP NULL;`,
+		createPostsDeletedIndex,
+		createCommentsDeletedIndex,
+	},
+	// 4: post titles, drafts and scheduled publishing
+	{
+		`ALTER TABLE posts ADD COLUMN title TEXT NULL;`,
+		`ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'published'));`,
+		`ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP NULL;`,
+		createPostsUserStatusIndex,
+	},
+	// 5: user roles (admin endpoints)
+	{
+		`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));`,
+	},
+	// 6: account deletion (anonymized accounts)
+	{
+		`ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL;`,
+	},
+	// 7: password reset and email verification; accounts from before verification existed were
+	// never asked to verify and count as verified, so an OAuth sign-up cannot release their email
+	{
+		`ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;`,
+		`UPDATE users SET email_verified_at = created_at;`,
+		createEmailTokensTable,
+		createEmailTokensEmailIndex,
+	},
+	// 8: avatars and profile bios
+	{
+		`ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN location TEXT NOT NULL DEFAULT '';`,
+	},
+	// 9: two-factor authentication
+	{createUserTOTPTable, createRecoveryCodesTable, createPendingLoginsTable, createRecoveryCodesUserIndex},
+	// 10: login brute-force protection and audit log
+	{createLoginFailuresTable, createAuditLogTable, createAuditLogCreatedIndex},
+	// 11: CSRF tokens; existing sessions get one so nobody is logged out
+	{
+		`ALTER TABLE sessions ADD COLUMN csrf_token TEXT NOT NULL DEFAULT '';`,
+		`UPDATE sessions SET csrf_token = lower(hex(randomblob(32)));`,
+	},
+	// 12: personal access tokens
+	{createAPITokensTable, createAPITokensUserIndex},
+	// 13: outgoing webhooks
+	{createWebhooksTable, createWebhookDeliveriesTable, createWebhookDeliveriesDueIndex, createWebhookDeliveriesLogIndex},
+	// 14: configurable OAuth providers; SQLite cannot drop a CHECK constraint, and flow
+	// states only live for minutes, so the table is recreated (pending logins start over)
+	{
+		`DROP TABLE IF EXISTS oauth_flow_states;`,
+		createOAuthFlowStatesTable,
+		createOAuthFlowStatesExpiresIndex,
+	},
+	// 15: PKCE, nonce and browser binding for OAuth flow states (recreated like in 14);
+	// access tokens are encrypted from now on, plaintext ones are dropped as nothing reads them
+	{
+		`DROP TABLE IF EXISTS oauth_flow_states;`,
+		createOAuthFlowStatesTable,
+		createOAuthFlowStatesExpiresIndex,
+		`UPDATE oauth_user_accounts SET access_token = '';`,
+	},
+	// 16: linking providers to a signed-in account (flow states recreated like in 14)
+	{
+		`DROP TABLE IF EXISTS oauth_flow_states;`,
+		createOAuthFlowStatesTable,
+		createOAuthFlowStatesExpiresIndex,
+	},
+	// 17: @username mentions
+	{createMentionsTable, createMentionsPostIndex, createMentionsCommentIndex, createMentionsMessageIndex, createMentionsUserIndex},
+	// 18: link preview cache
+	{createLinkPreviewsTable},
+	// 19: announcing scheduled posts when they go live; earlier versions announced every
+	// published post when it was saved, so those count as announced
+	{
+		`ALTER TABLE posts ADD COLUMN announced_at TIMESTAMP NULL;`,
+		`UPDATE posts SET announced_at = created_at WHERE status = 'published';`,
+		createPostsUnannouncedIndex,
+	},
+	// 20: mentions removed by an edit are kept, so whoever was notified once is not notified again
+	{
+		`ALTER TABLE mentions ADD COLUMN removed_at TIMESTAMP NULL;`,
+	},
 }
+
+// SchemaVersion is the schema version of a fully migrated database
+var SchemaVersion = len(SchemaMigrations)
@@ -9,3 +9,7 @@ require golang.org/x/crypto v0.38.0
 require github.com/google/uuid v1.6.0
 
 require github.com/gorilla/websocket v1.5.3
+
+require tlsserver v0.0.0
+
+replace tlsserver => ../tlsserver
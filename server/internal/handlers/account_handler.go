@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+	ws "real-time-forum/internal/websocket"
+)
+
+// ExportUserDataHandler sends the current user a ZIP with all of their personal data
+func ExportUserDataHandler(ar *repository.AccountRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		export, err := ar.GetDataExport(user.ID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to export user data")
+			return
+		}
+
+		filename := fmt.Sprintf("forum-export-%s-%s.zip", user.Username, time.Now().Format("20060102"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		w.Header().Set("Cache-Control", "no-store")
+
+		// Headers are already sent, so a failure here can only be logged
+		if err := utils.WriteDataExportZip(w, export); err != nil {
+			log.Printf("Failed to write data export for user %s: %v", user.ID, err)
+		}
+	}
+}
+
+// DeleteAccountHandler closes the current user's account after re-confirming the password
+func DeleteAccountHandler(ur *repository.UserRepository, ar *repository.AccountRepository, hub *ws.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		// Parse request body
+		var req models.DeleteAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		// Re-confirm the password; OAuth-only accounts have to set one first
+		if !confirmCurrentPassword(w, ur, user.ID, req.Password, "Incorrect password") {
+			return
+		}
+
+		images, err := ar.DeleteAccount(user.ID, config.Config.AccountDeletionPolicy)
+		if err != nil {
+			if err.Error() == "user not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			log.Printf("Failed to delete account %s: %v", user.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete account")
+			return
+		}
+
+		// Sessions are gone from the database; drop the cookie and any live connection too
+		utils.DeleteImageFilesFromDisk(images)
+		utils.ClearSessionCookie(w)
+		hub.DisconnectUser(user.ID)
+
+		utils.RespondWithSuccess(w, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// CreateBackupHandler takes an online backup of the database and uploads
+func CreateBackupHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backup, err := database.CreateBackup(db, config.Config.BackupDir)
+		if err != nil {
+			log.Printf("Backup failed: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create backup")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusCreated, backup)
+	}
+}
+
+// ListBackupsHandler lists the backups kept on the server, newest first
+func ListBackupsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backups, err := database.ListBackups(config.Config.BackupDir)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list backups")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.BackupListResponse{Backups: backups})
+	}
+}
+
+// DownloadBackupHandler exports a backup archive so it can be stored off the server
+func DownloadBackupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path, err := database.BackupPath(config.Config.BackupDir, r.PathValue("name"))
+		if err != nil {
+			switch err.Error() {
+			case "invalid backup name":
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid backup name")
+			case "backup not found":
+				utils.RespondWithError(w, http.StatusNotFound, "Backup not found")
+			default:
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read backup")
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+r.PathValue("name")+"\"")
+		http.ServeFile(w, r, path)
+	}
+}
+
+// GetAuditLogHandler returns a page of the security audit log, newest first
+func GetAuditLogHandler(ar *repository.AuditRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Parse pagination parameters
+		limit, offset := utils.ParsePaginationParams(r)
+
+		totalCount, err := ar.CountEvents()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve audit log")
+			return
+		}
+
+		events, err := ar.GetEvents(limit, offset)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve audit log")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.AuditLogResponse{
+			Events:     events,
+			Pagination: models.NewPaginationInfo(totalCount, limit, offset),
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// maxAPITokenNameLength keeps token names short enough for a list view
+const maxAPITokenNameLength = 50
+
+// ListAPITokensHandler lists the current user's personal access tokens (never the tokens themselves)
+func ListAPITokensHandler(atr *repository.APITokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		tokens, err := atr.GetTokensByUser(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve access tokens")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, tokens)
+	}
+}
+
+// CreateAPITokenHandler issues a personal access token; the plain token is only returned here
+func CreateAPITokenHandler(atr *repository.APITokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.CreateAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > maxAPITokenNameLength {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Token name must be between 1 and %d characters", maxAPITokenNameLength))
+			return
+		}
+
+		scopes, err := validateTokenScopes(req.Scopes)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ttl := config.Config.APITokenDefaultTTL
+		if req.ExpiresInDays != 0 {
+			ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		}
+		if ttl <= 0 || ttl > config.Config.APITokenMaxTTL {
+			maxDays := int(config.Config.APITokenMaxTTL / (24 * time.Hour))
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("expires_in_days must be between 1 and %d", maxDays))
+			return
+		}
+
+		plain, token, err := atr.CreateToken(user.ID, req.Name, scopes, ttl)
+		if err != nil {
+			if err.Error() == "too many tokens" {
+				utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("You can have at most %d access tokens", config.Config.APITokenMaxPerUser))
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create access token")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusCreated, models.CreateAPITokenResponse{Token: plain, APIToken: *token})
+	}
+}
+
+// RevokeAPITokenHandler deletes one of the current user's personal access tokens
+func RevokeAPITokenHandler(atr *repository.APITokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		if err := atr.DeleteToken(user.ID, r.PathValue("id")); err != nil {
+			if err.Error() == "token not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Access token not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke access token")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Access token revoked"})
+	}
+}
+
+// validateTokenScopes checks the requested scopes and removes duplicates
+func validateTokenScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("At least one scope is required")
+	}
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, scope := range requested {
+		switch scope {
+		case models.TokenScopeRead, models.TokenScopeWrite, models.TokenScopeMessages:
+		default:
+			return nil, fmt.Errorf("Invalid scope %q: must be read, write or messages", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// ToggleBookmarkHandler saves or unsaves a post for the current user
+func ToggleBookmarkHandler(br *repository.BookmarkRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		// Parse request body
+		var req models.BookmarkRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		// Validate the request
+		if err := req.Validate(); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		result, err := br.ToggleBookmark(user.ID, req.PostID)
+		if err != nil {
+			if err.Error() == "post not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Post not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to toggle bookmark")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, result)
+	}
+}
+
+// GetUserBookmarksHandler retrieves the posts saved by the current user
+// Bookmarks are private, so there is no user ID in the path
+func GetUserBookmarksHandler(pr *repository.PostsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		// Parse pagination parameters
+		limit, offset := utils.ParsePaginationParams(r)
+		// Parse sort options from query parameters
+		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+
+		totalCount, err := pr.GetCountBookmarkedPostByUser(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve bookmarks count")
+			return
+		}
+
+		posts, nextCursor, err := pr.GetPostsBookmarkedByUser(user.ID, limit, offset, cursor, user.ID, sortOptions)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve bookmarks")
+			return
+		}
+
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
+	}
+}
@@ -11,7 +11,7 @@ import (
 	"real-time-forum/internal/utils"
 )
 // ToggleCommentReactionHandler handles toggling reactions on comments
-func ToggleCommentReactionHandler(crr *repository.CommentReactionRepository, nr *repository.NotificationRepository, cr *repository.CommentRepository, ur *repository.UserRepository, pr *repository.PostsRepository) http.HandlerFunc {
+func ToggleCommentReactionHandler(crr *repository.CommentReactionRepository, nr *repository.NotificationRepository, cr *repository.CommentRepository, ur *repository.UserRepository, pr *repository.PostsRepository, wr *repository.WebhookRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -43,6 +43,16 @@ func ToggleCommentReactionHandler(crr *repository.CommentReactionRepository, nr
 			return
 		}
 
+		// Webhooks hear about every toggle, including removed and changed reactions
+		queueWebhookEvent(wr, models.WebhookEventReactionToggled, models.WebhookReactionData{
+			Target:       "comment",
+			CommentID:    req.CommentID,
+			UserID:       user.ID,
+			Username:     user.Username,
+			ReactionType: req.ReactionType,
+			Action:       result.Action,
+		})
+
 		// Create notification only for new reactions
 		if result.Action == models.ActionCommentLikeCreated || result.Action == models.ActionCommentDislikeCreated {
 			// Get comment details to know who to notify (pass empty string since we don't need user-specific data)
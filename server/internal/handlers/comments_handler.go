@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"real-time-forum/config"
+	"real-time-forum/internal/jobs"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/repository"
@@ -12,7 +14,7 @@ import (
 )
 
 // create comment handler.
-func CreateCommentHandler(cor *repository.CommentRepository, nr *repository.NotificationRepository, pr *repository.PostsRepository, ur *repository.UserRepository) http.HandlerFunc {
+func CreateCommentHandler(cor *repository.CommentRepository, nr *repository.NotificationRepository, pr *repository.PostsRepository, ur *repository.UserRepository, wr *repository.WebhookRepository, mnr *repository.MentionRepository, lu *jobs.LinkUnfurler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -52,12 +54,33 @@ func CreateCommentHandler(cor *repository.CommentRepository, nr *repository.Noti
 
 		// Create notification for post owner
 		createNewCommentNotification(pr, nr, postID, user)
+		recordMentions(ur, mnr, nr, user, commentMentionTarget(createResponse.CommentID, postID, req.Content))
+		lu.Enqueue(commentLinkPreviewJob(createResponse.CommentID, postID, req.Content))
+
+		queueWebhookEvent(wr, models.WebhookEventCommentCreated, models.WebhookCommentData{
+			CommentID: createResponse.CommentID,
+			PostID:    postID,
+			UserID:    user.ID,
+			Username:  user.Username,
+			Content:   req.Content,
+		})
 
 		// Return lightweight response
 		utils.RespondWithSuccess(w, http.StatusCreated, createResponse)
 	}
 }
 
+// commentLinkPreviewJob asks for the previews of a comment's links; comments are only written on
+// live posts, so everyone online gets them
+func commentLinkPreviewJob(commentID, postID, content string) jobs.LinkPreviewJob {
+	return jobs.LinkPreviewJob{
+		Source:   models.LinkPreviewSourceComment,
+		SourceID: commentID,
+		PostID:   postID,
+		Text:     content,
+	}
+}
+
 // Helper function to create new comment notifications
 func createNewCommentNotification(pr *repository.PostsRepository, nr *repository.NotificationRepository, postID string, user *models.User) {
 	// Get post details to know who to notify (pass nil for userID since we don't need reaction data)
@@ -94,7 +117,7 @@ func createNewCommentNotification(pr *repository.PostsRepository, nr *repository
 }
 
 // update comment handler.
-func UpdateCommentHandler(cor *repository.CommentRepository) http.HandlerFunc {
+func UpdateCommentHandler(cor *repository.CommentRepository, ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository, lu *jobs.LinkUnfurler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -135,6 +158,12 @@ func UpdateCommentHandler(cor *repository.CommentRepository) http.HandlerFunc {
 			return
 		}
 
+		// Notify users the edit mentions for the first time
+		if comment, err := cor.GetCommentByID(commentID, user.ID); err == nil {
+			recordMentions(ur, mnr, nr, user, commentMentionTarget(commentID, comment.PostID, req.Content))
+			lu.Enqueue(commentLinkPreviewJob(commentID, comment.PostID, req.Content))
+		}
+
 		// Respond with success
 		utils.RespondWithSuccess(w, http.StatusOK, "Comment updated successfully")
 	}
@@ -173,6 +202,40 @@ func DeleteCommentHandler(cor *repository.CommentRepository) http.HandlerFunc {
 	}
 }
 
+// RestoreCommentHandler restores a soft-deleted comment while the grace period is still running
+func RestoreCommentHandler(cor *repository.CommentRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		commentID := r.PathValue("id")
+		if commentID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Comment ID is required")
+			return
+		}
+
+		err := cor.RestoreComment(commentID, user.ID, config.Config.DeletedContentGracePeriod)
+		if err != nil {
+			switch err.Error() {
+			case "comment not found":
+				utils.RespondWithError(w, http.StatusNotFound, "Comment not found")
+			case "unauthorized: you can only restore your own comments":
+				utils.RespondWithError(w, http.StatusForbidden, "You can only restore your own comments")
+			case "comment is not deleted":
+				utils.RespondWithError(w, http.StatusConflict, "Comment is not deleted")
+			case "restore period has expired":
+				utils.RespondWithError(w, http.StatusGone, "The restore period for this comment has expired")
+			default:
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to restore comment")
+			}
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, "Comment restored successfully")
+	}
+}
+
 // Get ALL comments by post ID handler.
 func GetCommentsByPostIDHandler(cor *repository.CommentRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -191,6 +254,12 @@ func GetCommentsByPostIDHandler(cor *repository.CommentRepository) http.HandlerF
 		limit, offset := utils.ParsePaginationParams(r)
 		// Parse sort options using unified system
 		sortOptions := utils.ParseCommentSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 
 		// Get total count
 		totalCount, err := cor.GetCommentCountByPost(postID)
@@ -200,14 +269,14 @@ func GetCommentsByPostIDHandler(cor *repository.CommentRepository) http.HandlerF
 		}
 
 		// Get comments with sorting
-		comments, err := cor.GetCommentsByPostID(postID, limit, offset, user.ID, sortOptions)
+		comments, nextCursor, err := cor.GetCommentsByPostID(postID, limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve comments")
 			return
 		}
 
 		// Respond with paginated comments
-		utils.RespondWithPaginatedComments(w, comments, totalCount, limit, offset)
+		utils.RespondWithPaginatedComments(w, comments, totalCount, limit, offset, nextCursor)
 	}
 }
 
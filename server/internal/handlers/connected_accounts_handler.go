@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// ================================
+// CONNECTED ACCOUNTS
+// ================================
+
+// ServeConnectedAccounts lists the current user's login methods: password and linked providers
+func (h *OAuthHandler) ServeConnectedAccounts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetCurrentUser(r)
+
+	hasPassword, err := h.userRepo.HasPassword(user.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load connected accounts")
+		return
+	}
+	accounts, err := h.oauthRepo.ListOAuthAccounts(user.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load connected accounts")
+		return
+	}
+
+	utils.RespondWithSuccess(w, http.StatusOK, models.ConnectedAccountsResponse{
+		HasPassword: hasPassword,
+		Accounts:    accounts,
+		Providers:   h.providers.List(),
+	})
+}
+
+// ServeLinkStart starts linking the provider in the path to the current user. It answers with
+// the authorization URL instead of redirecting, so the request can carry the CSRF header; the
+// frontend then navigates there and the provider returns to the normal callback.
+func (h *OAuthHandler) ServeLinkStart(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetCurrentUser(r)
+
+	provider, err := h.providers.Get(r.PathValue("provider"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Unknown provider")
+		return
+	}
+
+	state, err := h.createFlowState(w, r, provider, user.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start linking")
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(state)
+	if err != nil {
+		log.Printf("OAuth link with %s failed: %v", provider.Name(), err)
+		utils.RespondWithError(w, http.StatusBadGateway, "Provider is unavailable")
+		return
+	}
+
+	utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"authorization_url": authURL})
+}
+
+// ServeUnlink removes the provider in the path from the current user, as long as the account
+// keeps another way to sign in (a password or another provider)
+func (h *OAuthHandler) ServeUnlink(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetCurrentUser(r)
+
+	hasPassword, err := h.userRepo.HasPassword(user.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to unlink account")
+		return
+	}
+
+	err = h.oauthRepo.UnlinkOAuthAccount(user.ID, r.PathValue("provider"), hasPassword)
+	if err != nil {
+		switch err.Error() {
+		case "OAuth account not found":
+			utils.RespondWithError(w, http.StatusNotFound, "Provider is not linked to this account")
+		case "last login method":
+			utils.RespondWithError(w, http.StatusConflict, "Set a password or link another provider before unlinking the last one")
+		default:
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to unlink account")
+		}
+		return
+	}
+
+	utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Account unlinked"})
+}
+
+// finishLink attaches the provider identity from the callback to the user who started linking.
+// The browser must still be signed in as that user, so a link flow cannot end on another account.
+func (h *OAuthHandler) finishLink(w http.ResponseWriter, r *http.Request, linkUserID string, identity *models.OAuthIdentity, accessToken string) {
+	user := middleware.GetCurrentUser(r)
+	if user == nil || user.ID != linkUserID || middleware.GetAPIToken(r) != nil {
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/?link_error=session_mismatch", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.oauthRepo.CreateOAuthAccount(user.ID, identity, accessToken); err != nil {
+		switch err.Error() {
+		case "OAuth account linked to another user":
+			http.Redirect(w, r, h.config.FrontendBaseURL+"/?link_error=account_in_use", http.StatusSeeOther)
+		case "provider already linked":
+			http.Redirect(w, r, h.config.FrontendBaseURL+"/?link_error=already_linked", http.StatusSeeOther)
+		default:
+			log.Printf("Linking %s account for %s failed: %v", identity.Provider, user.ID, err)
+			http.Redirect(w, r, h.config.FrontendBaseURL+"/?link_error=link_failed", http.StatusSeeOther)
+		}
+		return
+	}
+
+	http.Redirect(w, r, h.config.FrontendBaseURL+"/?linked="+identity.Provider, http.StatusSeeOther)
+}
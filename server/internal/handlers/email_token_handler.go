@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/mailer"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// forgotPasswordResponse is the same whether or not the address belongs to an account
+const forgotPasswordResponse = "If an account exists for this address, a password reset link has been sent"
+
+// ForgotPasswordHandler emails a single-use password reset link
+func ForgotPasswordHandler(ur *repository.UserRepository, etr *repository.EmailTokenRepository, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req models.ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		req.Email = strings.TrimSpace(req.Email)
+		if err := utils.ValidateEmail(req.Email); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Unknown addresses and rate-limited addresses get the same answer, so the
+		// endpoint cannot be used to find out who has an account
+		user, err := ur.GetUserByEmailOrUsername(req.Email)
+		if err == nil && strings.EqualFold(user.Email, req.Email) {
+			token, err := etr.CreateToken(user.ID, user.Email, models.EmailTokenPasswordReset, config.Config.PasswordResetTokenTTL)
+			if err != nil {
+				log.Printf("Password reset for %s not sent: %v", user.ID, err)
+			} else if err := m.Send(passwordResetEmail(user, token)); err != nil {
+				log.Printf("Failed to send password reset email: %v", err)
+			}
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": forgotPasswordResponse})
+	}
+}
+
+// ResetPasswordHandler sets a new password with a token from a reset email
+func ResetPasswordHandler(ur *repository.UserRepository, etr *repository.EmailTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req models.ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if req.Token == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Token is required")
+			return
+		}
+		if req.Password != req.ConfirmPassword {
+			utils.RespondWithError(w, http.StatusBadRequest, "Passwords do not match")
+			return
+		}
+		if err := utils.ValidatePassword(req.Password); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		token, err := etr.ConsumeToken(req.Token, models.EmailTokenPasswordReset)
+		if err != nil {
+			if err.Error() == "invalid or expired token" {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired reset link")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+			return
+		}
+
+		// Existing sessions are revoked, the user logs in again with the new password
+		if err := ur.UpdatePassword(token.UserID, req.Password, ""); err != nil {
+			if err.Error() == "user not found" {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired reset link")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Password has been reset"})
+	}
+}
+
+// SendVerificationEmailHandler (re)sends the email verification link to the current user
+func SendVerificationEmailHandler(etr *repository.EmailTokenRepository, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		if user.EmailVerified {
+			utils.RespondWithError(w, http.StatusConflict, "Email is already verified")
+			return
+		}
+
+		if err := sendVerificationEmail(etr, m, user); err != nil {
+			if err.Error() == "too many requests" {
+				utils.RespondWithError(w, http.StatusTooManyRequests, "Too many verification emails, try again later")
+				return
+			}
+			log.Printf("Failed to send verification email: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Verification email sent"})
+	}
+}
+
+// VerifyEmailHandler confirms the address with a token from a verification email
+func VerifyEmailHandler(ur *repository.UserRepository, etr *repository.EmailTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req models.VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		if req.Token == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Token is required")
+			return
+		}
+
+		token, err := etr.ConsumeToken(req.Token, models.EmailTokenEmailVerification)
+		if err != nil {
+			if err.Error() == "invalid or expired token" {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired verification link")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+			return
+		}
+
+		if err := ur.MarkEmailVerified(token.UserID, token.Email); err != nil {
+			if err.Error() == "email changed" {
+				utils.RespondWithError(w, http.StatusBadRequest, "This link is for an address that is no longer on the account")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Email verified"})
+	}
+}
+
+// sendVerificationEmail issues a verification token for the user's current address and mails it
+func sendVerificationEmail(etr *repository.EmailTokenRepository, m mailer.Mailer, user *models.User) error {
+	token, err := etr.CreateToken(user.ID, user.Email, models.EmailTokenEmailVerification, config.Config.EmailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.Config.FrontendBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	return m.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If you did not create an account, you can ignore this email.\n",
+			user.Username, link, config.Config.EmailVerificationTokenTTL),
+	})
+}
+
+func passwordResetEmail(user *models.User, token string) mailer.Message {
+	link := config.Config.FrontendBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. To choose a new password, open this link:\n\n%s\n\n"+
+			"The link expires in %s and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link, config.Config.PasswordResetTokenTTL),
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// maxUserSearchResults caps the autocomplete list
+const maxUserSearchResults = 10
+
+// usernamePrefixRegex accepts the characters a username can contain
+var usernamePrefixRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// mentionTarget describes where @mentions were written and where their notification leads
+type mentionTarget struct {
+	Source     string // models.MentionSourcePost, MentionSourceComment or MentionSourceMessage
+	SourceID   string
+	PostID     string // post the notification opens; empty for messages
+	Action     string
+	Text       string
+	OnlyUserID string // when set, nobody else can be mentioned (the recipient of a message)
+}
+
+// SearchUsersHandler lists users whose username starts with the "prefix" query parameter,
+// for @mention autocomplete
+func SearchUsersHandler(ur *repository.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		if prefix == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "prefix is required")
+			return
+		}
+
+		// Nothing longer than a username or outside its alphabet can match
+		if len(prefix) > config.Config.MaxUsernameLen || !usernamePrefixRegex.MatchString(prefix) {
+			utils.RespondWithSuccess(w, http.StatusOK, models.UserSearchResponse{Users: []models.UserSearchResult{}})
+			return
+		}
+
+		users, err := ur.SearchUsersByPrefix(prefix, maxUserSearchResults)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to search users")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.UserSearchResponse{Users: users})
+	}
+}
+
+// recordMentions stores the @mentions in target.Text and notifies users mentioned there for the
+// first time, so editing a post, comment or message does not notify the same people again, even
+// when an earlier edit had taken their mention out.
+// Errors are only logged to not break the request that wrote the content.
+func recordMentions(ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository, author *models.User, target mentionTarget) {
+	users, err := ur.GetUsersByUsernames(utils.ExtractMentions(target.Text))
+	if err != nil {
+		log.Printf("Failed to resolve mentions in %s %s: %v", target.Source, target.SourceID, err)
+		return
+	}
+
+	userIDs := []string{}
+	for _, mentioned := range users {
+		// Mentioning yourself does nothing
+		if mentioned.ID == author.ID {
+			continue
+		}
+		if target.OnlyUserID != "" && mentioned.ID != target.OnlyUserID {
+			continue
+		}
+		userIDs = append(userIDs, mentioned.ID)
+	}
+
+	added, err := mnr.SetMentions(target.Source, target.SourceID, author.ID, userIDs)
+	if err != nil {
+		log.Printf("Failed to save mentions in %s %s: %v", target.Source, target.SourceID, err)
+		return
+	}
+
+	// Preview of the text that mentions the user (first 50 chars)
+	contentPreview := target.Text
+	if runes := []rune(contentPreview); len(runes) > 50 {
+		contentPreview = string(runes[:50]) + "..."
+	}
+
+	for _, userID := range added {
+		nr.CreateNotification(&models.Notification{
+			NotificationID:     utils.GenerateUUIDToken(),
+			UserID:             userID,
+			TriggerUsername:    author.Username,
+			PostContentPreview: contentPreview,
+			PostID:             target.PostID,
+			Action:             target.Action,
+			IsRead:             false,
+			CreatedAt:          time.Now(),
+		})
+	}
+}
+
+// postMentionTarget covers the title and the content of a post
+func postMentionTarget(postID, title, content string) mentionTarget {
+	text := content
+	if title != "" {
+		text = title + "\n" + content
+	}
+	return mentionTarget{
+		Source:   models.MentionSourcePost,
+		SourceID: postID,
+		PostID:   postID,
+		Action:   models.ActionMentionedInPost,
+		Text:     text,
+	}
+}
+
+// commentMentionTarget covers a comment; the notification opens the post it belongs to
+func commentMentionTarget(commentID, postID, content string) mentionTarget {
+	return mentionTarget{
+		Source:   models.MentionSourceComment,
+		SourceID: commentID,
+		PostID:   postID,
+		Action:   models.ActionMentionedInComment,
+		Text:     content,
+	}
+}
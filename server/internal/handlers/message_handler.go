@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"real-time-forum/config"
+	"real-time-forum/internal/jobs"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/repository"
@@ -19,7 +20,7 @@ import (
 
 // SendMessageHandler handles sending a message via HTTP POST
 // After saving to DB, it broadcasts to WebSocket if recipient is online
-func SendMessageHandler(mr *repository.MessageRepository, hub *ws.Hub) http.HandlerFunc {
+func SendMessageHandler(mr *repository.MessageRepository, hub *ws.Hub, ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository, lu *jobs.LinkUnfurler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -118,11 +119,30 @@ func SendMessageHandler(mr *repository.MessageRepository, hub *ws.Hub) http.Hand
 
 		// Broadcast to WebSocket if recipient is online
 		hub.SendMessageToUser(recipientID, models.EventTypeReceiveMessage, models.ReceiveMessagePayload{
-			SenderID:   user.ID,
-			SenderName: user.Username,
-			Content:    content,
-			SentAt:     response.CreatedAt,
-			Images:     savedImages,
+			MessageID:       response.MessageID,
+			SenderID:        user.ID,
+			SenderName:      user.Username,
+			SenderAvatarURL: user.AvatarURL,
+			Content:         content,
+			SentAt:          response.CreatedAt,
+			Images:          savedImages,
+		})
+
+		// Only the recipient can see the message, so nobody else can be mentioned in it
+		recordMentions(ur, mnr, nr, user, mentionTarget{
+			Source:     models.MentionSourceMessage,
+			SourceID:   response.MessageID,
+			Action:     models.ActionMentionedInMessage,
+			Text:       content,
+			OnlyUserID: recipientID,
+		})
+
+		// Previews of a message are only for the two people in the conversation
+		lu.Enqueue(jobs.LinkPreviewJob{
+			Source:   models.LinkPreviewSourceMessage,
+			SourceID: response.MessageID,
+			Text:     content,
+			UserIDs:  []string{user.ID, recipientID},
 		})
 
 		// Return success response
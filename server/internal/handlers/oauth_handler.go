@@ -1,60 +1,108 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
 	"real-time-forum/config"
+	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/oauth"
 	"real-time-forum/internal/repository"
 	"real-time-forum/internal/utils"
 )
 
 type OAuthHandler struct {
-	oauthRepo   *repository.OAuthRepository
-	userRepo    *repository.UserRepository
-	sessionRepo *repository.SessionRepository
-	config      *config.AppConfig
+	providers     *oauth.Registry
+	oauthRepo     *repository.OAuthRepository
+	userRepo      *repository.UserRepository
+	sessionRepo   *repository.SessionRepository
+	twoFactorRepo *repository.TwoFactorRepository
+	webhookRepo   *repository.WebhookRepository
+	config        *config.AppConfig
 }
 
-func NewOAuthHandler(oauthRepo *repository.OAuthRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, cfg *config.AppConfig) *OAuthHandler {
+func NewOAuthHandler(providers *oauth.Registry, oauthRepo *repository.OAuthRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, twoFactorRepo *repository.TwoFactorRepository, webhookRepo *repository.WebhookRepository, cfg *config.AppConfig) *OAuthHandler {
 	return &OAuthHandler{
-		oauthRepo:   oauthRepo,
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		config:      cfg,
+		providers:     providers,
+		oauthRepo:     oauthRepo,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		twoFactorRepo: twoFactorRepo,
+		webhookRepo:   webhookRepo,
+		config:        cfg,
 	}
 }
 
 // ================================
-// GITHUB OAUTH FLOW HANDLERS (WEB ONLY)
+// OAUTH FLOW HANDLERS (WEB ONLY)
 // ================================
 
-// ServeGitHubLogin initiates GitHub OAuth flow (WEB ONLY)
-func (h *OAuthHandler) ServeGitHubLogin(w http.ResponseWriter, r *http.Request) {
+// ServeProviders lists the configured sign-in providers for the login page
+func (h *OAuthHandler) ServeProviders(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithSuccess(w, http.StatusOK, h.providers.List())
+}
+
+// ServeLogin initiates the OAuth flow of the provider in the path (WEB ONLY)
+func (h *OAuthHandler) ServeLogin(w http.ResponseWriter, r *http.Request) {
+
+	provider, err := h.providers.Get(r.PathValue("provider"))
+	if err != nil {
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=unknown_provider", http.StatusSeeOther)
+		return
+	}
 
-	// Create OAuth state for CSRF protection
-	state, err := h.oauthRepo.CreateOAuthState("github")
+	// Create OAuth state for CSRF protection, with the PKCE verifier and nonce
+	state, err := h.createFlowState(w, r, provider, "")
 	if err != nil {
 		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=state_failed", http.StatusSeeOther)
 		return
 	}
 
-	// Build GitHub authorization URL (WEB CALLBACK ONLY)
-	authURL := h.buildGitHubAuthURL(state.StateID)
+	// Build the authorization URL (discovers OpenID Connect endpoints on first use)
+	authURL, err := provider.AuthCodeURL(state)
+	if err != nil {
+		log.Printf("OAuth login with %s failed: %v", provider.Name(), err)
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=provider_unavailable", http.StatusSeeOther)
+		return
+	}
 
-	// Redirect user to GitHub
+	// Redirect user to the provider
 	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
 }
 
-// ServeGitHubCallback handles GitHub OAuth callback (WEB ONLY)
-func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Request) {
+// createFlowState stores a new flow state bound to this browser through the OAuth cookie.
+// An existing cookie is reused so flows started in several tabs all stay valid.
+func (h *OAuthHandler) createFlowState(w http.ResponseWriter, r *http.Request, provider *oauth.Provider, linkUserID string) (*models.OAuthFlowState, error) {
+	var browserBinding string
+	if cookie, err := r.Cookie(h.config.OAuthCookieName); err == nil && len(cookie.Value) >= 32 {
+		browserBinding = cookie.Value
+	} else {
+		if browserBinding, err = utils.GenerateSecureToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := h.oauthRepo.CreateOAuthState(provider.Name(), browserBinding, linkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.SetOAuthCookie(browserBinding, w, state.ExpiresAt)
+	return state, nil
+}
+
+// ServeCallback handles the OAuth callback of the provider in the path (WEB ONLY)
+func (h *OAuthHandler) ServeCallback(w http.ResponseWriter, r *http.Request) {
+
+	provider, err := h.providers.Get(r.PathValue("provider"))
+	if err != nil {
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=unknown_provider", http.StatusSeeOther)
+		return
+	}
 
 	// Get parameters from URL
 	code := r.URL.Query().Get("code")
@@ -63,7 +111,7 @@ func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Reques
 
 	// Handle user denial
 	if errorParam == "access_denied" {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=github_cancelled", http.StatusSeeOther)
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error="+provider.Name()+"_cancelled", http.StatusSeeOther)
 		return
 	}
 
@@ -73,29 +121,41 @@ func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Validate state (CSRF protection)
-	err := h.oauthRepo.ValidateOAuthState(state, "github")
+	// Validate state (CSRF protection); it must come back to the browser that started the login
+	browserBinding := ""
+	if cookie, err := r.Cookie(h.config.OAuthCookieName); err == nil {
+		browserBinding = cookie.Value
+	}
+	flowState, err := h.oauthRepo.ConsumeOAuthState(state, provider.Name(), browserBinding)
 	if err != nil {
 		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=invalid_state", http.StatusSeeOther)
 		return
 	}
 
-	// Exchange authorization code for access token
-	accessToken, err := h.exchangeGitHubCode(code)
+	// Exchange authorization code for tokens
+	token, err := provider.Exchange(code, flowState.CodeVerifier)
 	if err != nil {
+		log.Printf("OAuth token exchange with %s failed: %v", provider.Name(), err)
 		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=token_exchange_failed", http.StatusSeeOther)
 		return
 	}
 
-	// Get user information from GitHub
-	gitHubUser, err := h.getGitHubUserInfo(accessToken)
+	// Get the user's identity (verified ID token or user info endpoint)
+	identity, err := provider.Identity(token, flowState.Nonce)
 	if err != nil {
+		log.Printf("OAuth identity from %s failed: %v", provider.Name(), err)
 		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=user_info_failed", http.StatusSeeOther)
 		return
 	}
 
+	// Flows started from the connected accounts page link the provider instead of logging in
+	if flowState.LinkUserID != "" {
+		h.finishLink(w, r, flowState.LinkUserID, identity, token.AccessToken)
+		return
+	}
+
 	// Process the OAuth authentication
-	result, err := h.processGitHubAuthentication(gitHubUser, accessToken)
+	result, err := h.processAuthentication(identity, token.AccessToken)
 	if err != nil {
 		// Check if it's an email conflict error
 		if strings.Contains(err.Error(), "email_conflict:") {
@@ -107,8 +167,13 @@ func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Accounts with two-factor authentication finish signing in with a code
+	if h.redirectToTwoFactor(w, r, result.User.ID) {
+		return
+	}
+
 	// Create session for the authenticated user
-	session, err := h.sessionRepo.CreateSession(result.User.ID, r.RemoteAddr)
+	session, err := h.sessionRepo.CreateSession(result.User.ID, middleware.ClientIP(r))
 	if err != nil {
 		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=session_failed", http.StatusSeeOther)
 		return
@@ -116,13 +181,14 @@ func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Reques
 
 	// Set session cookie
 	utils.SetSessionCookie(session.SessionID, w, r, session.ExpiresAt)
+	utils.SetCSRFCookie(session.CSRFToken, w, session.ExpiresAt)
 
 	// Redirect to frontend with success parameters
 	var redirectURL string
 	if result.IsNewUser {
-		redirectURL = h.config.FrontendBaseURL + "/?welcome=github"
+		redirectURL = h.config.FrontendBaseURL + "/?welcome=" + provider.Name()
 	} else if result.IsLinkedAccount {
-		redirectURL = h.config.FrontendBaseURL + "/?linked=github"
+		redirectURL = h.config.FrontendBaseURL + "/?linked=" + provider.Name()
 	} else {
 		redirectURL = h.config.FrontendBaseURL + "/"
 	}
@@ -130,178 +196,47 @@ func (h *OAuthHandler) ServeGitHubCallback(w http.ResponseWriter, r *http.Reques
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
-// ================================
-// GITHUB API FUNCTIONS
-// ================================
-
-// buildGitHubAuthURL builds the GitHub authorization URL
-func (h *OAuthHandler) buildGitHubAuthURL(state string) string {
-	params := url.Values{}
-	params.Add("client_id", config.Config.GitHubClientID)
-	params.Add("redirect_uri", h.config.BackendBaseURL+"/api/auth/github/callback")
-	params.Add("scope", "user:email")
-	params.Add("state", state)
-
-	return "https://github.com/login/oauth/authorize?" + params.Encode()
-}
-
-// exchangeGitHubCode exchanges authorization code for access token
-func (h *OAuthHandler) exchangeGitHubCode(code string) (string, error) {
-	// Prepare form data
-	data := url.Values{}
-	data.Set("client_id", config.Config.GitHubClientID)
-	data.Set("client_secret", config.Config.GitHubClientSecret)
-	data.Set("code", code)
-
-	// Create request
-	req, err := http.NewRequest("POST", "https://github.com/login/oauth/access_token", strings.NewReader(data.Encode()))
-	if err != nil {
-		return "", fmt.Errorf("failed to create token request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("token exchange request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read token response: %w", err)
-	}
-
-	// Parse JSON response
-	var tokenResp models.GitHubTokenResponse
-	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", fmt.Errorf("failed to parse token response: %w", err)
-	}
-
-	// Check for errors
-	if tokenResp.Error != "" {
-		return "", fmt.Errorf("GitHub OAuth error: %s - %s", tokenResp.Error, tokenResp.ErrorDescription)
-	}
-
-	if tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("no access token received from GitHub")
-	}
-
-	return tokenResp.AccessToken, nil
-}
-
-// getGitHubUserInfo fetches user information from GitHub API
-func (h *OAuthHandler) getGitHubUserInfo(accessToken string) (*models.GitHubUser, error) {
-	// Create request to GitHub user API
-	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user info request: %w", err)
-	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("user info request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-
-	// Read and parse response
-	body, err := io.ReadAll(resp.Body)
+// redirectToTwoFactor sends users with two-factor authentication to the frontend code prompt
+// with a pending login instead of creating a session. It reports whether it responded.
+func (h *OAuthHandler) redirectToTwoFactor(w http.ResponseWriter, r *http.Request, userID string) bool {
+	enabled, err := h.twoFactorRepo.IsEnabled(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read user info response: %w", err)
-	}
-
-	var gitHubUser models.GitHubUser
-	if err := json.Unmarshal(body, &gitHubUser); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
-	}
-
-	// Get user's primary email if not included
-	if gitHubUser.Email == "" {
-		email, err := h.getGitHubUserEmail(accessToken)
-		if err == nil {
-			gitHubUser.Email = email
-		}
-	}
-
-	return &gitHubUser, nil
-}
-
-// getGitHubUserEmail fetches user's primary email from GitHub API
-func (h *OAuthHandler) getGitHubUserEmail(accessToken string) (string, error) {
-	// Create request to GitHub emails API
-	req, err := http.NewRequest("GET", "https://api.github.com/user/emails", nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create emails request: %w", err)
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=auth_failed", http.StatusSeeOther)
+		return true
 	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("emails request failed: %w", err)
+	if !enabled {
+		return false
 	}
-	defer resp.Body.Close()
 
-	// Read and parse response
-	body, err := io.ReadAll(resp.Body)
+	pendingToken, expiresAt, err := h.twoFactorRepo.CreatePendingLogin(userID)
 	if err != nil {
-		return "", fmt.Errorf("failed to read emails response: %w", err)
-	}
-
-	var emails []models.GitHubEmail
-	if err := json.Unmarshal(body, &emails); err != nil {
-		return "", fmt.Errorf("failed to parse emails: %w", err)
-	}
-
-	// Find primary verified email
-	for _, email := range emails {
-		if email.Primary && email.Verified {
-			return email.Email, nil
-		}
+		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=session_failed", http.StatusSeeOther)
+		return true
 	}
 
-	return "", fmt.Errorf("no primary verified email found")
+	// The token travels in a cookie; /api/auth/login/2fa reads it when the body has none
+	utils.SetTwoFactorCookie(pendingToken, w, expiresAt)
+	http.Redirect(w, r, h.config.FrontendBaseURL+"/login?two_factor=required", http.StatusSeeOther)
+	return true
 }
 
 // ================================
 // AUTHENTICATION PROCESSING
 // ================================
 
-// processGitHubAuthentication handles the core OAuth authentication logic
-func (h *OAuthHandler) processGitHubAuthentication(gitHubUser *models.GitHubUser, accessToken string) (*models.OAuthProcessResult, error) {
-	providerUserID := strconv.Itoa(gitHubUser.ID)
-
-	// Check if this GitHub account is already linked
-	existingOAuth, err := h.oauthRepo.GetOAuthAccountByProvider("github", providerUserID)
+// processAuthentication handles the core OAuth authentication logic
+func (h *OAuthHandler) processAuthentication(identity *models.OAuthIdentity, accessToken string) (*models.OAuthProcessResult, error) {
+	// Check if this provider account is already linked
+	existingOAuth, err := h.oauthRepo.GetOAuthAccountByProvider(identity.Provider, identity.ProviderUserID)
 	if err == nil {
-		// GitHub account already linked - get the user and login
+		// Provider account already linked - get the user and login
 		user, err := h.userRepo.GetUserBySessionID(existingOAuth.UserID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get linked user: %w", err)
 		}
 
 		// Update the access token
-		h.oauthRepo.UpdateOAuthToken(existingOAuth.UserID, "github", accessToken)
+		h.oauthRepo.UpdateOAuthToken(existingOAuth.UserID, identity.Provider, accessToken)
 
 		return &models.OAuthProcessResult{
 			User:            user,
@@ -311,30 +246,40 @@ func (h *OAuthHandler) processGitHubAuthentication(gitHubUser *models.GitHubUser
 	}
 
 	// Check for email conflicts with existing users
-	if gitHubUser.Email != "" {
-		conflictingUser, err := h.oauthRepo.CheckEmailConflict(gitHubUser.Email)
+	if identity.Email != "" {
+		// A provider-verified address wins over an unverified local claim
+		if identity.EmailVerified {
+			if err := h.oauthRepo.ReleaseUnverifiedEmail(identity.Email); err != nil {
+				return nil, err
+			}
+		}
+
+		conflictingUser, err := h.oauthRepo.CheckEmailConflict(identity.Email)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check email conflict: %w", err)
 		}
 
 		if conflictingUser != nil {
 			// Email exists - return specific error for email conflict
-			return nil, fmt.Errorf("email_conflict: account with email %s already exists", gitHubUser.Email)
+			return nil, fmt.Errorf("email_conflict: account with email %s already exists", identity.Email)
 		}
 	}
 
 	// Create new user with OAuth data
-	newUser, err := h.createOAuthUser(gitHubUser)
+	newUser, err := h.createOAuthUser(identity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
 	}
 
 	// Link OAuth account to new user
-	err = h.oauthRepo.CreateOAuthAccount(newUser.ID, gitHubUser, accessToken)
+	err = h.oauthRepo.CreateOAuthAccount(newUser.ID, identity, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to link OAuth account: %w", err)
 	}
 
+	// Signing up through a provider is a registration like any other
+	queueWebhookEvent(h.webhookRepo, models.WebhookEventUserRegistered, models.WebhookUserData{UserID: newUser.ID, Username: newUser.Username})
+
 	return &models.OAuthProcessResult{
 		User:            newUser,
 		IsNewUser:       true,
@@ -342,16 +287,24 @@ func (h *OAuthHandler) processGitHubAuthentication(gitHubUser *models.GitHubUser
 	}, nil
 }
 
-// createOAuthUser creates a new user from GitHub OAuth data
-func (h *OAuthHandler) createOAuthUser(gitHubUser *models.GitHubUser) (*models.User, error) {
-	// Generate a unique username based on GitHub login
-	username := h.generateUniqueUsername(gitHubUser.Login)
+// createOAuthUser creates a new user from a provider identity
+func (h *OAuthHandler) createOAuthUser(identity *models.OAuthIdentity) (*models.User, error) {
+	// Generate a unique username based on the provider username
+	username, err := h.generateUniqueUsername(identity.Username)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create user registration data
+	// Create user registration data. Providers don't share age or gender, so the
+	// placeholders of anonymized accounts are used until the user edits the profile.
 	reg := models.UserRegistration{
-		Username: username,
-		Email:    gitHubUser.Email,
-		Password: "", // OAuth users don't have passwords
+		Username:  username,
+		Email:     identity.Email,
+		Password:  "", // OAuth users don't have passwords
+		Age:       13,
+		Gender:    "Other",
+		FirstName: identity.FirstName,
+		LastName:  identity.LastName,
 	}
 
 	// Create the user
@@ -360,300 +313,77 @@ func (h *OAuthHandler) createOAuthUser(gitHubUser *models.GitHubUser) (*models.U
 		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
 	}
 
-	return user, nil
-}
-
-// generateUniqueUsername generates a unique username based on GitHub login
-func (h *OAuthHandler) generateUniqueUsername(githubLogin string) string {
-	baseUsername := githubLogin
-	username := baseUsername
-	counter := 1
-
-	// Check if username exists and append number if needed
-	for counter <= 100 {
-		// This is a simplified approach - in production you'd want proper username checking
-		return username
-	}
-
-	// Fallback to UUID if all variations taken
-	return "user_" + utils.GenerateUUIDToken()[:8]
-}
-
-// ================================
-// GOOGLE OAUTH FLOW HANDLERS
-// ================================
-
-// ServeGoogleLogin initiates Google OAuth flow
-func (h *OAuthHandler) ServeGoogleLogin(w http.ResponseWriter, r *http.Request) {
-
-	// Create OAuth state for CSRF protection
-	state, err := h.oauthRepo.CreateOAuthState("google")
-	if err != nil {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=state_failed", http.StatusSeeOther)
-		return
+	// Only addresses the provider vouches for count as verified
+	if identity.EmailVerified {
+		if err := h.userRepo.MarkEmailVerified(user.ID, user.Email); err != nil {
+			return nil, fmt.Errorf("failed to mark email verified: %w", err)
+		}
+		user.EmailVerified = true
 	}
 
-	// Build Google authorization URL
-	authURL := h.buildGoogleAuthURL(state.StateID)
+	h.importProviderAvatar(user, identity.AvatarURL)
 
-	// Redirect user to Google
-	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+	return user, nil
 }
 
-// ServeGoogleCallback handles Google OAuth callback
-func (h *OAuthHandler) ServeGoogleCallback(w http.ResponseWriter, r *http.Request) {
-
-	// Get parameters from URL
-	code := r.URL.Query().Get("code")
-	state := r.URL.Query().Get("state")
-	errorParam := r.URL.Query().Get("error")
-
-	// Handle user denial
-	if errorParam == "access_denied" {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=google_cancelled", http.StatusSeeOther)
+// importProviderAvatar copies the provider's profile picture as the new user's avatar.
+// Sign-up continues without an avatar if the picture cannot be imported.
+func (h *OAuthHandler) importProviderAvatar(user *models.User, pictureURL string) {
+	if pictureURL == "" {
 		return
 	}
 
-	// Validate required parameters
-	if code == "" || state == "" {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=missing_parameters", http.StatusSeeOther)
-		return
-	}
-
-	// Validate state (CSRF protection)
-	err := h.oauthRepo.ValidateOAuthState(state, "google")
+	avatarURL, err := utils.ImportAvatarFromURL(pictureURL)
 	if err != nil {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=invalid_state", http.StatusSeeOther)
+		log.Printf("Failed to import avatar for %s: %v", user.ID, err)
 		return
 	}
 
-	// Exchange authorization code for access token
-	accessToken, err := h.exchangeGoogleCode(code)
-	if err != nil {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=token_exchange_failed", http.StatusSeeOther)
+	if _, err := h.userRepo.SetAvatar(user.ID, avatarURL); err != nil {
+		utils.DeleteUploadedFile(avatarURL)
+		log.Printf("Failed to save avatar for %s: %v", user.ID, err)
 		return
 	}
-
-	// Get user information from Google
-	googleUser, err := h.getGoogleUserInfo(accessToken)
-	if err != nil {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=user_info_failed", http.StatusSeeOther)
-		return
-	}
-
-	// Process the OAuth authentication
-	result, err := h.processGoogleAuthentication(googleUser, accessToken)
-	if err != nil {
-		// Check if it's an email conflict error
-		if strings.Contains(err.Error(), "email_conflict:") {
-			http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=email_conflict", http.StatusSeeOther)
-			return
-		}
-		// Other authentication errors
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=auth_failed", http.StatusSeeOther)
-		return
-	}
-
-	// Create session for the authenticated user
-	session, err := h.sessionRepo.CreateSession(result.User.ID, r.RemoteAddr)
-	if err != nil {
-		http.Redirect(w, r, h.config.FrontendBaseURL+"/login?error=session_failed", http.StatusSeeOther)
-		return
-	}
-
-	// Set session cookie
-	utils.SetSessionCookie(session.SessionID, w, r, session.ExpiresAt)
-
-	// Redirect to frontend with success parameters
-	var redirectURL string
-	if result.IsNewUser {
-		redirectURL = h.config.FrontendBaseURL + "/?welcome=google"
-	} else if result.IsLinkedAccount {
-		redirectURL = h.config.FrontendBaseURL + "/?linked=google"
-	} else {
-		redirectURL = h.config.FrontendBaseURL + "/"
-	}
-
-	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
-}
-
-// ================================
-// GOOGLE API FUNCTIONS
-// ================================
-
-// buildGoogleAuthURL builds the Google authorization URL
-func (h *OAuthHandler) buildGoogleAuthURL(state string) string {
-	params := url.Values{}
-	params.Add("client_id", config.Config.GoogleClientID)
-	params.Add("redirect_uri", h.config.BackendBaseURL+"/api/auth/google/callback")
-	params.Add("scope", "openid email profile")
-	params.Add("response_type", "code")
-	params.Add("state", state)
-
-	return "https://accounts.google.com/o/oauth2/v2/auth?" + params.Encode()
+	user.AvatarURL = avatarURL
 }
 
-// exchangeGoogleCode exchanges authorization code for access token
-func (h *OAuthHandler) exchangeGoogleCode(code string) (string, error) {
-	// Prepare form data
-	data := url.Values{}
-	data.Set("client_id", config.Config.GoogleClientID)
-	data.Set("client_secret", config.Config.GoogleClientSecret)
-	data.Set("code", code)
-	data.Set("grant_type", "authorization_code")
-	data.Set("redirect_uri", h.config.BackendBaseURL+"/api/auth/google/callback")
-
-	// Create request
-	req, err := http.NewRequest("POST", "https://oauth2.googleapis.com/token", strings.NewReader(data.Encode()))
-	if err != nil {
-		return "", fmt.Errorf("failed to create token request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("token exchange request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read token response: %w", err)
-	}
-
-	// Parse JSON response
-	var tokenResp models.GoogleTokenResponse
-	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", fmt.Errorf("failed to parse token response: %w", err)
-	}
-
-	// Check for errors
-	if tokenResp.Error != "" {
-		return "", fmt.Errorf("google oauth error: %s - %s", tokenResp.Error, tokenResp.ErrorDescription)
-	}
-
-	if tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("no access token received from Google")
-	}
-
-	return tokenResp.AccessToken, nil
-}
-
-// getGoogleUserInfo fetches user information from Google API
-func (h *OAuthHandler) getGoogleUserInfo(accessToken string) (*models.GoogleUser, error) {
-	// Create request to Google user API
-	req, err := http.NewRequest("GET", "https://www.googleapis.com/oauth2/v2/userinfo", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user info request: %w", err)
-	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("user info request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("google API returned status %d", resp.StatusCode)
-	}
-
-	// Read and parse response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read user info response: %w", err)
-	}
-
-	var googleUser models.GoogleUser
-	if err := json.Unmarshal(body, &googleUser); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
+// generateUniqueUsername turns the provider username into a valid local one, appending a
+// number when it is taken (several providers can have a user called "alice")
+func (h *OAuthHandler) generateUniqueUsername(providerUsername string) (string, error) {
+	// Keep the characters ValidateUsername allows
+	base := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		if r == ' ' || r == '-' || r == '.' {
+			return '_'
+		}
+		return -1
+	}, providerUsername)
+	if len(base) < h.config.MinUsernameLen {
+		base = "user_" + base
 	}
 
-	return &googleUser, nil
-}
-
-// processGoogleAuthentication handles the core OAuth authentication logic
-func (h *OAuthHandler) processGoogleAuthentication(googleUser *models.GoogleUser, accessToken string) (*models.OAuthProcessResult, error) {
-	// Check if this Google account is already linked
-	existingOAuth, err := h.oauthRepo.GetOAuthAccountByProvider("google", googleUser.ID)
-	if err == nil {
-		// Google account already linked - get the user and login
-		user, err := h.userRepo.GetUserBySessionID(existingOAuth.UserID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get linked user: %w", err)
+	for counter := 1; counter <= 100; counter++ {
+		suffix := ""
+		if counter > 1 {
+			suffix = strconv.Itoa(counter)
 		}
+		username := base
+		if len(username)+len(suffix) > h.config.MaxUsernameLen {
+			username = username[:h.config.MaxUsernameLen-len(suffix)]
+		}
+		username += suffix
 
-		// Update the access token
-		h.oauthRepo.UpdateOAuthToken(existingOAuth.UserID, "google", accessToken)
-
-		return &models.OAuthProcessResult{
-			User:            user,
-			IsNewUser:       false,
-			IsLinkedAccount: false,
-		}, nil
-	}
-
-	// Check for email conflicts with existing users
-	if googleUser.Email != "" {
-		conflictingUser, err := h.oauthRepo.CheckEmailConflict(googleUser.Email)
+		taken, err := h.userRepo.UsernameExists(username)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check email conflict: %w", err)
+			return "", fmt.Errorf("failed to check username: %w", err)
 		}
-
-		if conflictingUser != nil {
-			// Email exists - return specific error for email conflict
-			return nil, fmt.Errorf("email_conflict: account with email %s already exists", googleUser.Email)
+		if !taken {
+			return username, nil
 		}
 	}
 
-	// Create new user with OAuth data
-	newUser, err := h.createGoogleOAuthUser(googleUser)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
-	}
-
-	// Link OAuth account to new user
-	err = h.oauthRepo.CreateOAuthAccount(newUser.ID, googleUser, accessToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to link OAuth account: %w", err)
-	}
-
-	return &models.OAuthProcessResult{
-		User:            newUser,
-		IsNewUser:       true,
-		IsLinkedAccount: false,
-	}, nil
-}
-
-// createGoogleOAuthUser creates a new user from Google OAuth data
-func (h *OAuthHandler) createGoogleOAuthUser(googleUser *models.GoogleUser) (*models.User, error) {
-	// Generate a unique username based on Google name
-	username := h.generateUniqueUsername(googleUser.Name)
-
-	// Create user registration data
-	reg := models.UserRegistration{
-		Username: username,
-		Email:    googleUser.Email,
-		Password: "", // OAuth users don't have passwords
-	}
-
-	// Create the user
-	user, err := h.userRepo.CreateUser(reg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
-	}
-
-	return user, nil
+	// Fallback to UUID if all variations taken
+	return "user_" + utils.GenerateUUIDToken()[:8], nil
 }
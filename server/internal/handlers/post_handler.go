@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"mime/multipart"
 	"net/http"
+	"strings"
+	"time"
 
 	"real-time-forum/config"
+	"real-time-forum/internal/jobs"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/repository"
 	"real-time-forum/internal/models"
@@ -17,7 +21,7 @@ import (
 // ...
 
 // CreatePostHandler creates a new post
-func CreatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRepository, pir *repository.PostImagesRepository) http.HandlerFunc {
+func CreatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRepository, pir *repository.PostImagesRepository, wr *repository.WebhookRepository, ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository, lu *jobs.LinkUnfurler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -30,15 +34,22 @@ func CreatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 			return
 		}
 
+		// Parse title, draft status and scheduled time
+		publishing, err := parsePostPublishing(r, nil)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		content := r.FormValue("content")
-		if err := utils.ValidatePostContent(content); err != nil {
+		if err := validatePostContentFor(publishing, content); err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		// Parse and validate categories
 		categoryNames := r.MultipartForm.Value["categories"]
-		categoryIDs, err := validateCategories(categoryNames, cr)
+		categoryIDs, err := validateCategoriesFor(publishing, categoryNames, cr)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
@@ -52,19 +63,27 @@ func CreatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 		}
 
 		// Create post - now returns lightweight response
-		createResponse, err := pr.CreatePost(user.ID, content, categoryIDs, images)
+		createResponse, err := pr.CreatePost(user.ID, content, categoryIDs, images, publishing)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create post")
 			return
 		}
 
+		// Drafts and scheduled posts are announced, and notify the people they mention, once they go live
+		if publishing.IsLive(time.Now()) {
+			announcePost(pr, wr, user, createResponse.PostID, publishing, content)
+			recordMentions(ur, mnr, nr, user, postMentionTarget(createResponse.PostID, publishing.Title, content))
+		}
+
+		lu.Enqueue(postLinkPreviewJob(createResponse.PostID, content, publishing, user.ID))
+
 		// Return lightweight response
 		utils.RespondWithSuccess(w, http.StatusCreated, createResponse)
 	}
 }
 
 // UpdatePostHandler updates an existing post
-func UpdatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRepository, pir *repository.PostImagesRepository) http.HandlerFunc {
+func UpdatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRepository, pir *repository.PostImagesRepository, wr *repository.WebhookRepository, ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository, lu *jobs.LinkUnfurler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		user := middleware.GetCurrentUser(r)
@@ -93,15 +112,21 @@ func UpdatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 			return
 		}
 
-		// Parse new content/categories
-		content := r.FormValue("content")
-		if err := utils.ValidatePostContent(content); err != nil {
+		// Fields that are not sent keep their current value, so drafts can be autosaved partially
+		publishing, err := parsePostPublishing(r, post)
+		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		categoryNames := r.MultipartForm.Value["categories"]
-		categoryIDs, err := validateCategories(categoryNames, cr)
+		// Parse new content/categories, keeping the current ones when they are not sent
+		content, categoryNames := parsePostContent(r, post)
+		if err := validatePostContentFor(publishing, content); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		categoryIDs, err := validateCategoriesFor(publishing, categoryNames, cr)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
@@ -120,17 +145,30 @@ func UpdatePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 		}
 
 		// Update the post's main content and categories
-		err = pr.UpdatePost(postID, user.ID, content, categoryIDs)
+		err = pr.UpdatePost(postID, user.ID, content, categoryIDs, publishing)
 		if err != nil {
+			if err.Error() == "published posts cannot be turned back into drafts" {
+				utils.RespondWithError(w, http.StatusConflict, "Published posts cannot be turned back into drafts")
+				return
+			}
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update post")
 			return
 		}
+
+		// Going live is when the post is created as far as webhooks and mentions are concerned
+		if publishing.IsLive(time.Now()) {
+			announcePost(pr, wr, user, postID, publishing, content)
+			recordMentions(ur, mnr, nr, user, postMentionTarget(postID, publishing.Title, content))
+		}
+
+		lu.Enqueue(postLinkPreviewJob(postID, content, publishing, user.ID))
+
 		utils.RespondWithSuccess(w, http.StatusOK, nil)
 	}
 }
 
 // DeletePostHandler deletes an existing post
-func DeletePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRepository, pir *repository.PostImagesRepository) http.HandlerFunc {
+func DeletePostHandler(pr *repository.PostsRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -154,14 +192,7 @@ func DeletePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 			utils.RespondWithError(w, http.StatusForbidden, "You can only delete your own posts")
 			return
 		}
-		// Delete image files
-		images, err := pir.DeleteAllImagesForPost(postID) // Deletes records and returns metadata
-		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete post images")
-			return
-		}
-		deleteImageFilesFromDisk(images)
-		// Delete the post
+		// Soft delete the post; images stay on disk until it is purged
 		err = pr.DeletePost(postID, user.ID)
 		if err != nil {
 			if err.Error() == "post not found" {
@@ -179,6 +210,40 @@ func DeletePostHandler(pr *repository.PostsRepository, cr *repository.CategoryRe
 	}
 }
 
+// RestorePostHandler restores a soft-deleted post while the grace period is still running
+func RestorePostHandler(pr *repository.PostsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		postID := r.PathValue("id")
+		if postID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Post ID is required")
+			return
+		}
+
+		err := pr.RestorePost(postID, user.ID, config.Config.DeletedContentGracePeriod)
+		if err != nil {
+			switch err.Error() {
+			case "post not found":
+				utils.RespondWithError(w, http.StatusNotFound, "Post not found")
+			case "unauthorized: you can only restore your own posts":
+				utils.RespondWithError(w, http.StatusForbidden, "You can only restore your own posts")
+			case "post is not deleted":
+				utils.RespondWithError(w, http.StatusConflict, "Post is not deleted")
+			case "restore period has expired":
+				utils.RespondWithError(w, http.StatusGone, "The restore period for this post has expired")
+			default:
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to restore post")
+			}
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, "Post restored successfully")
+	}
+}
+
 // GetAllPostsHandler retrieves all posts with pagination and sorting
 func GetAllPostsHandler(pr *repository.PostsRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -190,8 +255,14 @@ func GetAllPostsHandler(pr *repository.PostsRepository) http.HandlerFunc {
 		limit, offset := utils.ParsePaginationParams(r)
 		// Parse sort options from query parameters
 		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		// Get posts and total count
-		posts, err := pr.GetAllPosts(limit, offset, user.ID, sortOptions)
+		posts, nextCursor, err := pr.GetAllPosts(limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve posts")
 			return
@@ -204,7 +275,7 @@ func GetAllPostsHandler(pr *repository.PostsRepository) http.HandlerFunc {
 		}
 
 		// Respond with standardized format - ONE LINE!
-		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset)
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
 	}
 }
 
@@ -225,6 +296,12 @@ func GetPostsByCategoryHandler(pr *repository.PostsRepository) http.HandlerFunc
 		limit, offset := utils.ParsePaginationParams(r)
 		// Parse sort options from query parameters
 		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		// Get total count for this category first
 		totalCount, err := pr.GetCountPostByCategory(categoryID)
 		if err != nil {
@@ -233,12 +310,12 @@ func GetPostsByCategoryHandler(pr *repository.PostsRepository) http.HandlerFunc
 		}
 
 		// Pass userID to repository
-		posts, err := pr.GetPostsByCategory(categoryID, limit, offset, user.ID, sortOptions)
+		posts, nextCursor, err := pr.GetPostsByCategory(categoryID, limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve posts")
 			return
 		}
-		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset)
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
 	}
 }
 
@@ -295,13 +372,19 @@ func GetUserPostsProfileHandler(pr *repository.PostsRepository) http.HandlerFunc
 		}
 		limit, offset := utils.ParsePaginationParams(r)
 		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		//Pass both targetUserID and currentUserID to repository
-		posts, err := pr.GetPostsByUser(userID, limit, offset, user.ID, sortOptions)
+		posts, nextCursor, err := pr.GetPostsByUser(userID, limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user posts")
 			return
 		}
-		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset)
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
 	}
 }
 
@@ -327,18 +410,59 @@ func GetUserLikedPostsProfileHandler(pr *repository.PostsRepository) http.Handle
 		limit, offset := utils.ParsePaginationParams(r)
 		// sorting options
 		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		totalCount, err := pr.GetCountLikedPostByUser(userID)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve liked posts count")
 			return
 		}
 		// Pass both targetUserID and currentUserID to repository
-		posts, err := pr.GetPostsLikedByUser(userID, limit, offset, user.ID, sortOptions)
+		posts, nextCursor, err := pr.GetPostsLikedByUser(userID, limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve liked posts")
 			return
 		}
-		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset)
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
+	}
+}
+
+// GetUserDraftsHandler retrieves the current user's drafts and scheduled posts
+// Drafts are private, so there is no user ID in the path
+func GetUserDraftsHandler(pr *repository.PostsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		// Parse pagination parameters
+		limit, offset := utils.ParsePaginationParams(r)
+		// Parse sort options from query parameters
+		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+
+		totalCount, err := pr.GetCountDraftsByUser(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve drafts count")
+			return
+		}
+
+		posts, nextCursor, err := pr.GetDraftsByUser(user.ID, limit, offset, cursor, sortOptions)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve drafts")
+			return
+		}
+
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
 	}
 }
 
@@ -366,6 +490,12 @@ func GetUserCommentedPostsProfileHandler(pr *repository.PostsRepository) http.Ha
 		limit, offset := utils.ParsePaginationParams(r)
 		// Parse sort options from query parameters
 		sortOptions := utils.ParsePostSortOptions(r)
+		// Parse optional keyset cursor (must match the requested sort)
+		cursor, err := utils.ParseCursorParam(r, sortOptions.SortBy)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		// Get total count of posts user has commented on
 		totalCount, err := pr.GetCountCommentedPostByUser(userID)
 		if err != nil {
@@ -374,14 +504,14 @@ func GetUserCommentedPostsProfileHandler(pr *repository.PostsRepository) http.Ha
 		}
 
 		// Get posts that user has commented on
-		posts, err := pr.GetPostsCommentedByUser(userID, limit, offset, user.ID, sortOptions)
+		posts, nextCursor, err := pr.GetPostsCommentedByUser(userID, limit, offset, cursor, user.ID, sortOptions)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve commented posts")
 			return
 		}
 
 		// Respond with paginated posts
-		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset)
+		utils.RespondWithPaginatedPosts(w, posts, totalCount, limit, offset, nextCursor)
 	}
 }
 
@@ -415,16 +545,138 @@ func validateCategories(categoryNames []string, cr *repository.CategoryRepositor
 	return categoryIDs, nil
 }
 
-// deleteImageFilesFromDisk deletes image files from disk
-func deleteImageFilesFromDisk(images []models.PostImage) {
-	for _, img := range images {
-		filePath := "." + img.ImageURL // assuming "/uploads/xyz" -> "./uploads/xyz"
-		err := utils.RemoveFileIfExists(filePath)
-		if err != nil {
-			// Log and continue
-			fmt.Printf("Warning: failed to delete image file %s: %v\n", filePath, err)
+// parsePostPublishing reads the optional "title", "status" and "publish_at" (RFC 3339) form fields.
+// When updating, fields that are not sent keep the values of the current post.
+func parsePostPublishing(r *http.Request, current *models.Post) (models.PostPublishing, error) {
+	publishing := models.PostPublishing{Status: models.PostStatusPublished}
+	if current != nil {
+		publishing = models.PostPublishing{Title: current.Title, Status: current.Status, PublishAt: current.PublishAt}
+	}
+
+	form := r.MultipartForm.Value
+	if values, ok := form["title"]; ok && len(values) > 0 {
+		publishing.Title = strings.TrimSpace(values[0])
+		if err := utils.ValidatePostTitle(publishing.Title); err != nil {
+			return publishing, err
+		}
+	}
+
+	if values, ok := form["status"]; ok && len(values) > 0 {
+		status := strings.TrimSpace(values[0])
+		if status != models.PostStatusDraft && status != models.PostStatusPublished {
+			return publishing, fmt.Errorf("status must be '%s' or '%s'", models.PostStatusDraft, models.PostStatusPublished)
+		}
+		publishing.Status = status
+	}
+
+	if values, ok := form["publish_at"]; ok && len(values) > 0 {
+		publishing.PublishAt = nil
+		if value := strings.TrimSpace(values[0]); value != "" {
+			publishAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return publishing, fmt.Errorf("publish_at must be an RFC 3339 timestamp")
+			}
+			// A time in the past simply means "publish now"
+			if publishAt.After(time.Now()) {
+				publishing.PublishAt = &publishAt
+			}
+		}
+	}
+
+	return publishing, nil
+}
+
+// parsePostContent reads the "content" and "categories" form fields of an update. A field that
+// is not sent keeps the post's current value; an empty "categories" value clears the categories.
+func parsePostContent(r *http.Request, current *models.Post) (string, []string) {
+	form := r.MultipartForm.Value
+
+	content := current.Content
+	if values, ok := form["content"]; ok && len(values) > 0 {
+		content = values[0]
+	}
+
+	categoryNames := []string{}
+	if values, ok := form["categories"]; ok {
+		for _, name := range values {
+			if name != "" {
+				categoryNames = append(categoryNames, name)
+			}
 		}
+	} else {
+		for _, category := range current.Categories {
+			categoryNames = append(categoryNames, category.Name)
+		}
+	}
+
+	return content, categoryNames
+}
+
+// validatePostContentFor applies the full content rules only to posts being published
+func validatePostContentFor(publishing models.PostPublishing, content string) error {
+	if publishing.Status == models.PostStatusDraft {
+		return utils.ValidatePostDraftContent(content)
+	}
+	return utils.ValidatePostContent(content)
+}
+
+// validateCategoriesFor lets drafts be saved before any category is picked
+func validateCategoriesFor(publishing models.PostPublishing, categoryNames []string, cr *repository.CategoryRepository) ([]string, error) {
+	if publishing.Status == models.PostStatusDraft && len(categoryNames) == 0 {
+		return nil, nil
+	}
+	return validateCategories(categoryNames, cr)
+}
+
+// announcePost queues the post.created webhook of a live post the first time it is called for
+// it; later edits, or the PostPublisher coming by, find it already announced. Reports whether
+// the post was announced now.
+func announcePost(pr *repository.PostsRepository, wr *repository.WebhookRepository, author *models.User, postID string, publishing models.PostPublishing, content string) bool {
+	claimed, err := pr.ClaimAnnouncement(postID, time.Now())
+	if err != nil {
+		log.Printf("Failed to announce post %s: %v", postID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	queueWebhookEvent(wr, models.WebhookEventPostCreated, models.WebhookPostData{
+		PostID:    postID,
+		UserID:    author.ID,
+		Username:  author.Username,
+		Title:     publishing.Title,
+		Content:   content,
+		PublishAt: publishing.PublishAt,
+	})
+	return true
+}
+
+// AnnounceScheduledPost returns what the PostPublisher does when a scheduled post goes live:
+// the same as publishing a post right away
+func AnnounceScheduledPost(pr *repository.PostsRepository, wr *repository.WebhookRepository, ur *repository.UserRepository, mnr *repository.MentionRepository, nr *repository.NotificationRepository) func(post models.Post) {
+	return func(post models.Post) {
+		author := &models.User{ID: post.UserID, Username: post.Username}
+		publishing := models.PostPublishing{Title: post.Title, Status: post.Status, PublishAt: post.PublishAt}
+		if announcePost(pr, wr, author, post.ID, publishing, post.Content) {
+			recordMentions(ur, mnr, nr, author, postMentionTarget(post.ID, publishing.Title, post.Content))
+		}
+	}
+}
+
+// postLinkPreviewJob asks for the previews of a post's links. Until the post is live they only
+// go to its author, as nobody else can see it.
+func postLinkPreviewJob(postID, content string, publishing models.PostPublishing, authorID string) jobs.LinkPreviewJob {
+	job := jobs.LinkPreviewJob{
+		Source:   models.LinkPreviewSourcePost,
+		SourceID: postID,
+		PostID:   postID,
+		Text:     content,
+	}
+	if !publishing.IsLive(time.Now()) {
+		job.UserIDs = []string{authorID}
 	}
+	return job
 }
 
 // removeImagesFromPost removes images from a post by their IDs
@@ -438,7 +690,7 @@ func removeImagesFromPost(removeIDs []string, pir *repository.PostImagesReposito
 			continue
 		}
 		// Remove file from disk
-		deleteImageFilesFromDisk([]models.PostImage{*img})
+		utils.DeleteImageFilesFromDisk([]models.PostImage{*img})
 	}
 }
 
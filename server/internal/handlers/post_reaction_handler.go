@@ -11,7 +11,7 @@ import (
 	"real-time-forum/internal/utils"
 )
 
-func TogglePostReactionHandler(prr *repository.PostReactionRepository, nr *repository.NotificationRepository, pr *repository.PostsRepository, ur *repository.UserRepository) http.HandlerFunc {
+func TogglePostReactionHandler(prr *repository.PostReactionRepository, nr *repository.NotificationRepository, pr *repository.PostsRepository, ur *repository.UserRepository, wr *repository.WebhookRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Get authenticated user
@@ -43,6 +43,16 @@ func TogglePostReactionHandler(prr *repository.PostReactionRepository, nr *repos
 			return
 		}
 
+		// Webhooks hear about every toggle, including removed and changed reactions
+		queueWebhookEvent(wr, models.WebhookEventReactionToggled, models.WebhookReactionData{
+			Target:       "post",
+			PostID:       req.PostID,
+			UserID:       user.ID,
+			Username:     user.Username,
+			ReactionType: req.ReactionType,
+			Action:       result.Action,
+		})
+
 		// Create notification only for new reactions
 		if result.Action == models.ActionPostLikeCreated || result.Action == models.ActionPostDislikeCreated {
 			// Get post details to know who to notify
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// GetPostRevisionsHandler returns the edit history of a post
+func GetPostRevisionsHandler(rr *repository.RevisionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("id")
+		if postID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Post ID is required")
+			return
+		}
+
+		revisions, err := rr.GetPostRevisions(postID)
+		if err != nil {
+			if err.Error() == "post not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Post not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve post revisions")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.RevisionHistoryResponse{
+			ContentID:   postID,
+			ContentType: "post",
+			Revisions:   revisions,
+		})
+	}
+}
+
+// GetCommentRevisionsHandler returns the edit history of a comment
+func GetCommentRevisionsHandler(rr *repository.RevisionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		commentID := r.PathValue("id")
+		if commentID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Comment ID is required")
+			return
+		}
+
+		revisions, err := rr.GetCommentRevisions(commentID)
+		if err != nil {
+			if err.Error() == "comment not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Comment not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve comment revisions")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.RevisionHistoryResponse{
+			ContentID:   commentID,
+			ContentType: "comment",
+			Revisions:   revisions,
+		})
+	}
+}
+
+// GetPostRevisionDiffHandler returns a diff between two versions of a post (?from=&to=)
+func GetPostRevisionDiffHandler(rr *repository.RevisionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("id")
+		if postID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Post ID is required")
+			return
+		}
+
+		revisions, err := rr.GetPostRevisions(postID)
+		if err != nil {
+			if err.Error() == "post not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Post not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve post revisions")
+			return
+		}
+
+		respondWithRevisionDiff(w, r, postID, "post", revisions)
+	}
+}
+
+// GetCommentRevisionDiffHandler returns a diff between two versions of a comment (?from=&to=)
+func GetCommentRevisionDiffHandler(rr *repository.RevisionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		commentID := r.PathValue("id")
+		if commentID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Comment ID is required")
+			return
+		}
+
+		revisions, err := rr.GetCommentRevisions(commentID)
+		if err != nil {
+			if err.Error() == "comment not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Comment not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve comment revisions")
+			return
+		}
+
+		respondWithRevisionDiff(w, r, commentID, "comment", revisions)
+	}
+}
+
+// respondWithRevisionDiff diffs the versions selected by the from/to query parameters.
+// Defaults compare the previous version with the current one.
+func respondWithRevisionDiff(w http.ResponseWriter, r *http.Request, contentID, contentType string, revisions []models.Revision) {
+	latest := len(revisions)
+
+	to, ok := parseVersionParam(r, "to", latest, latest)
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'to' version")
+		return
+	}
+	from, ok := parseVersionParam(r, "from", max(to-1, 1), latest)
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'from' version")
+		return
+	}
+
+	utils.RespondWithSuccess(w, http.StatusOK, models.RevisionDiffResponse{
+		ContentID:   contentID,
+		ContentType: contentType,
+		From:        from,
+		To:          to,
+		Segments:    utils.DiffText(revisions[from-1].Content, revisions[to-1].Content),
+	})
+}
+
+// parseVersionParam reads a 1-based version number, falling back to defaultValue when absent
+func parseVersionParam(r *http.Request, name string, defaultValue, latest int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, true
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 || version > latest {
+		return 0, false
+	}
+	return version, true
+}
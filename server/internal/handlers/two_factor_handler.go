@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/totp"
+	"real-time-forum/internal/utils"
+)
+
+// GetTwoFactorStatusHandler reports whether the current user has two-factor authentication enabled
+func GetTwoFactorStatusHandler(tfr *repository.TwoFactorRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		enabled, err := tfr.IsEnabled(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve two-factor status")
+			return
+		}
+		remaining, err := tfr.CountRecoveryCodes(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve two-factor status")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.TwoFactorStatus{Enabled: enabled, RecoveryCodesRemaining: remaining})
+	}
+}
+
+// SetupTwoFactorHandler issues a new TOTP secret; it takes effect after ConfirmTwoFactorHandler
+func SetupTwoFactorHandler(ur *repository.UserRepository, tfr *repository.TwoFactorRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.TwoFactorSetupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if !confirmCurrentPassword(w, ur, user.ID, req.CurrentPassword, "Current password is incorrect") {
+			return
+		}
+
+		secret, err := tfr.BeginEnrollment(user.ID)
+		if err != nil {
+			if err.Error() == "two-factor already enabled" {
+				utils.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to set up two-factor authentication")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.TwoFactorSetupResponse{
+			Secret:     secret,
+			OTPAuthURL: totp.URI(config.Config.TOTPIssuer, user.Username, secret),
+		})
+	}
+}
+
+// ConfirmTwoFactorHandler enables two-factor authentication with a first code from the app
+// and returns the recovery codes
+func ConfirmTwoFactorHandler(tfr *repository.TwoFactorRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		codes, err := tfr.ConfirmEnrollment(user.ID, req.Code)
+		if err != nil {
+			switch err.Error() {
+			case "two-factor setup not started":
+				utils.RespondWithError(w, http.StatusBadRequest, "Two-factor setup has not been started")
+			case "invalid code":
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid code")
+			default:
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+			}
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableTwoFactorHandler turns two-factor authentication off; it needs the password and a code
+func DisableTwoFactorHandler(ur *repository.UserRepository, tfr *repository.TwoFactorRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.TwoFactorDisableRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if !confirmCurrentPassword(w, ur, user.ID, req.CurrentPassword, "Current password is incorrect") {
+			return
+		}
+		if !verifyTwoFactorCode(w, tfr, user.ID, req.Code) {
+			return
+		}
+
+		if err := tfr.Disable(user.ID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes after checking a current code
+func RegenerateRecoveryCodesHandler(tfr *repository.TwoFactorRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if !verifyTwoFactorCode(w, tfr, user.ID, req.Code) {
+			return
+		}
+
+		codes, err := tfr.RegenerateRecoveryCodes(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// TwoFactorLoginHandler completes a login that returned a TwoFactorChallenge: the pending token
+// and a TOTP or recovery code are exchanged for a session
+func TwoFactorLoginHandler(ur *repository.UserRepository, sr *repository.SessionRepository, tfr *repository.TwoFactorRepository, lr *repository.LoginAttemptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req models.TwoFactorLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		// OAuth logins leave the pending token in a cookie instead of the response body
+		if cookie, err := r.Cookie(config.Config.TwoFactorCookieName); err == nil && req.PendingToken == "" {
+			req.PendingToken = cookie.Value
+		}
+		if req.PendingToken == "" || req.Code == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Pending token and code are required")
+			return
+		}
+
+		// Each pending login only allows TwoFactorMaxAttempts codes
+		userID, err := tfr.ReservePendingLoginAttempt(req.PendingToken)
+		if err != nil {
+			if err.Error() == "invalid or expired token" {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Login expired, please sign in again")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "authentication failed")
+			return
+		}
+
+		user, err := ur.GetUserByID(userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Login expired, please sign in again")
+			return
+		}
+
+		// Wrong codes count towards the account's backoff and lockout like wrong passwords
+		clientIP := middleware.ClientIP(r)
+		if !checkLoginAllowed(w, lr, user.Username, clientIP) {
+			return
+		}
+
+		if err := tfr.VerifyCode(userID, req.Code); err != nil {
+			if err.Error() == "invalid code" {
+				if err := lr.RecordFailure(user.Username, clientIP); err != nil {
+					log.Printf("Failed to record failed login: %v", err)
+				}
+				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid code")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "authentication failed")
+			return
+		}
+
+		if err := tfr.ConsumePendingLogin(req.PendingToken); err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Login expired, please sign in again")
+			return
+		}
+		utils.ClearTwoFactorCookie(w)
+
+		if err := lr.RecordSuccess(user.Username); err != nil {
+			log.Printf("Failed to reset failed logins: %v", err)
+		}
+
+		session, err := sr.CreateSession(user.ID, clientIP)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "failed to create session")
+			return
+		}
+
+		utils.SetSessionCookie(session.SessionID, w, r, session.ExpiresAt)
+		utils.SetCSRFCookie(session.CSRFToken, w, session.ExpiresAt)
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.LoginResponse{
+			User:      *user,
+			SessionID: session.SessionID,
+			CSRFToken: session.CSRFToken,
+		})
+	}
+}
+
+// verifyTwoFactorCode checks a code of a logged-in user and writes the error response if it fails
+func verifyTwoFactorCode(w http.ResponseWriter, tfr *repository.TwoFactorRepository, userID, code string) bool {
+	err := tfr.VerifyCode(userID, code)
+	if err == nil {
+		return true
+	}
+
+	switch err.Error() {
+	case "two-factor not enabled":
+		utils.RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+	case "invalid code":
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid code")
+	default:
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+	}
+	return false
+}
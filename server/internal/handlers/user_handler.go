@@ -3,9 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"real-time-forum/config"
+	"real-time-forum/internal/mailer"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/repository"
@@ -13,7 +18,7 @@ import (
 )
 
 // Handle user registration logic here
-func RegisterHandler(ur *repository.UserRepository) http.HandlerFunc {
+func RegisterHandler(ur *repository.UserRepository, etr *repository.EmailTokenRepository, m mailer.Mailer, wr *repository.WebhookRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		var reg models.UserRegistration
@@ -81,12 +86,19 @@ func RegisterHandler(ur *repository.UserRepository) http.HandlerFunc {
 			return
 		}
 
+		// The account works right away; the address is confirmed through the emailed link
+		if err := sendVerificationEmail(etr, m, user); err != nil {
+			log.Printf("Failed to send verification email to new user %s: %v", user.ID, err)
+		}
+
+		queueWebhookEvent(wr, models.WebhookEventUserRegistered, models.WebhookUserData{UserID: user.ID, Username: user.Username})
+
 		utils.RespondWithSuccess(w, http.StatusCreated, user)
 	}
 }
 
 // LoginHandler handles user login
-func LoginHandler(ur *repository.UserRepository, sr *repository.SessionRepository) http.HandlerFunc {
+func LoginHandler(ur *repository.UserRepository, sr *repository.SessionRepository, tfr *repository.TwoFactorRepository, lr *repository.LoginAttemptRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// Parse request body
@@ -108,11 +120,21 @@ func LoginHandler(ur *repository.UserRepository, sr *repository.SessionRepositor
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+
+		// Refuse the attempt while the account or the address is backing off or locked
+		clientIP := middleware.ClientIP(r)
+		if !checkLoginAllowed(w, lr, login.Identifier, clientIP) {
+			return
+		}
+
 		// Authenticate user
 		user, err := ur.Authenticate(login)
 		if err != nil {
-			switch err {
-			case errors.New("invalid credentials"), errors.New("email not found"):
+			switch err.Error() {
+			case "invalid credentials", "email not found":
+				if err := lr.RecordFailure(login.Identifier, clientIP); err != nil {
+					log.Printf("Failed to record failed login: %v", err)
+				}
 				utils.RespondWithError(w, http.StatusUnauthorized, errors.New("invalid credentials").Error())
 			default:
 				utils.RespondWithError(w, http.StatusInternalServerError, errors.New("authentication failed").Error())
@@ -120,24 +142,67 @@ func LoginHandler(ur *repository.UserRepository, sr *repository.SessionRepositor
 			return
 		}
 
+		// Accounts with two-factor authentication get a pending login instead of a session
+		twoFactorEnabled, err := tfr.IsEnabled(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, errors.New("authentication failed").Error())
+			return
+		}
+		if twoFactorEnabled {
+			pendingToken, expiresAt, err := tfr.CreatePendingLogin(user.ID)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, errors.New("authentication failed").Error())
+				return
+			}
+			utils.RespondWithSuccess(w, http.StatusOK, models.TwoFactorChallenge{
+				TwoFactorRequired: true,
+				PendingToken:      pendingToken,
+				ExpiresAt:         expiresAt,
+			})
+			return
+		}
+
+		if err := lr.RecordSuccess(login.Identifier); err != nil {
+			log.Printf("Failed to reset failed logins: %v", err)
+		}
+
 		// Create a new session
-		session, err := sr.CreateSession(user.ID, r.RemoteAddr)
+		session, err := sr.CreateSession(user.ID, clientIP)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, errors.New("failed to create session").Error())
 			return
 		}
 
 		utils.SetSessionCookie(session.SessionID, w, r, session.ExpiresAt) // CHANGED: Use simplified call
+		utils.SetCSRFCookie(session.CSRFToken, w, session.ExpiresAt)
 
 		// Return JSON response
 		response := models.LoginResponse{
 			User:      *user,
 			SessionID: session.SessionID,
+			CSRFToken: session.CSRFToken,
 		}
 		utils.RespondWithSuccess(w, http.StatusOK, response)
 	}
 }
 
+// checkLoginAllowed answers 429 with a Retry-After header when logins for identifier from ip
+// are currently throttled. The message does not say whether the account exists.
+func checkLoginAllowed(w http.ResponseWriter, lr *repository.LoginAttemptRepository, identifier, ip string) bool {
+	wait, err := lr.CheckLogin(identifier, ip)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, errors.New("authentication failed").Error())
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.RespondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+	return false
+}
+
 // LogoutHandler handles user logout
 func LogoutHandler(ur *repository.UserRepository, sr *repository.SessionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -204,3 +269,278 @@ func GetUserProfileHandler(ur *repository.UserRepository) http.HandlerFunc {
 		utils.RespondWithSuccess(w, http.StatusOK, profile)
 	}
 }
+
+// UpdateCurrentUserHandler changes the current user's name, age, gender, bio, location and email
+func UpdateCurrentUserHandler(ur *repository.UserRepository, etr *repository.EmailTokenRepository, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		currentUser := middleware.GetCurrentUser(r)
+
+		var update models.UserUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		user, err := ur.GetUserByID(currentUser.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user")
+			return
+		}
+
+		// Apply and validate only the fields that were sent
+		if update.FirstName != nil {
+			if err := utils.ValidateName(*update.FirstName, "first name"); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.FirstName = strings.TrimSpace(*update.FirstName)
+		}
+		if update.LastName != nil {
+			if err := utils.ValidateName(*update.LastName, "last name"); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.LastName = strings.TrimSpace(*update.LastName)
+		}
+		if update.Age != nil {
+			if err := utils.ValidateAge(*update.Age); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.Age = *update.Age
+		}
+		if update.Gender != nil {
+			if err := utils.ValidateGender(*update.Gender); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.Gender = strings.TrimSpace(*update.Gender)
+		}
+		if update.Bio != nil {
+			if err := utils.ValidateBio(strings.TrimSpace(*update.Bio)); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.Bio = strings.TrimSpace(*update.Bio)
+		}
+		if update.Location != nil {
+			if err := utils.ValidateLocation(strings.TrimSpace(*update.Location)); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			user.Location = strings.TrimSpace(*update.Location)
+		}
+
+		emailChanged := false
+		if update.Email != nil && !strings.EqualFold(strings.TrimSpace(*update.Email), user.Email) {
+			if err := utils.ValidateEmail(strings.TrimSpace(*update.Email)); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			// The email is where password reset links go, so changing it needs the password
+			if !confirmCurrentPassword(w, ur, user.ID, update.CurrentPassword, "Current password is incorrect") {
+				return
+			}
+
+			user.Email = strings.TrimSpace(*update.Email)
+			user.EmailVerified = false
+			emailChanged = true
+		}
+
+		if err := ur.UpdateUser(user); err != nil {
+			if err.Error() == "email already taken" {
+				utils.RespondWithError(w, http.StatusConflict, err.Error())
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update profile")
+			return
+		}
+
+		if emailChanged {
+			if err := sendVerificationEmail(etr, m, user); err != nil {
+				log.Printf("Failed to send verification email to %s: %v", user.ID, err)
+			}
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, user)
+	}
+}
+
+// ChangePasswordHandler changes the current user's password and signs out their other sessions
+func ChangePasswordHandler(ur *repository.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.PasswordChange
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if !confirmCurrentPassword(w, ur, user.ID, req.CurrentPassword, "Current password is incorrect") {
+			return
+		}
+		if req.NewPassword != req.ConfirmPassword {
+			utils.RespondWithError(w, http.StatusBadRequest, "Passwords do not match")
+			return
+		}
+		if req.NewPassword == req.CurrentPassword {
+			utils.RespondWithError(w, http.StatusBadRequest, "New password must be different from the current one")
+			return
+		}
+		if err := utils.ValidatePassword(req.NewPassword); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Keep the session making this request signed in
+		var currentSessionID string
+		if cookie, err := r.Cookie(config.Config.SessionName); err == nil {
+			currentSessionID = cookie.Value
+		}
+
+		if err := ur.UpdatePassword(user.ID, req.NewPassword, currentSessionID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change password")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Password changed"})
+	}
+}
+
+// SetPasswordHandler sets a first password on an account that only signs in with OAuth, so it
+// can also log in with username or email and unlink providers
+func SetPasswordHandler(ur *repository.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		var req models.PasswordSet
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		hasPassword, err := ur.HasPassword(user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to set password")
+			return
+		}
+		if hasPassword {
+			utils.RespondWithError(w, http.StatusConflict, "Account already has a password, change it instead")
+			return
+		}
+		if req.NewPassword != req.ConfirmPassword {
+			utils.RespondWithError(w, http.StatusBadRequest, "Passwords do not match")
+			return
+		}
+		if err := utils.ValidatePassword(req.NewPassword); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Keep the session making this request signed in
+		var currentSessionID string
+		if cookie, err := r.Cookie(config.Config.SessionName); err == nil {
+			currentSessionID = cookie.Value
+		}
+
+		if err := ur.UpdatePassword(user.ID, req.NewPassword, currentSessionID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to set password")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Password set"})
+	}
+}
+
+// UploadAvatarHandler replaces the current user's avatar with an uploaded image ("avatar" form field)
+func UploadAvatarHandler(ur *repository.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		// ---- Parse multipart form ----
+		err := r.ParseMultipartForm(10 << 20) // 10MB max memory
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid multipart form")
+			return
+		}
+
+		files := r.MultipartForm.File["avatar"]
+		if len(files) != 1 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Exactly one avatar image is required")
+			return
+		}
+
+		avatarURL, err := utils.ProcessAvatarUpload(files[0])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		previous, err := ur.SetAvatar(user.ID, avatarURL)
+		if err != nil {
+			utils.DeleteUploadedFile(avatarURL)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update avatar")
+			return
+		}
+		if previous != "" {
+			utils.DeleteUploadedFile(previous)
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"avatar_url": avatarURL})
+	}
+}
+
+// DeleteAvatarHandler removes the current user's avatar
+func DeleteAvatarHandler(ur *repository.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated user
+		user := middleware.GetCurrentUser(r)
+
+		previous, err := ur.SetAvatar(user.ID, "")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to remove avatar")
+			return
+		}
+		if previous != "" {
+			utils.DeleteUploadedFile(previous)
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Avatar removed"})
+	}
+}
+
+// confirmCurrentPassword re-confirms the password of a logged-in user before a sensitive change
+// and writes the error response if it does not match. Accounts without a password (OAuth only)
+// get 409 and have to set one first with POST /api/users/me/password.
+func confirmCurrentPassword(w http.ResponseWriter, ur *repository.UserRepository, userID, password, incorrectMessage string) bool {
+	if password != "" {
+		auth, err := ur.GetAuthByUserID(userID)
+		if err == nil && utils.CheckPasswordHash(password, auth.PasswordHash) {
+			return true
+		}
+	}
+
+	// Tell accounts that have no password to compare against apart from a wrong guess
+	hasPassword, err := ur.HasPassword(userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check password")
+		return false
+	}
+	if !hasPassword {
+		utils.RespondWithError(w, http.StatusConflict, "Set a password first")
+		return false
+	}
+	utils.RespondWithError(w, http.StatusUnauthorized, incorrectMessage)
+	return false
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// ListWebhooksHandler lists all registered webhooks (admin only)
+func ListWebhooksHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		webhooks, err := wr.GetWebhooks()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve webhooks")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, webhooks)
+	}
+}
+
+// CreateWebhookHandler registers a webhook; the signing secret is only returned here (admin only)
+func CreateWebhookHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// Get authenticated admin
+		user := middleware.GetCurrentUser(r)
+
+		var req models.CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		req.URL = strings.TrimSpace(req.URL)
+		if err := validateWebhookURL(req.URL); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		events, err := validateWebhookEvents(req.Events)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		webhook, err := wr.CreateWebhook(req.URL, events, user.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusCreated, webhook)
+	}
+}
+
+// UpdateWebhookHandler changes the URL, events or active flag of a webhook (admin only)
+func UpdateWebhookHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		webhook, err := wr.GetWebhookByID(r.PathValue("id"))
+		if err != nil {
+			if err.Error() == "webhook not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve webhook")
+			return
+		}
+
+		var req models.UpdateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if req.URL != nil {
+			webhook.URL = strings.TrimSpace(*req.URL)
+			if err := validateWebhookURL(webhook.URL); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.Events != nil {
+			if webhook.Events, err = validateWebhookEvents(req.Events); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.Active != nil {
+			webhook.Active = *req.Active
+		}
+
+		if err := wr.UpdateWebhook(webhook); err != nil {
+			if err.Error() == "webhook not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update webhook")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, webhook)
+	}
+}
+
+// DeleteWebhookHandler removes a webhook and its delivery log (admin only)
+func DeleteWebhookHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if err := wr.DeleteWebhook(r.PathValue("id")); err != nil {
+			if err.Error() == "webhook not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, map[string]string{"message": "Webhook deleted"})
+	}
+}
+
+// GetWebhookDeliveriesHandler returns a page of a webhook's delivery log, newest first (admin only)
+func GetWebhookDeliveriesHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		webhookID := r.PathValue("id")
+		if _, err := wr.GetWebhookByID(webhookID); err != nil {
+			if err.Error() == "webhook not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve deliveries")
+			return
+		}
+
+		// Parse pagination parameters
+		limit, offset := utils.ParsePaginationParams(r)
+
+		totalCount, err := wr.CountDeliveries(webhookID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve deliveries")
+			return
+		}
+
+		deliveries, err := wr.GetDeliveries(webhookID, limit, offset)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve deliveries")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusOK, models.WebhookDeliveriesResponse{
+			Deliveries: deliveries,
+			Pagination: models.NewPaginationInfo(totalCount, limit, offset),
+		})
+	}
+}
+
+// RedeliverWebhookHandler queues a logged delivery again (admin only)
+func RedeliverWebhookHandler(wr *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if err := wr.Redeliver(r.PathValue("id"), r.PathValue("deliveryID")); err != nil {
+			if err.Error() == "delivery not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Delivery not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to queue delivery")
+			return
+		}
+
+		utils.RespondWithSuccess(w, http.StatusAccepted, map[string]string{"message": "Delivery queued"})
+	}
+}
+
+// queueWebhookEvent queues event for the subscribed webhooks. Like notifications, a failure
+// is only logged so it never breaks the request that triggered the event.
+func queueWebhookEvent(wr *repository.WebhookRepository, event string, data interface{}) {
+	if err := wr.Enqueue(event, data); err != nil {
+		log.Printf("Failed to queue %s webhook: %v", event, err)
+	}
+}
+
+// validateWebhookURL accepts absolute http(s) URLs
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// validateWebhookEvents checks the subscribed events and removes duplicates
+func validateWebhookEvents(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("At least one event is required")
+	}
+
+	var events []string
+	seen := make(map[string]bool)
+	for _, event := range requested {
+		known := false
+		for _, e := range models.WebhookEvents {
+			if e == event {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown event %q: must be one of %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
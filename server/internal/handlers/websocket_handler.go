@@ -13,10 +13,9 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	// Allow connections from any origin (adjust for production)
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	// Browsers send the page's Origin with the upgrade; only the allowed frontends may connect
+	// (the session cookie would otherwise let any site open a socket as the user)
+	CheckOrigin: middleware.IsAllowedOrigin,
 }
 
 // WebSocketHandler handles WebSocket connections
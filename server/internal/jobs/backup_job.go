@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+)
+
+// BackupScheduler takes periodic online backups and applies the retention policy
+type BackupScheduler struct {
+	db *sql.DB
+}
+
+// NewBackupScheduler creates a new BackupScheduler
+func NewBackupScheduler(db *sql.DB) *BackupScheduler {
+	return &BackupScheduler{db: db}
+}
+
+// Run takes a backup on every BackupInterval tick; the first one happens after one interval
+func (b *BackupScheduler) Run() {
+	ticker := time.NewTicker(config.Config.BackupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.BackupAndPrune()
+	}
+}
+
+// BackupAndPrune creates a backup and then removes the ones outside the retention policy
+func (b *BackupScheduler) BackupAndPrune() {
+	backup, err := database.CreateBackup(b.db, config.Config.BackupDir)
+	if err != nil {
+		log.Printf("Scheduled backup failed: %v", err)
+		return
+	}
+	log.Printf("Created backup %s (%d bytes)", backup.Name, backup.Size)
+
+	removed, err := database.PruneBackups(config.Config.BackupDir, config.Config.BackupKeep, config.Config.BackupMaxAge)
+	if err != nil {
+		log.Printf("Failed to prune backups: %v", err)
+	}
+	if len(removed) > 0 {
+		log.Printf("Removed %d old backups", len(removed))
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/unfurl"
+	ws "real-time-forum/internal/websocket"
+)
+
+// linkPreviewQueueSize is how many items can wait for their links; more are skipped until there is room
+const linkPreviewQueueSize = 256
+
+// linkPreviewWorkers is how many pages are fetched at the same time
+const linkPreviewWorkers = 4
+
+// linkPreviewRetryAfter is how long a failed fetch is remembered before the page is tried again
+const linkPreviewRetryAfter = time.Hour
+
+// LinkPreviewJob asks for the previews of the links in one post, comment or message
+type LinkPreviewJob struct {
+	Source   string // models.LinkPreviewSourcePost, LinkPreviewSourceComment or LinkPreviewSourceMessage
+	SourceID string
+	PostID   string // post a comment belongs to, so clients can find it
+	Text     string
+	UserIDs  []string // who is sent the previews; nil sends them to everyone online
+
+	links []string
+}
+
+// LinkUnfurler fetches link previews in the background so saving a post or message never waits
+// for another site. Results are cached in link_previews and pushed over the WebSocket hub.
+// The queue is in memory: items still waiting at shutdown get their previews the next time
+// they are edited.
+type LinkUnfurler struct {
+	previewRepo *repository.LinkPreviewRepository
+	fetcher     *unfurl.Fetcher
+	hub         *ws.Hub
+	queue       chan LinkPreviewJob
+}
+
+// NewLinkUnfurler creates a new LinkUnfurler
+func NewLinkUnfurler(lpr *repository.LinkPreviewRepository, hub *ws.Hub) *LinkUnfurler {
+	return &LinkUnfurler{
+		previewRepo: lpr,
+		fetcher:     unfurl.NewFetcher(),
+		hub:         hub,
+		queue:       make(chan LinkPreviewJob, linkPreviewQueueSize),
+	}
+}
+
+// Run resolves queued jobs with linkPreviewWorkers workers
+func (u *LinkUnfurler) Run() {
+	for i := 1; i < linkPreviewWorkers; i++ {
+		go u.work()
+	}
+	u.work()
+}
+
+// Enqueue queues the links of job.Text without blocking; text without links is ignored
+func (u *LinkUnfurler) Enqueue(job LinkPreviewJob) {
+	if !config.Config.LinkPreviewEnabled {
+		return
+	}
+	job.links = unfurl.LinksIn(job.Text)
+	if len(job.links) == 0 {
+		return
+	}
+
+	select {
+	case u.queue <- job:
+	default:
+		log.Printf("Link preview queue is full, skipping %s %s", job.Source, job.SourceID)
+	}
+}
+
+func (u *LinkUnfurler) work() {
+	for job := range u.queue {
+		u.resolve(job)
+	}
+}
+
+// resolve fetches the links that are not cached (or whose cache entry is stale) and pushes the
+// previews of the job to its users
+func (u *LinkUnfurler) resolve(job LinkPreviewJob) {
+	cached, err := u.previewRepo.GetPreviews(job.links)
+	if err != nil {
+		log.Printf("Failed to load link previews for %s %s: %v", job.Source, job.SourceID, err)
+		return
+	}
+
+	previews := []models.LinkPreview{}
+	for _, link := range job.links {
+		preview, ok := cached[link]
+		if !ok || !isFresh(preview) {
+			preview = u.fetch(link)
+		}
+		if preview.Status == models.LinkPreviewOK {
+			previews = append(previews, preview)
+		}
+	}
+	if len(previews) == 0 {
+		return
+	}
+
+	payload := models.LinkPreviewsPayload{
+		Source:   job.Source,
+		SourceID: job.SourceID,
+		PostID:   job.PostID,
+		Previews: previews,
+	}
+	if job.UserIDs == nil {
+		u.hub.Broadcast(models.EventTypeLinkPreviews, payload)
+		return
+	}
+	for _, userID := range job.UserIDs {
+		u.hub.SendMessageToUser(userID, models.EventTypeLinkPreviews, payload)
+	}
+}
+
+// fetch downloads one page and caches the result, failures included
+func (u *LinkUnfurler) fetch(link string) models.LinkPreview {
+	preview, err := u.fetcher.Fetch(link)
+	if err != nil {
+		preview = &models.LinkPreview{
+			URL:    link,
+			Status: models.LinkPreviewFailed,
+			Error:  truncateError(err),
+		}
+	}
+	preview.FetchedAt = time.Now()
+
+	if err := u.previewRepo.SavePreview(preview); err != nil {
+		log.Printf("Failed to save link preview of %s: %v", link, err)
+	}
+	return *preview
+}
+
+// isFresh reports whether a cached preview can be used without fetching the page again
+func isFresh(preview models.LinkPreview) bool {
+	ttl := config.Config.LinkPreviewTTL
+	if preview.Status == models.LinkPreviewFailed && ttl > linkPreviewRetryAfter {
+		ttl = linkPreviewRetryAfter
+	}
+	return time.Since(preview.FetchedAt) < ttl
+}
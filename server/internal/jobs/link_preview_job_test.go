@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/unfurl/linkfixture"
+)
+
+// TestBlockedLinkIsCachedAsFailed checks that a page redirecting to a private address is stored
+// as failed, so it is neither shown nor fetched again until linkPreviewRetryAfter has passed
+func TestBlockedLinkIsCachedAsFailed(t *testing.T) {
+	if err := config.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.DBPath = filepath.Join(t.TempDir(), "forum.db")
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	server := httptest.NewServer(linkfixture.NewHandler(linkfixture.Options{}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	config.Config.LinkPreviewAllowedHosts = u.Host
+
+	previewRepo := repository.NewLinkPreviewRepository(db)
+	unfurler := NewLinkUnfurler(previewRepo, nil)
+
+	ok := server.URL + "/article"
+	blocked := server.URL + "/redirect-metadata"
+	for _, link := range []string{ok, blocked} {
+		unfurler.fetch(link)
+	}
+
+	cached, err := previewRepo.GetPreviews([]string{ok, blocked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview := cached[ok]; preview.Status != models.LinkPreviewOK {
+		t.Errorf("preview of %s = %+v, want status %q", ok, preview, models.LinkPreviewOK)
+	}
+	preview, found := cached[blocked]
+	if !found {
+		t.Fatalf("preview of %s was not cached", blocked)
+	}
+	if preview.Status != models.LinkPreviewFailed || !strings.Contains(preview.Error, "private or reserved address") {
+		t.Errorf("preview of %s = %+v, want status %q with the blocked address error", blocked, preview, models.LinkPreviewFailed)
+	}
+	if preview.Title != "" || preview.Description != "" {
+		t.Errorf("blocked preview has content: %+v", preview)
+	}
+	if !isFresh(preview) {
+		t.Error("a failed preview just fetched is not fresh")
+	}
+	preview.FetchedAt = time.Now().Add(-linkPreviewRetryAfter)
+	if isFresh(preview) {
+		t.Errorf("a failed preview is still fresh after %s", linkPreviewRetryAfter)
+	}
+}
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+)
+
+// scheduledPostCheckInterval is how often scheduled posts are checked for having gone live
+const scheduledPostCheckInterval = 30 * time.Second
+
+// PostPublisher announces scheduled posts once their publication time has come. Scheduled posts
+// become visible by themselves (the feed queries compare publish_at with the current time), but
+// what goes out when a post is published, like webhooks, has to wait for that moment.
+type PostPublisher struct {
+	postRepo *repository.PostsRepository
+	announce func(post models.Post)
+}
+
+// NewPostPublisher creates a new PostPublisher; announce is called for every post that went live
+func NewPostPublisher(pr *repository.PostsRepository, announce func(post models.Post)) *PostPublisher {
+	return &PostPublisher{postRepo: pr, announce: announce}
+}
+
+// Run announces due posts once at startup (catching up on downtime) and then on every tick
+func (p *PostPublisher) Run() {
+	ticker := time.NewTicker(scheduledPostCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.AnnounceDue()
+		<-ticker.C
+	}
+}
+
+// AnnounceDue announces every scheduled post whose publication time has passed
+func (p *PostPublisher) AnnounceDue() {
+	posts, err := p.postRepo.GetDueScheduledPosts(time.Now())
+	if err != nil {
+		log.Printf("Failed to load scheduled posts: %v", err)
+		return
+	}
+	for _, post := range posts {
+		p.announce(post)
+	}
+}
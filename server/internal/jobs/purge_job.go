@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/repository"
+	"real-time-forum/internal/utils"
+)
+
+// ContentPurger permanently removes soft-deleted posts and comments once their restore period is over
+type ContentPurger struct {
+	postRepo    *repository.PostsRepository
+	commentRepo *repository.CommentRepository
+}
+
+// NewContentPurger creates a new ContentPurger
+func NewContentPurger(pr *repository.PostsRepository, cor *repository.CommentRepository) *ContentPurger {
+	return &ContentPurger{postRepo: pr, commentRepo: cor}
+}
+
+// Run purges once at startup and then on every DeletedContentPurgeInterval tick
+func (p *ContentPurger) Run() {
+	ticker := time.NewTicker(config.Config.DeletedContentPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeExpired()
+		<-ticker.C
+	}
+}
+
+// PurgeExpired deletes everything soft-deleted longer than the grace period ago, including image files
+func (p *ContentPurger) PurgeExpired() {
+	cutoff := time.Now().Add(-config.Config.DeletedContentGracePeriod)
+
+	images, posts, err := p.postRepo.PurgeDeletedPosts(cutoff)
+	if err != nil {
+		log.Printf("Failed to purge deleted posts: %v", err)
+	} else {
+		utils.DeleteImageFilesFromDisk(images)
+	}
+
+	comments, err := p.commentRepo.PurgeDeletedComments(cutoff)
+	if err != nil {
+		log.Printf("Failed to purge deleted comments: %v", err)
+	}
+
+	if posts > 0 || comments > 0 {
+		log.Printf("Purged %d deleted posts and %d deleted comments", posts, comments)
+	}
+}
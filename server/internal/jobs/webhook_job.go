@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repository"
+)
+
+// webhookBatchSize is the number of deliveries sent per poll; the rest wait for the next tick
+const webhookBatchSize = 50
+
+// webhookPruneInterval is how often old deliveries are removed from the log
+const webhookPruneInterval = time.Hour
+
+// WebhookDispatcher sends queued webhook deliveries and retries failed ones with exponential backoff.
+// The queue lives in SQLite, so deliveries survive restarts.
+type WebhookDispatcher struct {
+	webhookRepo *repository.WebhookRepository
+	client      *http.Client
+	lastPrune   time.Time
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher
+func NewWebhookDispatcher(wr *repository.WebhookRepository) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookRepo: wr,
+		client: &http.Client{
+			Timeout: config.Config.WebhookTimeout,
+			// A redirect counts as a failed delivery instead of sending the payload somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run sends due deliveries on every WebhookPollInterval tick
+func (d *WebhookDispatcher) Run() {
+	ticker := time.NewTicker(config.Config.WebhookPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.DeliverDue()
+		d.pruneDeliveries()
+	}
+}
+
+// DeliverDue sends every delivery whose next attempt is due
+func (d *WebhookDispatcher) DeliverDue() {
+	for {
+		deliveries, err := d.webhookRepo.GetDueDeliveries(webhookBatchSize)
+		if err != nil {
+			log.Printf("Failed to load webhook deliveries: %v", err)
+			return
+		}
+
+		recorded := true
+		for i := range deliveries {
+			recorded = d.attempt(&deliveries[i]) && recorded
+		}
+
+		// Stop when the queue is drained, or when results could not be saved (the same
+		// deliveries would be picked up again right away)
+		if len(deliveries) < webhookBatchSize || !recorded {
+			return
+		}
+	}
+}
+
+// attempt sends one delivery and records the result; it reports whether the result was saved
+func (d *WebhookDispatcher) attempt(delivery *models.WebhookDelivery) bool {
+	statusCode, err := d.send(delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= config.Config.WebhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = truncateError(err)
+		log.Printf("Webhook delivery %s to %s failed for good after %d attempts: %v", delivery.ID, delivery.URL, delivery.Attempts, err)
+	default:
+		next := now.Add(webhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = truncateError(err)
+	}
+
+	if err := d.webhookRepo.RecordAttempt(delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+		return false
+	}
+	return true
+}
+
+// send posts the payload and treats any 2xx answer as success
+func (d *WebhookDispatcher) send(delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RealTimeForum-Webhook/1.0")
+	req.Header.Set("X-Forum-Event", delivery.Event)
+	req.Header.Set("X-Forum-Delivery", delivery.ID)
+	req.Header.Set("X-Forum-Timestamp", timestamp)
+	req.Header.Set("X-Forum-Signature", "sha256="+signWebhookPayload(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // let the connection be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// pruneDeliveries removes finished deliveries older than WebhookDeliveryRetention, at most once per webhookPruneInterval
+func (d *WebhookDispatcher) pruneDeliveries() {
+	if time.Since(d.lastPrune) < webhookPruneInterval {
+		return
+	}
+	d.lastPrune = time.Now()
+
+	removed, err := d.webhookRepo.PruneDeliveries(time.Now().Add(-config.Config.WebhookDeliveryRetention))
+	if err != nil {
+		log.Printf("Failed to prune webhook deliveries: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Removed %d old webhook deliveries", removed)
+	}
+}
+
+// signWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>". Including the timestamp
+// lets receivers reject replayed deliveries.
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay is WebhookRetryBase doubled for every failed attempt after the first, capped at WebhookRetryMax
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := config.Config.WebhookRetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= config.Config.WebhookRetryMax {
+			return config.Config.WebhookRetryMax
+		}
+	}
+	return delay
+}
+
+// truncateError keeps delivery log entries short
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = msg[:500]
+	}
+	return msg
+}
@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"real-time-forum/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails; implementations are selected with MAIL_DRIVER
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromConfig returns the mailer configured by MAIL_DRIVER ("smtp" or "log", validated by LoadConfig)
+func NewFromConfig() Mailer {
+	if config.Config.MailDriver == "smtp" {
+		return NewSMTPMailer(
+			config.Config.SMTPHost,
+			config.Config.SMTPPort,
+			config.Config.SMTPUsername,
+			config.Config.SMTPPassword,
+			config.Config.MailFrom,
+		)
+	}
+	return NewLogMailer(config.Config.MailLogFile)
+}
+
+// SMTPMailer delivers email through an SMTP server.
+// Authentication is only used when a username is set, so a local MailHog-style server works without it.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers the message to the SMTP server
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	// The envelope sender is the bare address of a "Name <address>" From header
+	sender := m.from
+	if address, err := mail.ParseAddress(m.from); err == nil {
+		sender = address.Address
+	}
+
+	err := smtp.SendMail(m.addr, auth, sender, []string{msg.To}, formatMessage(m.from, msg))
+	if err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// LogMailer writes emails to a file (or the server log when no file is set) instead of sending them.
+// Meant for development: the links in password reset and verification emails can be copied from there.
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer creates a new LogMailer
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+// Send appends the message to the mail log
+func (m *LogMailer) Send(msg Message) error {
+	if m.path == "" {
+		log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\n%s\n", formatMessage("", msg), strings.Repeat("-", 72))
+	return err
+}
+
+// formatMessage builds an RFC 5322 message with headers; header values are stripped of line breaks
+func formatMessage(from string, msg Message) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,159 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the stand-in SMTP server received in one session
+type smtpSession struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPServer accepts one SMTP session in-process, like a local MailHog would, and returns
+// its host, port and a channel receiving the session once the client quits
+func startSMTPServer(t *testing.T) (string, string, <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session smtpSession
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost test SMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				session.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				session.to = append(session.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				sessions <- session
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, sessions
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, sessions := startSMTPServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "Real-Time Forum <noreply@forum.example>")
+
+	err := mailer.Send(Message{
+		To:      "alice@example.com",
+		Subject: "Reset your password\r\nBcc: mallory@example.com",
+		Body:    "Follow the link:\nhttps://forum.example/reset-password?token=abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := <-sessions
+
+	if session.from != "noreply@forum.example" {
+		t.Errorf("envelope sender = %q, want the bare From address", session.from)
+	}
+	if len(session.to) != 1 || session.to[0] != "alice@example.com" {
+		t.Errorf("envelope recipients = %q, want only alice@example.com", session.to)
+	}
+	for _, want := range []string{
+		"From: Real-Time Forum <noreply@forum.example>\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: Reset your passwordBcc: mallory@example.com\r\n",
+		"\r\n\r\nFollow the link:\r\nhttps://forum.example/reset-password?token=abc",
+	} {
+		if !strings.Contains(session.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, session.data)
+		}
+	}
+	if strings.Contains(session.data, "\r\nBcc:") {
+		t.Errorf("subject injected a header:\n%s", session.data)
+	}
+}
+
+func TestSMTPMailerRejectsLineBreakInRecipient(t *testing.T) {
+	host, port, _ := startSMTPServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "noreply@forum.example")
+
+	if err := mailer.Send(Message{To: "alice@example.com\r\nRCPT TO:<mallory@example.com>", Subject: "Hi"}); err == nil {
+		t.Error("Send accepted a recipient with a line break")
+	}
+}
+
+func TestFormatMessageStripsLineBreaksFromHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   string
+		msg    Message
+		header string // the single header line expected in the output
+	}{
+		{"subject CRLF", "", Message{To: "a@example.com", Subject: "Hi\r\nBcc: x@example.com"}, "Subject: HiBcc: x@example.com\r\n"},
+		{"subject LF", "", Message{To: "a@example.com", Subject: "Hi\nBcc: x@example.com"}, "Subject: HiBcc: x@example.com\r\n"},
+		{"subject CR", "", Message{To: "a@example.com", Subject: "Hi\rBcc: x@example.com"}, "Subject: HiBcc: x@example.com\r\n"},
+		{"to", "", Message{To: "a@example.com\r\nCc: x@example.com", Subject: "Hi"}, "To: a@example.comCc: x@example.com\r\n"},
+		{"from", "noreply@forum.example\nReply-To: x@example.com", Message{To: "a@example.com"}, "From: noreply@forum.exampleReply-To: x@example.com\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := string(formatMessage(tt.from, tt.msg))
+			headers, _, _ := strings.Cut(message, "\r\n\r\n")
+			if !strings.Contains(headers+"\r\n", tt.header) {
+				t.Errorf("headers do not contain %q:\n%s", tt.header, headers)
+			}
+			for _, line := range strings.Split(headers, "\r\n") {
+				if strings.ContainsAny(line, "\r\n") {
+					t.Errorf("header line %q has a stray line break", line)
+				}
+				if name, _, _ := strings.Cut(line, ":"); name == "Bcc" || name == "Cc" || name == "Reply-To" {
+					t.Errorf("injected header %q", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// allowedTags is the allowlist of elements the renderer writes and the attributes each may carry.
+// open and close drop anything else, so no other markup can reach the output.
+var allowedTags = map[string]map[string]bool{
+	"p":          {},
+	"br":         {},
+	"strong":     {},
+	"em":         {},
+	"code":       {"class": true},
+	"pre":        {},
+	"blockquote": {},
+	"ul":         {},
+	"ol":         {"start": true},
+	"li":         {},
+	"a":          {"href": true, "rel": true},
+}
+
+// externalLinkRel is set on links that leave the forum: search engines should not credit them and
+// the opened page gets no handle on the forum's window
+const externalLinkRel = "nofollow noopener"
+
+// open writes an opening tag; attrs are name/value pairs and values are escaped
+func (r *renderer) open(tag string, attrs ...string) {
+	allowedAttrs, ok := allowedTags[tag]
+	if !ok {
+		return
+	}
+
+	r.out.WriteString("<" + tag)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if allowedAttrs[attrs[i]] {
+			r.out.WriteString(" " + attrs[i] + `="` + html.EscapeString(attrs[i+1]) + `"`)
+		}
+	}
+	r.out.WriteString(">")
+}
+
+// close writes a closing tag
+func (r *renderer) close(tag string) {
+	if _, ok := allowedTags[tag]; ok {
+		r.out.WriteString("</" + tag + ">")
+	}
+}
+
+// text writes escaped text
+func (r *renderer) text(s string) {
+	r.out.WriteString(html.EscapeString(s))
+}
+
+// linkTarget checks a link destination. Only http(s) and mailto URLs and absolute paths on the
+// forum are allowed, which rules out javascript:, data: and protocol-relative URLs. External
+// reports whether an http(s) link points to another host than the forum.
+func (r *renderer) linkTarget(destination string) (href string, external, ok bool) {
+	if destination == "" || strings.IndexFunc(destination, isSpaceOrControl) >= 0 {
+		return "", false, false
+	}
+
+	// Paths on the forum; "//host" and "/\host" would be read as another host by browsers
+	if strings.HasPrefix(destination, "/") {
+		if strings.HasPrefix(destination, "//") || strings.HasPrefix(destination, `/\`) {
+			return "", false, false
+		}
+		return destination, false, true
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", false, false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false, false
+		}
+		return destination, strings.ToLower(u.Hostname()) != r.siteHost, true
+	case "mailto":
+		if u.Opaque == "" {
+			return "", false, false
+		}
+		return destination, false, true
+	default:
+		return "", false, false
+	}
+}
+
+func isSpaceOrControl(c rune) bool {
+	return c <= ' ' || c == 0x7f
+}
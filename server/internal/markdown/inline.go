@@ -0,0 +1,294 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// inline renders the text of a paragraph or list item: code spans, emphasis, links and line
+// breaks. Inside link text (inLink) no further links are created.
+func (r *renderer) inline(text string, depth int, inLink bool) {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			r.text(text[i+1 : i+2])
+			i += 2
+
+		case c == '\n':
+			r.open("br")
+			r.out.WriteString("\n")
+			i++
+
+		case c == '`':
+			if end, code, ok := codeSpan(text, i); ok {
+				r.open("code")
+				r.text(code)
+				r.close("code")
+				i = end
+				continue
+			}
+			n := runLength(text, i)
+			r.text(text[i : i+n])
+			i += n
+
+		case c == '*' || c == '_':
+			if end, inner, tag, ok := emphasis(text, i); ok && depth < maxNesting {
+				r.open(tag)
+				r.inline(inner, depth+1, inLink)
+				r.close(tag)
+				i = end
+				continue
+			}
+			n := runLength(text, i)
+			r.text(text[i : i+n])
+			i += n
+
+		case c == '[' && !inLink:
+			if end, label, destination, ok := link(text, i); ok {
+				if label == "" {
+					label = destination
+				}
+				r.link(destination, label, depth)
+				i = end
+				continue
+			}
+			r.text("[")
+			i++
+
+		case c == '<' && !inLink:
+			if end := strings.IndexByte(text[i:], '>'); end > 1 {
+				// Only absolute URLs, so closing tags like </b> stay text
+				destination := text[i+1 : i+end]
+				if _, _, ok := r.linkTarget(destination); ok && !strings.HasPrefix(destination, "/") && !strings.Contains(destination, "<") {
+					r.link(destination, destination, depth)
+					i += end + 1
+					continue
+				}
+			}
+			r.text("<")
+			i++
+
+		case c == 'h' && !inLink && bareURLLength(text, i) > 0:
+			n := bareURLLength(text, i)
+			if _, _, ok := r.linkTarget(text[i : i+n]); ok {
+				r.link(text[i:i+n], text[i:i+n], depth)
+			} else {
+				r.text(text[i : i+n])
+			}
+			i += n
+
+		default:
+			j := i + 1
+			for j < len(text) && !strings.ContainsRune("\\\n`*_[<h", rune(text[j])) {
+				j++
+			}
+			r.text(text[i:j])
+			i = j
+		}
+	}
+}
+
+// link writes an anchor for destination, or only its label when the destination is not allowed
+func (r *renderer) link(destination, label string, depth int) {
+	href, external, ok := r.linkTarget(destination)
+	if !ok {
+		r.inline(label, depth+1, true)
+		return
+	}
+
+	if external {
+		r.links = append(r.links, href)
+		r.open("a", "href", href, "rel", externalLinkRel)
+	} else {
+		r.open("a", "href", href)
+	}
+	r.inline(label, depth+1, true)
+	r.close("a")
+}
+
+// codeSpan finds the code span opened by the backticks at text[i]. It is closed by a run of
+// exactly as many backticks; one space on each side of the code is dropped.
+func codeSpan(text string, i int) (end int, code string, ok bool) {
+	n := runLength(text, i)
+	for j := i + n; j < len(text); {
+		if text[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(text, j)
+		if m == n {
+			code = strings.ReplaceAll(text[i+n:j], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			return j + m, code, true
+		}
+		j += m
+	}
+	return 0, "", false
+}
+
+// emphasis finds *em*, _em_, **strong** or __strong__ starting at text[i]. The delimiters must
+// hug the text, and underscores inside a word (snake_case, @user_name) are not emphasis.
+func emphasis(text string, i int) (end int, inner, tag string, ok bool) {
+	c := text[i]
+	size, tag := 1, "em"
+	if runLength(text, i) >= 2 {
+		size, tag = 2, "strong"
+	}
+	if i+size >= len(text) || isSpace(text[i+size]) {
+		return 0, "", "", false
+	}
+	if c == '_' && i > 0 && isWordChar(text[i-1]) {
+		return 0, "", "", false
+	}
+
+	for j := i + size + 1; j+size <= len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			if codeEnd, _, found := codeSpan(text, j); found {
+				j = codeEnd - 1
+			}
+			continue
+		}
+		if text[j] != c || (size == 2 && text[j+1] != c) || isSpace(text[j-1]) {
+			continue
+		}
+		// The closer must not be part of a longer run, e.g. the end of **strong** for *em*
+		after := j + size
+		if after < len(text) && text[after] == c {
+			continue
+		}
+		if size == 1 && text[j-1] == c {
+			continue
+		}
+		if c == '_' && after < len(text) && isWordChar(text[after]) {
+			continue
+		}
+		return after, text[i+size : j], tag, true
+	}
+	return 0, "", "", false
+}
+
+// link parses [label](destination "optional title") starting at text[i]
+func link(text string, i int) (end int, label, destination string, ok bool) {
+	// Find the matching ], allowing nested brackets in the label
+	depth := 0
+	closing := -1
+	for j := i; j < len(text) && closing < 0; j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(text) || text[closing+1] != '(' {
+		return 0, "", "", false
+	}
+	label = text[i+1 : closing]
+
+	j := closing + 2
+	for j < len(text) && text[j] == ' ' {
+		j++
+	}
+
+	// Destination: <...> or up to a space or the ) that closes the link, keeping balanced parentheses
+	if j < len(text) && text[j] == '<' {
+		endOfDestination := strings.IndexByte(text[j:], '>')
+		if endOfDestination < 0 {
+			return 0, "", "", false
+		}
+		destination = text[j+1 : j+endOfDestination]
+		j += endOfDestination + 1
+	} else {
+		start, parens := j, 0
+		for ; j < len(text) && !isSpace(text[j]); j++ {
+			if text[j] == '(' {
+				parens++
+			} else if text[j] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		destination = text[start:j]
+	}
+
+	// Optional title, which is not rendered
+	for j < len(text) && text[j] == ' ' {
+		j++
+	}
+	if j < len(text) && (text[j] == '"' || text[j] == '\'') {
+		endOfTitle := strings.IndexByte(text[j+1:], text[j])
+		if endOfTitle < 0 {
+			return 0, "", "", false
+		}
+		j += endOfTitle + 2
+		for j < len(text) && text[j] == ' ' {
+			j++
+		}
+	}
+
+	if j >= len(text) || text[j] != ')' {
+		return 0, "", "", false
+	}
+	return j + 1, label, destination, true
+}
+
+// bareURLLength returns the length of an http(s) URL written as plain text at text[i], or 0.
+// Trailing punctuation belongs to the sentence, and a ) only to the URL if it opened one.
+func bareURLLength(text string, i int) int {
+	if i > 0 && isWordChar(text[i-1]) {
+		return 0
+	}
+	rest := text[i:]
+	scheme := len("https://")
+	if strings.HasPrefix(rest, "http://") {
+		scheme = len("http://")
+	} else if !strings.HasPrefix(rest, "https://") {
+		return 0
+	}
+
+	n := 0
+	for n < len(rest) && !isSpace(rest[n]) && rest[n] != '<' {
+		n++
+	}
+	for n > 0 {
+		last := rest[n-1]
+		if strings.IndexByte(".,:;!?*_~'\"", last) >= 0 {
+			n--
+			continue
+		}
+		if last == ')' && strings.Count(rest[:n], "(") < strings.Count(rest[:n], ")") {
+			n--
+			continue
+		}
+		break
+	}
+	if n <= scheme {
+		return 0
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIPunctuation(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
@@ -0,0 +1,352 @@
+// Package markdown renders the Markdown subset allowed in posts and comments to HTML:
+// emphasis, inline code, fenced code blocks, links, lists and block quotes.
+//
+// The output is safe to insert into a page as is. Raw HTML in the source is escaped like any
+// other text, elements are only written through the allowlist in html.go, and link targets are
+// limited to http(s), mailto and paths on the forum itself.
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"real-time-forum/config"
+)
+
+// maxNesting limits nested quotes, lists and emphasis; deeper markup is rendered as text
+const maxNesting = 8
+
+var (
+	// listItemRegex matches "- item", "* item", "+ item", "1. item" and "1) item"
+	listItemRegex = regexp.MustCompile(`^( {0,3})([-*+]|[0-9]{1,9}[.)])( +|$)(.*)$`)
+
+	// codeLanguageRegex is what a fenced code block may declare as its language
+	codeLanguageRegex = regexp.MustCompile(`^[a-zA-Z0-9_+-]{1,32}$`)
+)
+
+// renderer holds the output of one Render call
+type renderer struct {
+	out      strings.Builder
+	siteHost string   // links to this host are not external
+	links    []string // external links in the order they were written
+}
+
+// Render converts Markdown source to sanitized HTML
+func Render(source string) string {
+	r := render(source)
+	return strings.TrimSuffix(r.out.String(), "\n")
+}
+
+// ExternalLinks returns the distinct http(s) links to other sites in source, in the order they
+// appear. Links inside code are not links, so the source is parsed exactly as Render does.
+func ExternalLinks(source string) []string {
+	r := render(source)
+
+	seen := map[string]bool{}
+	links := []string{}
+	for _, link := range r.links {
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// render parses source into a renderer holding the HTML and the links found
+func render(source string) *renderer {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandLeadingTabs(strings.TrimRight(line, " \t"))
+	}
+
+	r := &renderer{siteHost: siteHost()}
+	r.blocks(lines, 0, false)
+	return r
+}
+
+// siteHost returns the host of the forum frontend, used to tell internal links from external ones
+func siteHost() string {
+	u, err := url.Parse(config.Config.FrontendBaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// blocks renders a sequence of lines as block elements. In a tight list item paragraphs are not
+// wrapped in <p>.
+func (r *renderer) blocks(lines []string, depth int, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case isFenceOpen(line):
+			i = r.codeBlock(lines, i)
+		case depth < maxNesting && isQuote(line):
+			i = r.blockquote(lines, i, depth)
+		case depth < maxNesting && isListItem(line):
+			i = r.list(lines, i, depth)
+		default:
+			i = r.paragraph(lines, i, depth, tight)
+		}
+	}
+}
+
+// paragraph renders consecutive text lines; single line breaks are kept as <br>
+func (r *renderer) paragraph(lines []string, i, depth int, tight bool) int {
+	text := []string{strings.TrimLeft(lines[i], " ")}
+	for i++; i < len(lines) && !isBlank(lines[i]) && !interruptsParagraph(lines[i]); i++ {
+		text = append(text, strings.TrimLeft(lines[i], " "))
+	}
+
+	if !tight {
+		r.open("p")
+	}
+	r.inline(strings.Join(text, "\n"), depth, false)
+	if !tight {
+		r.close("p")
+	}
+	if !tight || i < len(lines) {
+		r.out.WriteString("\n")
+	}
+	return i
+}
+
+// codeBlock renders a fenced code block (``` or ~~~) with an optional language
+func (r *renderer) codeBlock(lines []string, i int) int {
+	fence, info := parseFence(lines[i])
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		if closesFence(lines[i], fence) {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	r.open("pre")
+	if language := strings.Fields(info); len(language) > 0 && codeLanguageRegex.MatchString(language[0]) {
+		r.open("code", "class", "language-"+language[0])
+	} else {
+		r.open("code")
+	}
+	if len(code) > 0 {
+		r.text(strings.Join(code, "\n") + "\n")
+	}
+	r.close("code")
+	r.close("pre")
+	r.out.WriteString("\n")
+	return i
+}
+
+// blockquote renders consecutive "> " lines, whose content can hold any other block
+func (r *renderer) blockquote(lines []string, i, depth int) int {
+	var inner []string
+	for ; i < len(lines) && isQuote(lines[i]); i++ {
+		line := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+		inner = append(inner, strings.TrimPrefix(line, " "))
+	}
+
+	r.open("blockquote")
+	r.out.WriteString("\n")
+	r.blocks(inner, depth+1, false)
+	r.close("blockquote")
+	r.out.WriteString("\n")
+	return i
+}
+
+// list renders consecutive items of the same kind of list. Lines indented to an item's content
+// belong to it, so lists can be nested.
+func (r *renderer) list(lines []string, i, depth int) int {
+	first := parseListItem(lines[i])
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+
+	if first.ordered && first.start != 1 {
+		r.open(tag, "start", strconv.Itoa(first.start))
+	} else {
+		r.open(tag)
+	}
+	r.out.WriteString("\n")
+
+	for i < len(lines) {
+		if !isListItem(lines[i]) {
+			break
+		}
+		item := parseListItem(lines[i])
+		if !first.sameList(item) {
+			break
+		}
+
+		body := []string{item.content}
+		loose := false
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				// A blank line only continues the item if the next line is indented into it
+				if i+1 < len(lines) && !isBlank(lines[i+1]) && indentation(lines[i+1]) >= item.indent {
+					body = append(body, "")
+					loose = true
+					continue
+				}
+				break
+			}
+			if indentation(line) >= item.indent {
+				body = append(body, line[item.indent:])
+				continue
+			}
+			// Unindented text continues the item's paragraph unless it starts a new block or item
+			if interruptsParagraph(line) || body[len(body)-1] == "" || (isListItem(line) && first.sameList(parseListItem(line))) {
+				break
+			}
+			body = append(body, strings.TrimLeft(line, " "))
+		}
+
+		r.open("li")
+		r.blocks(body, depth+1, !loose)
+		r.close("li")
+		r.out.WriteString("\n")
+
+		// Blank lines between two items of the same list do not end it
+		next := i
+		for next < len(lines) && isBlank(lines[next]) {
+			next++
+		}
+		if next == i || next == len(lines) || !isListItem(lines[next]) {
+			continue
+		}
+		if first.sameList(parseListItem(lines[next])) {
+			i = next
+		}
+	}
+
+	r.close(tag)
+	r.out.WriteString("\n")
+	return i
+}
+
+// listItem is the marker line of a list item
+type listItem struct {
+	ordered   bool
+	delimiter byte // '-', '*' or '+' for bullets, '.' or ')' for numbers
+	start     int
+	indent    int // column where the item's content starts
+	content   string
+}
+
+// sameList reports whether other continues the list started by item
+func (item listItem) sameList(other listItem) bool {
+	return item.ordered == other.ordered && item.delimiter == other.delimiter
+}
+
+func isListItem(line string) bool {
+	return listItemRegex.MatchString(line)
+}
+
+func parseListItem(line string) listItem {
+	m := listItemRegex.FindStringSubmatch(line)
+	marker := m[2]
+	item := listItem{
+		delimiter: marker[len(marker)-1],
+		indent:    len(m[1]) + len(marker) + len(m[3]),
+		content:   m[4],
+	}
+	if m[3] == "" {
+		item.indent++
+	}
+	if n, err := strconv.Atoi(marker[:len(marker)-1]); err == nil {
+		item.ordered = true
+		item.start = n
+	}
+	return item
+}
+
+// interruptsParagraph reports whether a line starts a new block instead of continuing a
+// paragraph. As in CommonMark, only a numbered list starting at 1 can interrupt one, so a
+// line like "2024. What a year" stays text.
+func interruptsParagraph(line string) bool {
+	if isFenceOpen(line) || isQuote(line) {
+		return true
+	}
+	if !isListItem(line) {
+		return false
+	}
+	item := parseListItem(line)
+	return item.content != "" && (!item.ordered || item.start == 1)
+}
+
+func isQuote(line string) bool {
+	return indentation(line) <= 3 && strings.HasPrefix(strings.TrimLeft(line, " "), ">")
+}
+
+func isFenceOpen(line string) bool {
+	fence, _ := parseFence(line)
+	return fence != ""
+}
+
+// parseFence returns the fence (three or more ` or ~) and the info string of an opening fence line
+func parseFence(line string) (fence, info string) {
+	if indentation(line) > 3 {
+		return "", ""
+	}
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", ""
+	}
+
+	n := runLength(trimmed, 0)
+	if n < 3 {
+		return "", ""
+	}
+	info = strings.TrimSpace(trimmed[n:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", ""
+	}
+	return trimmed[:n], info
+}
+
+// closesFence reports whether line closes a code block opened with fence
+func closesFence(line, fence string) bool {
+	if indentation(line) > 3 {
+		return false
+	}
+	trimmed := strings.TrimLeft(line, " ")
+	n := runLength(trimmed, 0)
+	return n >= len(fence) && trimmed[0] == fence[0] && strings.TrimSpace(trimmed[n:]) == ""
+}
+
+// runLength counts how often the byte at s[i] repeats from i on
+func runLength(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// expandLeadingTabs turns tabs in the indentation into 4 spaces, so indents can be compared
+func expandLeadingTabs(line string) string {
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	if !strings.Contains(line[:indent], "\t") {
+		return line
+	}
+	return strings.ReplaceAll(line[:indent], "\t", "    ") + line[indent:]
+}
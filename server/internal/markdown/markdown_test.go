@@ -0,0 +1,229 @@
+package markdown
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"real-time-forum/config"
+)
+
+func init() {
+	config.Config.FrontendBaseURL = "http://localhost:3000"
+}
+
+func TestRenderLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"javascript", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"javascript mixed case", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"javascript entity", "[x](&#106;avascript:alert(1))", "<p>x</p>"},
+		{"javascript with tab", "[x](java\tscript:alert(1))", "<p>[x](java\tscript:alert(1))</p>"},
+		{"data", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"vbscript", "[x](vbscript:msgbox)", "<p>x</p>"},
+		{"protocol relative", "[x](//evil.example/path)", "<p>x</p>"},
+		{"backslash host", `[x](/\evil.example)`, "<p>x</p>"},
+		{"http without host", "[x](https:///nohost)", "<p>x</p>"},
+		{"empty mailto", "[x](mailto:)", "<p>x</p>"},
+		{"unsafe link inside emphasis", "**[x](javascript:alert(1))**", "<p><strong>x</strong></p>"},
+		{"forum path", "[x](/posts/1)", `<p><a href="/posts/1">x</a></p>`},
+		{"forum host", "[x](http://localhost:3000/post/1)", `<p><a href="http://localhost:3000/post/1">x</a></p>`},
+		{"mailto", "[x](mailto:a@example.com)", `<p><a href="mailto:a@example.com">x</a></p>`},
+		{
+			"external",
+			"[x](https://example.com/a?b=1&c=2)",
+			`<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			"title is dropped",
+			`[x](https://example.com "title")`,
+			`<p><a href="https://example.com" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			"nested link is text",
+			"[[x](https://a.example)](https://b.example)",
+			`<p><a href="https://b.example" rel="nofollow noopener">[x](https://a.example)</a></p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderAttributeQuoting(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"double quote in href",
+			`[x](https://example.com/"onmouseover="alert(1))`,
+			`<p><a href="https://example.com/&#34;onmouseover=&#34;alert(1)" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			"single quote and tag in href",
+			"[x](https://example.com/'><script>)",
+			`<p><a href="https://example.com/&#39;&gt;&lt;script&gt;" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			"bare URL followed by markup",
+			`https://example.com/"><img src=x onerror=alert(1)>`,
+			`<p><a href="https://example.com/&#34;&gt;" rel="nofollow noopener">https://example.com/&#34;&gt;</a>&lt;img src=x onerror=alert(1)&gt;</p>`,
+		},
+		{
+			"quote in code language",
+			"```\" onclick=\"x\n code\n```",
+			"<pre><code> code\n</code></pre>",
+		},
+		{
+			"markup in code language",
+			"```js\"><script>\nx\n```",
+			"<pre><code>x\n</code></pre>",
+		},
+		{
+			"valid code language",
+			"```go\nx\n```",
+			"<pre><code class=\"language-go\">x\n</code></pre>",
+		},
+		{"list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesRawHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"script", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"event handler", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"anchor", `<a href="javascript:alert(1)">x</a>`, "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>"},
+		{"allowed tag", "a <b>bold</b> & 'x'", "<p>a &lt;b&gt;bold&lt;/b&gt; &amp; &#39;x&#39;</p>"},
+		{"inline code", "`<script>`", "<p><code>&lt;script&gt;</code></p>"},
+		{
+			"code block",
+			"```html\n<script>alert(1)</script>\n```",
+			"<pre><code class=\"language-html\">&lt;script&gt;alert(1)&lt;/script&gt;\n</code></pre>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderNestingLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		tag    string
+		rest   string // what is left as text once the limit is reached
+	}{
+		{"block quotes", strings.Repeat(">", 20) + " deep", "<blockquote>", strings.Repeat("&gt;", 12) + " deep"},
+		{"lists", strings.Repeat("- ", 12) + "item", "<ul>", "- - - - item"},
+		{"emphasis", strings.Repeat("*", 20) + "x" + strings.Repeat("*", 20), "<strong>", "****"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.source)
+			if n := strings.Count(got, tt.tag); n > maxNesting {
+				t.Errorf("Render(%q) opened %s %d times", tt.source, tt.tag, n)
+			}
+			if !strings.Contains(got, tt.rest) {
+				t.Errorf("Render(%q) = %q, want %q left as text", tt.source, got, tt.rest)
+			}
+		})
+	}
+
+	// A block quote nests exactly maxNesting levels deep
+	got := Render(strings.Repeat(">", 20) + " deep")
+	if n := strings.Count(got, "<blockquote>"); n != maxNesting {
+		t.Errorf("got %d nested block quotes, want %d", n, maxNesting)
+	}
+}
+
+// tagRegex matches the tags in rendered HTML; attribute values never contain a raw quote or >
+var tagRegex = regexp.MustCompile(`<(/?)([a-z]+)((?: [a-z]+="[^"<>]*")*)>`)
+
+var attrRegex = regexp.MustCompile(` ([a-z]+)="`)
+
+// TestRenderOnlyAllowedMarkup renders hostile input and checks that every tag in the output is on
+// the allowlist with allowed attributes, and that no other '<' reaches the output
+func TestRenderOnlyAllowedMarkup(t *testing.T) {
+	sources := []string{
+		"<script>alert(1)</script>",
+		"<svg/onload=alert(1)>",
+		"[x](javascript:alert(1))",
+		`[<img src=x>](https://example.com/"><script>)`,
+		"**<b>x</b>** _<i onclick=x>y</i>_",
+		"> <iframe src=//evil.example>\n> - <style>x</style>",
+		"```\"><script>\n</code></pre><script>\n```",
+		"`</code><script>`",
+		"1. <a href=x>\n2) [x](data:text/html,<script>)",
+		"https://example.com/<script> mailto:x@example.com\"><img>",
+		strings.Repeat("[", 50) + "x" + strings.Repeat("](javascript:x)", 50),
+		strings.Repeat("> - ", 30) + "<script>",
+	}
+	for _, source := range sources {
+		got := Render(source)
+		stripped := tagRegex.ReplaceAllStringFunc(got, func(tag string) string {
+			m := tagRegex.FindStringSubmatch(tag)
+			allowedAttrs, ok := allowedTags[m[2]]
+			if !ok {
+				t.Errorf("Render(%q) wrote <%s%s>", source, m[1], m[2])
+				return ""
+			}
+			for _, attr := range attrRegex.FindAllStringSubmatch(m[3], -1) {
+				if m[1] != "" || !allowedAttrs[attr[1]] {
+					t.Errorf("Render(%q) wrote attribute %s on <%s%s>", source, attr[1], m[1], m[2])
+				}
+			}
+			return ""
+		})
+		if strings.ContainsAny(stripped, "<>") {
+			t.Errorf("Render(%q) = %q, has unescaped markup", source, got)
+		}
+		if strings.Contains(strings.ToLower(got), `href="javascript`) || strings.Contains(got, `href="data`) {
+			t.Errorf("Render(%q) = %q, has an unsafe link", source, got)
+		}
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"none", "no links here", []string{}},
+		{"unsafe and internal links", "[a](javascript:x) [b](/posts/1) [c](http://localhost:3000/x) [d](mailto:a@b.c)", []string{}},
+		{"distinct in order", "https://b.example [a](https://a.example) https://b.example", []string{"https://b.example", "https://a.example"}},
+		{"code is not a link", "`https://a.example`\n```\nhttps://b.example\n```", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExternalLinks(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExternalLinks(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
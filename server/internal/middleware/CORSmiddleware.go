@@ -10,16 +10,19 @@ func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
-		// Check if the origin matches our allowed frontend
-		if origin == config.Config.AllowedOrigins {
+		// Check if the origin matches one of our allowed frontends
+		if origin != "" && config.IsOriginAllowed(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
+		// The response depends on the Origin, so caches must not share it between origins
+		w.Header().Add("Vary", "Origin")
 
 		// Always set these headers for API functionality
 		w.Header().Set("Access-Control-Allow-Methods", config.Config.AllowedMethods)
 		w.Header().Set("Access-Control-Allow-Headers", config.Config.AllowedHeaders)
 		w.Header().Set("Access-Control-Max-Age", "86400")
+		w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
 
 		// Handle preflight OPTIONS requests
 		if r.Method == "OPTIONS" {
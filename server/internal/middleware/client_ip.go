@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"real-time-forum/config"
+)
+
+var (
+	trustedProxies     []*net.IPNet
+	trustedProxiesOnce sync.Once
+)
+
+// loadTrustedProxies parses TRUSTED_PROXIES (comma-separated IPs or CIDR ranges) once
+func loadTrustedProxies() {
+	for _, entry := range strings.Split(config.Config.TrustedProxies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+			continue
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+}
+
+// isTrustedProxy reports whether ip belongs to one of the configured proxies
+func isTrustedProxy(ip string) bool {
+	trustedProxiesOnce.Do(loadTrustedProxies)
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent the request. Forwarding headers are
+// only honored when the direct peer is a trusted proxy, because any client can set them.
+func ClientIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr // Use as-is if can't parse
+	}
+
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	// X-Forwarded-For: every proxy appends the address it received the request from, so walk it
+	// from the right and skip our own proxies; the first other address is the client
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !isTrustedProxy(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+
+	// X-Real-IP (nginx reverse proxy)
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
+		return xri
+	}
+
+	// CF-Connecting-IP (Cloudflare)
+	if cfip := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); net.ParseIP(cfip) != nil {
+		return cfip
+	}
+
+	return remoteIP
+}
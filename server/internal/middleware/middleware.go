@@ -12,14 +12,16 @@ import (
 )
 
 type AuthMiddleware struct {
-	userRepo    *repository.UserRepository
-	sessionRepo *repository.SessionRepository
+	userRepo     *repository.UserRepository
+	sessionRepo  *repository.SessionRepository
+	apiTokenRepo *repository.APITokenRepository
 }
 
-func NewMiddleware(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository) *AuthMiddleware {
+func NewMiddleware(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, apiTokenRepo *repository.APITokenRepository) *AuthMiddleware {
 	return &AuthMiddleware{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		apiTokenRepo: apiTokenRepo,
 	}
 }
 
@@ -27,12 +29,20 @@ type contextKey string
 
 // Define constants using this type
 const (
-	userContextKey contextKey = "user"
+	userContextKey     contextKey = "user"
+	sessionContextKey  contextKey = "session"
+	apiTokenContextKey contextKey = "api_token"
 )
 
-// Authenticate middleware verifies authentication and sets user in context
+// Authenticate middleware verifies authentication and sets user in context.
+// A personal access token in "Authorization: Bearer" takes precedence over the session cookie.
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			m.authenticateToken(w, r, next, token)
+			return
+		}
+
 		// Get the session cookie using config session name
 		cookie, err := r.Cookie(config.Config.SessionName)
 		if err != nil {
@@ -57,8 +67,15 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// Set user in context
+		// Re-issue the CSRF cookie if the browser does not have the session's token
+		// (cookie deleted, or a session from before CSRF tokens existed)
+		if csrfCookie, err := r.Cookie(config.Config.CSRFCookieName); err != nil || csrfCookie.Value != session.CSRFToken {
+			utils.SetCSRFCookie(session.CSRFToken, w, session.ExpiresAt)
+		}
+
+		// Set user and session in context
 		ctx := context.WithValue(r.Context(), userContextKey, user)
+		ctx = context.WithValue(ctx, sessionContextKey, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -75,6 +92,22 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// RequireAdmin middleware ensures the user is authenticated and has the admin role
+func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetCurrentUser(r)
+		if user == nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, errors.New("unauthorized access").Error())
+			return
+		}
+		if user.Role != models.RoleAdmin {
+			utils.RespondWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // GetCurrentUser returns the authenticated user from the context
 func GetCurrentUser(r *http.Request) *models.User {
 	userValue := r.Context().Value(userContextKey)
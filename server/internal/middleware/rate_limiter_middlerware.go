@@ -1,116 +1,78 @@
 package middleware
 
 import (
-	"net"
+	"math"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
 
+	"real-time-forum/internal/ratelimit"
 	"real-time-forum/internal/utils"
 )
 
-// RateLimiter tracks request rates per IP and limits excessive requests
+// RateLimiter applies token-bucket limits per client: authenticated requests are counted per
+// user, anonymous ones per IP. Routes registered with SetRoutePolicy have their own budget,
+// every other route shares the default policy.
 type RateLimiter struct {
-	requests    map[string][]time.Time
-	windowSize  time.Duration
-	maxRequests int
-	mutex       sync.Mutex
+	defaultLimiter *ratelimit.Limiter
+	routeLimiters  map[string]*ratelimit.Limiter // by ServeMux pattern
 }
 
-// NewRateLimiter creates a new rate limiter with the specified window size and max requests
-func NewRateLimiter(windowSize time.Duration, maxRequests int) *RateLimiter {
+// NewRateLimiter creates a rate limiter with the policy used for routes without their own
+func NewRateLimiter(defaultPolicy ratelimit.Policy) *RateLimiter {
 	return &RateLimiter{
-		requests:    make(map[string][]time.Time),
-		windowSize:  windowSize,
-		maxRequests: maxRequests,
-		mutex:       sync.Mutex{},
+		defaultLimiter: ratelimit.NewLimiter(defaultPolicy),
+		routeLimiters:  make(map[string]*ratelimit.Limiter),
 	}
 }
 
-// cleanupOldRequests removes requests outside the current time window
-func (rl *RateLimiter) cleanupOldRequests(ip string) {
-	now := time.Now()
-	keepFrom := now.Add(-rl.windowSize)
-
-	var validRequests []time.Time
-	for _, reqTime := range rl.requests[ip] {
-		if reqTime.After(keepFrom) {
-			validRequests = append(validRequests, reqTime)
-		}
+// SetRoutePolicy gives the route patterns (exactly as registered on the ServeMux, e.g.
+// "POST /api/posts/create") one shared budget separate from the default policy
+func (rl *RateLimiter) SetRoutePolicy(policy ratelimit.Policy, patterns ...string) {
+	limiter := ratelimit.NewLimiter(policy)
+	for _, pattern := range patterns {
+		rl.routeLimiters[pattern] = limiter
 	}
-
-	rl.requests[ip] = validRequests
-}
-
-// checkRateLimit checks if an IP has exceeded the rate limit
-func (rl *RateLimiter) checkRateLimit(ip string) bool {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
-	// First cleanup old requests
-	rl.cleanupOldRequests(ip)
-
-	// Check current count against limit
-	return len(rl.requests[ip]) >= rl.maxRequests
-}
-
-// addRequest records a new request for an IP
-func (rl *RateLimiter) addRequest(ip string) {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
-	// First cleanup old requests
-	rl.cleanupOldRequests(ip)
-
-	// Add current request
-	rl.requests[ip] = append(rl.requests[ip], time.Now())
 }
 
-// Limit is the middleware handler for rate limiting
-func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+// Limit is the middleware handler for rate limiting; it looks up the route in mux to pick the policy
+func (rl *RateLimiter) Limit(mux *http.ServeMux) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getClientIP(r)
+		limiter := rl.defaultLimiter
+		if _, pattern := mux.Handler(r); pattern != "" {
+			if routeLimiter, ok := rl.routeLimiters[pattern]; ok {
+				limiter = routeLimiter
+			}
+		}
+
+		result := limiter.Allow(rateLimitKey(r))
+		if result.Limit > 0 {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			w.Header().Set("RateLimit-Policy", limiter.Policy().String())
+		}
 
-		// Check if rate limited
-		if rl.checkRateLimit(ip) {
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 			utils.RespondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded. Please try again later.")
 			return
 		}
 
-		// Record this request
-		rl.addRequest(ip)
-
 		// Continue with the next handler
-		next.ServeHTTP(w, r)
+		mux.ServeHTTP(w, r)
 	})
 }
 
-// Get real client IP address (handles proxies and load balancers)
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (most common proxy header)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Can contain multiple IPs, take the first (original client)
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// Check X-Real-IP header (nginx reverse proxy)
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
-	}
-
-	// Check CF-Connecting-IP header (Cloudflare)
-	if cfip := r.Header.Get("CF-Connecting-IP"); cfip != "" {
-		return strings.TrimSpace(cfip)
+// rateLimitKey identifies the client: the user when authenticated, otherwise the IP address
+func rateLimitKey(r *http.Request) string {
+	if user := GetCurrentUser(r); user != nil {
+		return "user:" + user.ID
 	}
+	return "ip:" + ClientIP(r)
+}
 
-	// Fall back to direct connection IP
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr // Return as-is if can't parse
-	}
-	return host
+// ceilSeconds rounds a duration up to whole seconds, as the RateLimit and Retry-After headers expect
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -1,6 +1,11 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+
+	"real-time-forum/config"
+)
 
 // SecurityHeaders is a middleware that sets security-related HTTP headers
 // to protect against common web vulnerabilities.
@@ -9,14 +14,17 @@ func SecurityHeaders(next http.Handler) http.Handler {
 		//  Prevent MIME sniffing attacks
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 
-		// MINIMAL CSP for JSON API only
-		csp := "default-src 'none'; " + // Block everything by default
-			"connect-src 'self' http://localhost:3000; " + // Allow API calls
-			"frame-ancestors 'none'; " + // Prevent iframe embedding
-			"base-uri 'self'; " + // Prevent base tag hijacking
-			"form-action 'self'" // Prevent form hijacking
+		// MINIMAL CSP for JSON API only (built from ALLOWED_ORIGINS unless CONTENT_SECURITY_POLICY is set)
+		w.Header().Set("Content-Security-Policy", config.Config.Security.ContentSecurityPolicy)
 
-		w.Header().Set("Content-Security-Policy", csp)
+		// HTTPS only from now on (production default)
+		if maxAge := config.Config.Security.HSTSMaxAge; maxAge > 0 {
+			hsts := "max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+			if config.Config.Security.HSTSIncludeSubdomains {
+				hsts += "; includeSubDomains"
+			}
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
 
 		// Privacy protection
 		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
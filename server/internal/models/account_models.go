@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// Account deletion policies (ACCOUNT_DELETION_POLICY)
+const (
+	// AccountDeletionAnonymize keeps published posts, comments and reactions under an anonymous account
+	AccountDeletionAnonymize = "anonymize"
+	// AccountDeletionDelete removes everything the user created
+	AccountDeletionDelete = "delete"
+)
+
+// DeleteAccountRequest re-confirms the password before an account is closed
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DataExport is everything stored about a user; each field becomes a JSON file in the export ZIP
+type DataExport struct {
+	Profile       ExportProfile
+	Posts         []ExportPost
+	Comments      []ExportComment
+	Reactions     []ExportReaction
+	Notifications []ExportNotification
+	Messages      []ExportMessage
+	Bookmarks     []ExportBookmark
+}
+
+// ExportProfile is the account itself, including linked OAuth providers
+type ExportProfile struct {
+	ID                string                   `json:"id"`
+	Username          string                   `json:"username"`
+	Age               int                      `json:"age"`
+	Gender            string                   `json:"gender"`
+	FirstName         string                   `json:"first_name"`
+	LastName          string                   `json:"last_name"`
+	Email             string                   `json:"email"`
+	Role              string                   `json:"role"`
+	AvatarURL         string                   `json:"avatar_url,omitempty"`
+	Bio               string                   `json:"bio"`
+	Location          string                   `json:"location"`
+	CreatedAt         time.Time                `json:"created_at"`
+	ConnectedAccounts []ExportConnectedAccount `json:"connected_accounts"`
+}
+
+// ExportConnectedAccount is a linked OAuth provider (tokens are never exported)
+type ExportConnectedAccount struct {
+	Provider         string    `json:"provider"`
+	ProviderUsername string    `json:"provider_username"`
+	ProviderEmail    string    `json:"provider_email"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ExportPost includes drafts and soft-deleted posts that still exist
+type ExportPost struct {
+	PostID     string     `json:"post_id"`
+	Title      string     `json:"title,omitempty"`
+	Content    string     `json:"content"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Categories []string   `json:"categories"`
+	Images     []string   `json:"images"`
+}
+
+type ExportComment struct {
+	CommentID string     `json:"comment_id"`
+	PostID    string     `json:"post_id"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type ExportReaction struct {
+	TargetType   string    `json:"target_type"` // "post" or "comment"
+	TargetID     string    `json:"target_id"`
+	ReactionType int       `json:"reaction_type"` // 1 = like, 2 = dislike
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ExportNotification struct {
+	NotificationID  string    `json:"notification_id"`
+	TriggerUsername string    `json:"trigger_username"`
+	PostID          string    `json:"post_id"`
+	Action          string    `json:"action"`
+	IsRead          bool      `json:"is_read"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ExportMessage is a private message sent or received by the user
+type ExportMessage struct {
+	MessageID     string    `json:"message_id"`
+	Direction     string    `json:"direction"` // "sent" or "received"
+	OtherUserID   string    `json:"other_user_id"`
+	OtherUsername string    `json:"other_username"`
+	Content       string    `json:"content"`
+	IsRead        bool      `json:"is_read"`
+	CreatedAt     time.Time `json:"created_at"`
+	Images        []string  `json:"images"`
+}
+
+type ExportBookmark struct {
+	PostID    string    `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Personal access token scopes
+const (
+	TokenScopeRead     = "read"     // GET requests outside of private messages
+	TokenScopeWrite    = "write"    // creating, editing and deleting content, reactions, bookmarks
+	TokenScopeMessages = "messages" // private messages and the WebSocket connection
+)
+
+// APITokenPrefix starts every personal access token so leaked tokens are easy to recognize
+const APITokenPrefix = "rtf_"
+
+// APIToken is a personal access token used with "Authorization: Bearer"; only its hash is stored
+type APIToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPITokenRequest creates a personal access token; ExpiresInDays 0 uses the default lifetime
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreateAPITokenResponse carries the new token in plain text; it is only shown once
+type CreateAPITokenResponse struct {
+	Token    string   `json:"token"`
+	APIToken APIToken `json:"api_token"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Audit log event types
+const (
+	AuditEventAccountLocked = "account_locked"
+	AuditEventIPLocked      = "ip_locked"
+)
+
+// AuditEvent is an entry of the security audit log
+type AuditEvent struct {
+	ID        string    `json:"event_id"`
+	Event     string    `json:"event"`
+	UserID    string    `json:"user_id,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditLogResponse is a page of the audit log, newest first
+type AuditLogResponse struct {
+	Events     []*AuditEvent  `json:"events"`
+	Pagination PaginationInfo `json:"pagination"`
+}
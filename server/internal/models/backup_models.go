@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// BackupManifest is stored inside every backup archive and describes its contents
+type BackupManifest struct {
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Uploads       int       `json:"uploads"` // number of uploaded files included
+}
+
+// BackupInfo describes a backup archive on disk
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupListResponse lists the available backups, newest first
+type BackupListResponse struct {
+	Backups []BackupInfo `json:"backups"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Bookmark action constants
+const (
+	ActionBookmarkAdded   = "bookmark_added"
+	ActionBookmarkRemoved = "bookmark_removed"
+)
+
+// Bookmark - Database model for bookmarks table
+type Bookmark struct {
+	UserID    string    `json:"user_id"`
+	PostID    string    `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookmarkRequest - Request payload for saving/unsaving a post
+type BookmarkRequest struct {
+	PostID string `json:"post_id" binding:"required"`
+}
+
+// BookmarkResult represents the outcome of a bookmark toggle operation
+type BookmarkResult struct {
+	Action       string `json:"action"`
+	Message      string `json:"message"`
+	IsBookmarked bool   `json:"is_bookmarked"`
+}
+
+// Validate validates the bookmark request
+func (req *BookmarkRequest) Validate() error {
+	if req.PostID == "" {
+		return errors.New("post_id is required and cannot be empty")
+	}
+	return nil
+}
+
+// NewBookmarkResult creates a BookmarkResult with the appropriate message
+func NewBookmarkResult(action string) *BookmarkResult {
+	if action == ActionBookmarkAdded {
+		return &BookmarkResult{Action: action, Message: "Post saved to your bookmarks", IsBookmarked: true}
+	}
+	return &BookmarkResult{Action: action, Message: "Post removed from your bookmarks", IsBookmarked: false}
+}
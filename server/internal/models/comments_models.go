@@ -3,13 +3,15 @@ package models
 import "time"
 
 type Comment struct {
-	ID        string     `json:"comment_id"`
-	UserID    string     `json:"user_id"`
-	Username  string     `json:"username"`
-	PostID    string     `json:"post_id"`
-	Content   string     `json:"comment_content"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	ID          string     `json:"comment_id"`
+	UserID      string     `json:"user_id"`
+	Username    string     `json:"username"`
+	AvatarURL   string     `json:"avatar_url"`
+	PostID      string     `json:"post_id"`
+	Content     string     `json:"comment_content"`
+	ContentHTML string     `json:"content_html"` // Content rendered from Markdown and sanitized
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
 
 	// Aggregated metrics
 	LikeCount    int `json:"like_count"`
@@ -18,6 +20,8 @@ type Comment struct {
 	// User context
 	UserReaction *int `json:"user_reaction,omitempty"` // nil, 1=like, 2=dislike
 	IsOwner      bool `json:"is_owner,omitempty"`      // can current user edit/delete
+
+	LinkPreviews []LinkPreview `json:"link_previews"`
 }
 
 // Request models are good
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Email token purposes
+const (
+	EmailTokenPasswordReset     = "password_reset"
+	EmailTokenEmailVerification = "email_verification"
+)
+
+// EmailToken is a consumed password reset or email verification token
+type EmailToken struct {
+	UserID    string
+	Email     string
+	Purpose   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// ForgotPasswordRequest asks for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password using the token from the reset link
+type ResetPasswordRequest struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// VerifyEmailRequest confirms an email address using the token from the verification link
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
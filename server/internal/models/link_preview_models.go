@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Link preview statuses
+const (
+	LinkPreviewOK     = "ok"
+	LinkPreviewFailed = "failed"
+)
+
+// Where the links of a link_previews event were written
+const (
+	LinkPreviewSourcePost    = "post"
+	LinkPreviewSourceComment = "comment"
+	LinkPreviewSourceMessage = "message"
+)
+
+// LinkPreview is the OpenGraph / Twitter card summary of a page linked from a post, comment or message
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+
+	// Cache bookkeeping, not sent to clients
+	Status    string    `json:"-"` // LinkPreviewOK or LinkPreviewFailed
+	Error     string    `json:"-"` // why the fetch failed
+	FetchedAt time.Time `json:"-"`
+}
+
+// LinkPreviewsPayload is pushed over the WebSocket once the links of an item are resolved
+type LinkPreviewsPayload struct {
+	Source   string        `json:"source"`    // LinkPreviewSourcePost, LinkPreviewSourceComment or LinkPreviewSourceMessage
+	SourceID string        `json:"source_id"` // post, comment or message ID
+	PostID   string        `json:"post_id,omitempty"`
+	Previews []LinkPreview `json:"previews"`
+}
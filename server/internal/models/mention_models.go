@@ -0,0 +1,20 @@
+package models
+
+// Where an @mention was written; each source has its own column in the mentions table
+const (
+	MentionSourcePost    = "post"
+	MentionSourceComment = "comment"
+	MentionSourceMessage = "message"
+)
+
+// Notification actions for mentions; the frontend shows them after the author's name
+const (
+	ActionMentionedInPost    = "mentioned you in a post"
+	ActionMentionedInComment = "mentioned you in a comment"
+	ActionMentionedInMessage = "mentioned you in a message"
+)
+
+// UserSearchResponse - Response of the username autocomplete endpoint
+type UserSearchResponse struct {
+	Users []UserSearchResult `json:"users"`
+}
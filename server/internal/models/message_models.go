@@ -13,14 +13,16 @@ type MessageImage struct {
 
 // Message represents a chat message between two users
 type Message struct {
-	MessageID   string         `json:"message_id"`
-	SenderID    string         `json:"sender_id"`
-	SenderName  string         `json:"sender_name"`
-	RecipientID string         `json:"recipient_id"`
-	Content     string         `json:"content"`
-	CreatedAt   time.Time      `json:"created_at"`
-	IsRead      bool           `json:"is_read"`
-	Images      []MessageImage `json:"images"`
+	MessageID       string         `json:"message_id"`
+	SenderID        string         `json:"sender_id"`
+	SenderName      string         `json:"sender_name"`
+	SenderAvatarURL string         `json:"sender_avatar_url"`
+	RecipientID     string         `json:"recipient_id"`
+	Content         string         `json:"content"`
+	CreatedAt       time.Time      `json:"created_at"`
+	IsRead          bool           `json:"is_read"`
+	Images          []MessageImage `json:"images"`
+	LinkPreviews    []LinkPreview  `json:"link_previews"`
 }
 
 // SendMessageRequest is the payload for sending a message via HTTP
@@ -45,6 +47,7 @@ type GetMessagesResponse struct {
 type Conversation struct {
 	UserID      string       `json:"user_id"`
 	Username    string       `json:"username"`
+	AvatarURL   string       `json:"avatar_url"`
 	IsOnline    bool         `json:"is_online"`
 	LastMessage *LastMessage `json:"last_message"`
 	UnreadCount int          `json:"unread_count"`
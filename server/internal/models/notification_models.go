@@ -7,6 +7,7 @@ type Notification struct {
 	NotificationID     string    `json:"notification_id"`
 	UserID             string    `json:"user_id"`              // who gets the notification
 	TriggerUsername    string    `json:"trigger_username"`     // who caused it (e.g., "John")
+	TriggerAvatarURL   string    `json:"trigger_avatar_url"`   // avatar of who caused it ("" if none)
 	PostContentPreview string    `json:"post_content_preview"` // first 50 chars of post content
 	PostID             string    `json:"post_id"`              // link to the post
 	Action             string    `json:"action"`               // "liked" or "commented on"
@@ -14,12 +14,14 @@ type PaginationInfo struct {
 type PaginatedPostsResponse struct {
 	Posts      []*Post        `json:"posts"`
 	Pagination PaginationInfo `json:"pagination"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Opaque token for the next page, empty on the last page
 }
 
 // PaginatedCommentsResponse is the response for paginated comments
 type PaginatedCommentsResponse struct {
 	Comments   []*Comment     `json:"comments"`
 	Pagination PaginationInfo `json:"pagination"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Opaque token for the next page, empty on the last page
 }
 
 // NewPaginationInfo creates pagination metadata from basic parameters
@@ -45,17 +47,19 @@ func NewPaginationInfo(totalCount, limit, offset int) PaginationInfo {
 }
 
 // NewPaginatedPostsResponse creates a paginated posts response
-func NewPaginatedPostsResponse(posts []*Post, totalCount, limit, offset int) *PaginatedPostsResponse {
+func NewPaginatedPostsResponse(posts []*Post, totalCount, limit, offset int, nextCursor string) *PaginatedPostsResponse {
 	return &PaginatedPostsResponse{
 		Posts:      posts,
 		Pagination: NewPaginationInfo(totalCount, limit, offset),
+		NextCursor: nextCursor,
 	}
 }
 
 // NewPaginatedCommentsResponse creates a paginated comments response
-func NewPaginatedCommentsResponse(comments []*Comment, totalCount, limit, offset int) *PaginatedCommentsResponse {
+func NewPaginatedCommentsResponse(comments []*Comment, totalCount, limit, offset int, nextCursor string) *PaginatedCommentsResponse {
 	return &PaginatedCommentsResponse{
 		Comments:   comments,
 		Pagination: NewPaginationInfo(totalCount, limit, offset),
+		NextCursor: nextCursor,
 	}
 }
@@ -2,15 +2,26 @@ package models
 
 import "time"
 
+// Post status constants
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID         string         `json:"post_id"`
-	UserID     string         `json:"user_id"`
-	Username   string         `json:"username"`
-	Categories []PostCategory `json:"categories"`
-	Content    string         `json:"post_content"`
-	ImagePath  *string        `json:"image_path,omitempty"` // IMAGE
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  *time.Time     `json:"updated_at,omitempty"`
+	ID          string         `json:"post_id"`
+	UserID      string         `json:"user_id"`
+	Username    string         `json:"username"`
+	AvatarURL   string         `json:"avatar_url"`
+	Categories  []PostCategory `json:"categories"`
+	Title       string         `json:"title"`
+	Content     string         `json:"post_content"`
+	ContentHTML string         `json:"content_html"`         // Content rendered from Markdown and sanitized
+	ImagePath   *string        `json:"image_path,omitempty"` // IMAGE
+	Status      string         `json:"status"`               // draft or published
+	PublishAt   *time.Time     `json:"publish_at,omitempty"` // scheduled publication time
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   *time.Time     `json:"updated_at,omitempty"`
 
 	// Aggregated metrics
 	LikeCount    int `json:"like_count"`
@@ -20,8 +31,10 @@ type Post struct {
 	// NEW FIELDS for two-call approach:
 	UserReaction *int `json:"user_reaction,omitempty"` // nil, 1=like, 2=dislike
 	IsOwner      bool `json:"is_owner,omitempty"`      // can current user edit/delete
+	IsBookmarked bool `json:"is_bookmarked"`           // saved by current user
 
-	Images []PostImage `json:"images"`
+	Images       []PostImage   `json:"images"`
+	LinkPreviews []LinkPreview `json:"link_previews"`
 }
 
 // CreatePostRequest - Post creation payload
@@ -37,6 +50,18 @@ type UpdatePostRequest struct {
 	ImagesToDelete []string `json:"images_to_delete"`
 }
 
+// PostPublishing - Optional title and publication settings of a post
+type PostPublishing struct {
+	Title     string
+	Status    string     // PostStatusDraft or PostStatusPublished
+	PublishAt *time.Time // nil publishes immediately
+}
+
+// IsLive reports whether a post with these settings is visible to everyone at the given time
+func (pp PostPublishing) IsLive(now time.Time) bool {
+	return pp.Status == PostStatusPublished && (pp.PublishAt == nil || !pp.PublishAt.After(now))
+}
+
 type PostImage struct {
 	ImageID          string    `json:"image_id"`
 	PostID           string    `json:"post_id"`
@@ -13,12 +13,15 @@ type APIResponse struct {
 type LoginResponse struct {
 	User      User   `json:"user"`
 	SessionID string `json:"session_id"`
+	CSRFToken string `json:"csrf_token"` // also set as the readable CSRF cookie
 }
 
 // CreatePostResponse - Lightweight response for post creation
 type CreatePostResponse struct {
-	PostID    string    `json:"post_id"`
-	CreatedAt time.Time `json:"created_at"`
+	PostID    string     `json:"post_id"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 // CreateCommentResponse - Lightweight response for comment creation
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Diff segment operations
+const (
+	DiffOpEqual  = "equal"
+	DiffOpInsert = "insert"
+	DiffOpDelete = "delete"
+)
+
+// Revision is one version of a post or comment.
+// Version 1 is the original content; the highest version is the current content.
+type Revision struct {
+	Version        int       `json:"version"`
+	Content        string    `json:"content"`
+	EditorID       string    `json:"editor_id"`
+	EditorUsername string    `json:"editor_username"`
+	CreatedAt      time.Time `json:"created_at"`
+	IsCurrent      bool      `json:"is_current"`
+}
+
+// RevisionHistoryResponse lists every version of a post or comment, oldest first
+type RevisionHistoryResponse struct {
+	ContentID   string     `json:"content_id"`
+	ContentType string     `json:"content_type"` // "post" or "comment"
+	Revisions   []Revision `json:"revisions"`
+}
+
+// DiffSegment is a run of text that is unchanged, added or removed between two versions
+type DiffSegment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionDiffResponse - Word-level diff between two versions
+type RevisionDiffResponse struct {
+	ContentID   string        `json:"content_id"`
+	ContentType string        `json:"content_type"`
+	From        int           `json:"from"`
+	To          int           `json:"to"`
+	Segments    []DiffSegment `json:"segments"`
+}
@@ -7,6 +7,7 @@ type Session struct {
 	UserID    string    `json:"user_id"`
 	SessionID string    `json:"session_id"`
 	IPAddress string    `json:"ip_address"`
+	CSRFToken string    `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// TwoFactorSetupRequest re-confirms the password before a new TOTP secret is issued
+type TwoFactorSetupRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+// TwoFactorSetupResponse carries the secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`      // base32, for manual entry
+	OTPAuthURL string `json:"otpauth_url"` // otpauth://totp/... URI, usually shown as a QR code
+}
+
+// TwoFactorCodeRequest carries a TOTP code or a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorDisableRequest needs both the password and a current code
+type TwoFactorDisableRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+// RecoveryCodesResponse lists freshly generated recovery codes; they are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus describes the current user's two-factor setup
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorChallenge is returned by the login endpoints when the password was correct
+// but the account also needs a code; the session is created by POST /api/auth/login/2fa
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	PendingToken      string    `json:"pending_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest completes a login with the pending token and a code
+type TwoFactorLoginRequest struct {
+	PendingToken string `json:"pending_token"`
+	Code         string `json:"code"`
+}
@@ -7,6 +7,9 @@ type UserProfile struct {
 	ID        string       `json:"user_id"`
 	Username  string       `json:"username"`
 	Email     string       `json:"email"`
+	AvatarURL string       `json:"avatar_url"`
+	Bio       string       `json:"bio"`
+	Location  string       `json:"location"`
 	CreatedAt time.Time    `json:"created_at"`
 	Stats     ProfileStats `json:"stats"`
 }
@@ -2,16 +2,28 @@ package models
 
 import "time"
 
+// User role constants
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents public user information
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Age       int       `json:"age"`
-	Gender    string    `json:"gender"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               string    `json:"id"`
+	Username         string    `json:"username"`
+	Age              int       `json:"age"`
+	Gender           string    `json:"gender"`
+	FirstName        string    `json:"first_name"`
+	LastName         string    `json:"last_name"`
+	Email            string    `json:"email"`
+	EmailVerified    bool      `json:"email_verified"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	Role             string    `json:"role,omitempty"`
+	AvatarURL        string    `json:"avatar_url"`
+	Bio              string    `json:"bio"`
+	Location         string    `json:"location"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // UserAuth represents internal user data including authentication
@@ -37,3 +49,36 @@ type UserLogin struct {
 	Identifier    string `json:"identifier" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+// UserUpdate - Profile fields the user can change; fields left out keep their value.
+// Changing the email requires CurrentPassword.
+type UserUpdate struct {
+	FirstName       *string `json:"first_name"`
+	LastName        *string `json:"last_name"`
+	Age             *int    `json:"age"`
+	Gender          *string `json:"gender"`
+	Email           *string `json:"email"`
+	Bio             *string `json:"bio"`
+	Location        *string `json:"location"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// PasswordChange - Change password form data
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// PasswordSet - First password of an account that only signs in with OAuth
+type PasswordSet struct {
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// UserSearchResult - Public fields of a user returned by username autocomplete
+type UserSearchResult struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+// Webhook events
+const (
+	WebhookEventPostCreated     = "post.created"
+	WebhookEventCommentCreated  = "comment.created"
+	WebhookEventReactionToggled = "reaction.toggled"
+	WebhookEventUserRegistered  = "user.registered"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventPostCreated,
+	WebhookEventCommentCreated,
+	WebhookEventReactionToggled,
+	WebhookEventUserRegistered,
+}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // gave up after WebhookMaxAttempts
+)
+
+// Webhook is an endpoint registered by an admin. The secret signs every delivery and is
+// only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants event
+func (wh *Webhook) Subscribes(event string) bool {
+	for _, e := range wh.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest registers a webhook endpoint
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// UpdateWebhookRequest changes a webhook; fields that are not sent keep their value
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookDelivery is one event queued for one webhook, with the outcome of its last attempt
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	WebhookID      string     `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	// Filled in for the dispatcher only
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookDeliveriesResponse is a page of a webhook's delivery log
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Pagination PaginationInfo    `json:"pagination"`
+}
+
+// WebhookPayload is the JSON body of a delivery
+type WebhookPayload struct {
+	ID        string      `json:"id"` // delivery ID, also sent as X-Forum-Delivery
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookPostData is the data of a post.created event
+type WebhookPostData struct {
+	PostID    string     `json:"post_id"`
+	UserID    string     `json:"user_id"`
+	Username  string     `json:"username"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// WebhookCommentData is the data of a comment.created event
+type WebhookCommentData struct {
+	CommentID string `json:"comment_id"`
+	PostID    string `json:"post_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Content   string `json:"content"`
+}
+
+// WebhookReactionData is the data of a reaction.toggled event
+type WebhookReactionData struct {
+	Target       string `json:"target"` // "post" or "comment"
+	PostID       string `json:"post_id,omitempty"`
+	CommentID    string `json:"comment_id,omitempty"`
+	UserID       string `json:"user_id"`
+	Username     string `json:"username"`
+	ReactionType int    `json:"reaction_type"`
+	Action       string `json:"action"`
+}
+
+// WebhookUserData is the data of a user.registered event
+type WebhookUserData struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
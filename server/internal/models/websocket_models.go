@@ -12,6 +12,7 @@ const (
 	EventTypeUserOffline    = "user_offline"
 	EventTypeTypingStart    = "typing_start"
 	EventTypeTypingStop     = "typing_stop"
+	EventTypeLinkPreviews   = "link_previews"
 )
 
 // WebSocketMessage represents the generic WebSocket message structure
@@ -30,11 +31,13 @@ type SendMessagePayload struct {
 
 // ReceiveMessagePayload represents the payload for receiving a message
 type ReceiveMessagePayload struct {
-	SenderID   string         `json:"sender_id"`   // User ID of the sender
-	SenderName string         `json:"sender_name"` // Username of the sender
-	Content    string         `json:"content"`     // Message content
-	SentAt     time.Time      `json:"sent_at"`     // Timestamp when message was sent
-	Images     []MessageImage `json:"images"`      // Attached images
+	MessageID       string         `json:"message_id"`        // ID of the message, matched by later link_previews events
+	SenderID        string         `json:"sender_id"`         // User ID of the sender
+	SenderName      string         `json:"sender_name"`       // Username of the sender
+	SenderAvatarURL string         `json:"sender_avatar_url"` // Avatar of the sender ("" if none)
+	Content         string         `json:"content"`           // Message content
+	SentAt          time.Time      `json:"sent_at"`           // Timestamp when message was sent
+	Images          []MessageImage `json:"images"`            // Attached images
 }
 
 // ErrorPayload represents an error message
@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"fmt"
+	"strings"
+
+	"real-time-forum/internal/models"
+)
+
+// gitHubEmail is one entry of GitHub's /user/emails
+type gitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// completeGitHubEmail fills in the address of a GitHub identity. GitHub only shows verified
+// addresses on profiles; users who keep theirs private are asked for through /user/emails,
+// which the user:email scope grants.
+func (p *Provider) completeGitHubEmail(userInfoURL, accessToken string, identity *models.OAuthIdentity) error {
+	if identity.Email != "" {
+		identity.EmailVerified = true
+		return nil
+	}
+
+	var emails []gitHubEmail
+	if err := getJSON(strings.TrimSuffix(userInfoURL, "/")+"/emails", accessToken, &emails); err != nil {
+		return fmt.Errorf("emails request failed: %w", err)
+	}
+
+	// Find primary verified email
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email = email.Email
+			identity.EmailVerified = true
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// keySetTTL is how long fetched signing keys are used before the key set is fetched again
+const keySetTTL = time.Hour
+
+// keySetMinRefresh limits refetches for unknown key IDs, so forged tokens cannot hammer the issuer
+const keySetMinRefresh = time.Minute
+
+// keySet caches the issuer's signing keys (JWKS). Keys are fetched again when they are older
+// than keySetTTL or a token names an unknown key, which is how issuers roll their keys.
+type keySet struct {
+	url string
+
+	mutex     sync.Mutex
+	keys      []jsonWebKey
+	fetchedAt time.Time
+}
+
+// jsonWebKey is an RSA or EC public key from a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+
+	publicKey crypto.PublicKey
+}
+
+// newKeySet creates an empty key set for the JWKS URL
+func newKeySet(url string) *keySet {
+	return &keySet{url: url}
+}
+
+// lookup returns the signing keys of keyType ("RS" or "ES") that may have signed a token with kid.
+// Tokens without a kid are checked against every key of the right type.
+func (ks *keySet) lookup(kid, keyType string) ([]crypto.PublicKey, error) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	stale := time.Since(ks.fetchedAt) > keySetTTL
+	matches := ks.match(kid, keyType)
+	if stale || (len(matches) == 0 && time.Since(ks.fetchedAt) > keySetMinRefresh) {
+		if err := ks.fetch(); err != nil {
+			if len(ks.keys) == 0 {
+				return nil, err
+			}
+			log.Printf("Failed to refresh signing keys from %s, keeping previous keys: %v", ks.url, err)
+		}
+		matches = ks.match(kid, keyType)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no signing key %q found at %s", kid, ks.url)
+	}
+	return matches, nil
+}
+
+// match returns the cached keys usable for a token with kid
+func (ks *keySet) match(kid, keyType string) []crypto.PublicKey {
+	var matches []crypto.PublicKey
+	for _, key := range ks.keys {
+		if (kid == "" || key.Kid == kid) && jwkType(key.Kty) == keyType {
+			matches = append(matches, key.publicKey)
+		}
+	}
+	return matches
+}
+
+// fetch downloads the key set; keys that are not RSA or EC signing keys are skipped
+func (ks *keySet) fetch() error {
+	ks.fetchedAt = time.Now()
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ks.url, "", &doc); err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	var keys []jsonWebKey
+	for _, key := range doc.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.parse()
+		if err != nil {
+			continue
+		}
+		key.publicKey = publicKey
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return errors.New("key set contains no usable signing keys")
+	}
+
+	ks.keys = keys
+	return nil
+}
+
+// parse builds the public key from the JWK parameters
+func (k jsonWebKey) parse() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil || n.BitLen() < 2048 {
+			return nil, errors.New("invalid or too short RSA modulus")
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature checks a JWS signature made with alg (already checked by signatureAlgorithm)
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signingInput, signature []byte) error {
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		// ES256 uses P-256, ES384 P-384 and ES512 P-521; the signature is r || s
+		if curveForAlg(alg) != pub.Curve {
+			return errors.New("key curve does not match the algorithm")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid ECDSA signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported key")
+}
+
+// curveForAlg returns the curve an ES* algorithm is defined for
+func curveForAlg(alg string) elliptic.Curve {
+	switch alg {
+	case "ES256":
+		return elliptic.P256()
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// jwkType maps a JWK kty to the prefix of the JWS algorithms using it
+func jwkType(kty string) string {
+	switch kty {
+	case "RSA":
+		return "RS"
+	case "EC":
+		return "ES"
+	}
+	return ""
+}
+
+// decodeBigInt decodes a base64url big-endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
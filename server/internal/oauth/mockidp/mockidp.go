@@ -0,0 +1,329 @@
+// Package mockidp is a minimal OpenID Connect provider for trying out and testing the OAuth
+// login. It approves every authorization request as one fixed user, so no account or browser
+// interaction is needed.
+//
+// It serves discovery, JWKS, authorization, token and user info endpoints and signs ID tokens
+// with a key generated when it is created (RS256 or ES256). PKCE is checked when the client
+// sends a challenge, and required with Options.RequirePKCE.
+//
+// cmd/mockidp runs it as a local server; the oauth tests run it in-process with httptest.
+package mockidp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options describes the provider and the user it signs in
+type Options struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	Alg           string // ID token algorithm: RS256 or ES256
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+	Picture       string
+	RequirePKCE   bool // reject authorization requests without a PKCE S256 challenge
+}
+
+// authorization is an issued code waiting to be exchanged
+type authorization struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string // PKCE S256 challenge, empty when the client sent none
+	expiresAt     time.Time
+}
+
+// IdP is the provider state: signing key, issued codes and access tokens
+type IdP struct {
+	opts Options
+
+	mutex  sync.Mutex
+	signer crypto.Signer
+	keyID  string
+	codes  map[string]authorization
+	tokens map[string]bool
+}
+
+// New creates a provider with a freshly generated signing key
+func New(opts Options) (*IdP, error) {
+	if opts.Alg != "RS256" && opts.Alg != "ES256" {
+		return nil, fmt.Errorf("unsupported algorithm %q: use RS256 or ES256", opts.Alg)
+	}
+	opts.Issuer = strings.TrimSuffix(opts.Issuer, "/")
+
+	idp := &IdP{opts: opts, codes: make(map[string]authorization), tokens: make(map[string]bool)}
+	if err := idp.RotateKey(); err != nil {
+		return nil, err
+	}
+	return idp, nil
+}
+
+// RotateKey replaces the signing key and its key ID, like a provider rolling its keys.
+// Only the new key is published from then on.
+func (idp *IdP) RotateKey() error {
+	var signer crypto.Signer
+	var err error
+	if idp.opts.Alg == "ES256" {
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	idp.mutex.Lock()
+	defer idp.mutex.Unlock()
+	idp.signer = signer
+	idp.keyID = randomString(8)
+	return nil
+}
+
+// Handler serves the provider endpoints
+func (idp *IdP) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.serveDiscovery)
+	mux.HandleFunc("GET /jwks", idp.serveJWKS)
+	mux.HandleFunc("GET /authorize", idp.serveAuthorize)
+	mux.HandleFunc("POST /token", idp.serveToken)
+	mux.HandleFunc("GET /userinfo", idp.serveUserInfo)
+	return mux
+}
+
+// serveDiscovery answers <issuer>/.well-known/openid-configuration
+func (idp *IdP) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                idp.opts.Issuer,
+		"authorization_endpoint":                idp.opts.Issuer + "/authorize",
+		"token_endpoint":                        idp.opts.Issuer + "/token",
+		"userinfo_endpoint":                     idp.opts.Issuer + "/userinfo",
+		"jwks_uri":                              idp.opts.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{idp.opts.Alg},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// serveJWKS publishes the public signing key
+func (idp *IdP) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	idp.mutex.Lock()
+	signer, keyID := idp.signer, idp.keyID
+	idp.mutex.Unlock()
+
+	key := map[string]string{"kid": keyID, "use": "sig", "alg": idp.opts.Alg}
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		key["kty"] = "RSA"
+		key["n"] = encodeBigInt(pub.N, 0)
+		key["e"] = encodeBigInt(big.NewInt(int64(pub.E)), 0)
+	case *ecdsa.PublicKey:
+		key["kty"] = "EC"
+		key["crv"] = "P-256"
+		key["x"] = encodeBigInt(pub.X, 32)
+		key["y"] = encodeBigInt(pub.Y, 32)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []interface{}{key}})
+}
+
+// serveAuthorize approves the request right away and redirects back with a code
+func (idp *IdP) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" || query.Get("client_id") != idp.opts.ClientID {
+		http.Error(w, "unknown client_id or invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := target.Query()
+	params.Set("state", query.Get("state"))
+	challenge := query.Get("code_challenge")
+	if query.Get("response_type") != "code" {
+		params.Set("error", "unsupported_response_type")
+	} else if (challenge != "" && query.Get("code_challenge_method") != "S256") || (challenge == "" && idp.opts.RequirePKCE) {
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "PKCE with code_challenge_method S256 is required")
+	} else {
+		code := randomString(16)
+		idp.mutex.Lock()
+		idp.codes[code] = authorization{redirectURI: redirectURI, nonce: query.Get("nonce"), codeChallenge: challenge, expiresAt: time.Now().Add(time.Minute)}
+		idp.mutex.Unlock()
+		params.Set("code", code)
+	}
+	target.RawQuery = params.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// serveToken exchanges a code for an access token and a signed ID token
+func (idp *IdP) serveToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != idp.opts.ClientID || clientSecret != idp.opts.ClientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	idp.mutex.Lock()
+	auth, found := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code")) // codes are single use
+	idp.mutex.Unlock()
+	if !found || time.Now().After(auth.expiresAt) || auth.redirectURI != r.PostFormValue("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if auth.codeChallenge != "" {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+			tokenError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+	}
+
+	idToken, err := idp.Sign(idp.IDTokenClaims(auth.nonce))
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	accessToken := randomString(24)
+	idp.mutex.Lock()
+	idp.tokens[accessToken] = true
+	idp.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// serveUserInfo returns the user's claims for a valid access token
+func (idp *IdP) serveUserInfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	idp.mutex.Lock()
+	valid := idp.tokens[token]
+	idp.mutex.Unlock()
+	if !valid {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "invalid access token", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, idp.userClaims())
+}
+
+// IDTokenClaims returns the claims of an ID token for the user, valid for five minutes
+func (idp *IdP) IDTokenClaims(nonce string) map[string]interface{} {
+	now := time.Now()
+	claims := idp.userClaims()
+	claims["iss"] = idp.opts.Issuer
+	claims["aud"] = idp.opts.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(5 * time.Minute).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return claims
+}
+
+// userClaims are the standard claims of the signed-in user
+func (idp *IdP) userClaims() map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub":                idp.opts.Subject,
+		"email":              idp.opts.Email,
+		"email_verified":     idp.opts.EmailVerified,
+		"preferred_username": idp.opts.Username,
+		"name":               idp.opts.Name,
+	}
+	if idp.opts.Picture != "" {
+		claims["picture"] = idp.opts.Picture
+	}
+	return claims
+}
+
+// Sign returns claims as a compact JWS signed with the current key
+func (idp *IdP) Sign(claims map[string]interface{}) (string, error) {
+	idp.mutex.Lock()
+	signer, keyID := idp.signer, idp.keyID
+	idp.mutex.Unlock()
+
+	header, _ := json.Marshal(map[string]string{"alg": idp.opts.Alg, "typ": "JWT", "kid": keyID})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		// JWS uses the fixed-size r || s encoding instead of ASN.1
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// tokenError writes an OAuth error response
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": fmt.Sprintf("mock provider: %s", code)})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// encodeBigInt encodes a JWK integer, left-padded to size bytes when size is set
+func encodeBigInt(n *big.Int, size int) string {
+	data := n.Bytes()
+	if size > 0 {
+		data = n.FillBytes(make([]byte, size))
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// randomString returns n random bytes as base64url
+func randomString(n int) string {
+	data := make([]byte, n)
+	rand.Read(data)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
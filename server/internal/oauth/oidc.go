@@ -0,0 +1,234 @@
+package oauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+)
+
+// discoveryTTL is how long a discovery document is used before it is fetched again
+const discoveryTTL = 24 * time.Hour
+
+// clockSkew is the tolerance for exp, iat and nbf of ID tokens
+const clockSkew = 2 * time.Minute
+
+// discoveryDocument is the part of <issuer>/.well-known/openid-configuration the flow needs
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// resolveEndpoints returns the provider's endpoints. OpenID Connect providers are discovered
+// on first use and refreshed after discoveryTTL; if a refresh fails the previous document is kept.
+func (p *Provider) resolveEndpoints() (endpoints, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.cfg.Type != config.OAuthTypeOIDC {
+		return endpoints{auth: p.cfg.AuthURL, token: p.cfg.TokenURL, userInfo: p.cfg.UserInfoURL}, nil
+	}
+	if !p.discoveredAt.IsZero() && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.endpoints, nil
+	}
+
+	doc, err := discover(p.cfg.Issuer)
+	if err != nil {
+		if !p.discoveredAt.IsZero() {
+			log.Printf("OpenID Connect discovery for %s failed, keeping previous endpoints: %v", p.cfg.Name, err)
+			return p.endpoints, nil
+		}
+		return endpoints{}, fmt.Errorf("OpenID Connect discovery for %s failed: %w", p.cfg.Name, err)
+	}
+
+	// Explicitly configured endpoints win over discovered ones
+	p.endpoints = endpoints{
+		issuer:   doc.Issuer,
+		auth:     defaultURL(p.cfg.AuthURL, doc.AuthorizationEndpoint),
+		token:    defaultURL(p.cfg.TokenURL, doc.TokenEndpoint),
+		userInfo: defaultURL(p.cfg.UserInfoURL, doc.UserInfoEndpoint),
+		jwks:     defaultURL(p.cfg.JWKSURL, doc.JWKSURI),
+	}
+	if p.endpoints.auth == "" || p.endpoints.token == "" || p.endpoints.jwks == "" {
+		return endpoints{}, fmt.Errorf("discovery document of %s lacks the authorization, token or JWKS endpoint", p.cfg.Name)
+	}
+	if p.keys == nil || p.keys.url != p.endpoints.jwks {
+		p.keys = newKeySet(p.endpoints.jwks)
+	}
+	p.discoveredAt = time.Now()
+
+	return p.endpoints, nil
+}
+
+// discover fetches and checks the issuer's discovery document
+func discover(issuer string) (*discoveryDocument, error) {
+	var doc discoveryDocument
+	if err := getJSON(issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, err
+	}
+
+	// The document must be about the configured issuer (OpenID Connect Discovery 4.3)
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, expected %q", doc.Issuer, issuer)
+	}
+	return &doc, nil
+}
+
+// verifyIDToken checks the ID token's signature against the issuer's keys, then its issuer,
+// audience, lifetime and nonce, and returns its claims
+func (p *Provider) verifyIDToken(raw, nonce string) (map[string]interface{}, error) {
+	p.mutex.Lock()
+	ep, keys := p.endpoints, p.keys
+	p.mutex.Unlock()
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed ID token signature")
+	}
+
+	// Only asymmetric algorithms are accepted; "none" and HMAC would let anyone mint tokens
+	hash, keyType, err := signatureAlgorithm(header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := keys.lookup(header.Kid, keyType)
+	if err != nil {
+		return nil, err
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range candidates {
+		if verifySignature(header.Alg, hash, key, signingInput, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid ID token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+
+	if iss := claimString(claims, "iss"); iss != ep.issuer {
+		return nil, fmt.Errorf("ID token issued by %q, expected %q", iss, ep.issuer)
+	}
+	audiences := claimAudiences(claims)
+	if !slices.Contains(audiences, p.cfg.ClientID) {
+		return nil, errors.New("ID token is not meant for this client")
+	}
+	if len(audiences) > 1 && claimString(claims, "azp") != p.cfg.ClientID {
+		return nil, errors.New("ID token authorized party does not match this client")
+	}
+
+	now := time.Now()
+	exp, ok := claimTime(claims, "exp")
+	if !ok || now.After(exp.Add(clockSkew)) {
+		return nil, errors.New("ID token expired")
+	}
+	if iat, ok := claimTime(claims, "iat"); ok && iat.After(now.Add(clockSkew)) {
+		return nil, errors.New("ID token issued in the future")
+	}
+	if nbf, ok := claimTime(claims, "nbf"); ok && nbf.After(now.Add(clockSkew)) {
+		return nil, errors.New("ID token not valid yet")
+	}
+	if claimString(claims, "sub") == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	// The nonce ties the token to this login, so a token from another flow cannot be replayed
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claimString(claims, "nonce")), []byte(nonce)) != 1 {
+		return nil, errors.New("ID token nonce does not match the login")
+	}
+
+	return claims, nil
+}
+
+// signatureAlgorithm maps a JWS algorithm to its hash and the JWK key type it needs
+func signatureAlgorithm(alg string) (crypto.Hash, string, error) {
+	switch alg {
+	case "RS256", "ES256":
+		return crypto.SHA256, alg[:2], nil
+	case "RS384", "ES384":
+		return crypto.SHA384, alg[:2], nil
+	case "RS512", "ES512":
+		return crypto.SHA512, alg[:2], nil
+	}
+	return 0, "", fmt.Errorf("unsupported ID token algorithm %q", alg)
+}
+
+// decodeSegment decodes a base64url JSON part of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// claimAudiences reads "aud", which is either a string or an array of strings
+func claimAudiences(claims map[string]interface{}) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		var audiences []string
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+		return audiences
+	}
+	return nil
+}
+
+// claimTime reads a NumericDate claim (seconds since the epoch)
+func claimTime(claims map[string]interface{}, name string) (time.Time, bool) {
+	number, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// defaultURL returns configured, or discovered when nothing was configured
+func defaultURL(configured, discovered string) string {
+	if configured != "" {
+		return configured
+	}
+	return discovered
+}
@@ -0,0 +1,318 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/oauth/mockidp"
+)
+
+const (
+	testClientID     = "forum"
+	testClientSecret = "secret"
+	testRedirectURI  = "http://forum.example/api/auth/mock/callback"
+)
+
+// mockProvider runs the mock IdP in-process and returns it with a Provider configured for it
+// and a counter of the JWKS requests it answered
+func mockProvider(t *testing.T, alg string) (*mockidp.IdP, *Provider, *atomic.Int32) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+
+	idp, err := mockidp.New(mockidp.Options{
+		Issuer:        issuer,
+		ClientID:      testClientID,
+		ClientSecret:  testClientSecret,
+		Alg:           alg,
+		Subject:       "mock-user-1",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Username:      "alice",
+		Name:          "Alice Liddell",
+		RequirePKCE:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwksRequests := &atomic.Int32{}
+	handler := idp.Handler()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks" {
+			jwksRequests.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	server.Start()
+	t.Cleanup(server.Close)
+
+	registry := NewRegistry([]config.OAuthProviderConfig{{
+		Name:          "mock",
+		Type:          config.OAuthTypeOIDC,
+		ClientID:      testClientID,
+		ClientSecret:  testClientSecret,
+		RedirectURI:   testRedirectURI,
+		Scopes:        []string{"openid", "email", "profile"},
+		Issuer:        issuer,
+		IDClaim:       "sub",
+		EmailClaim:    "email",
+		UsernameClaim: "preferred_username",
+	}})
+	provider, err := registry.Get("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idp, provider, jwksRequests
+}
+
+// authorize follows the provider's authorization URL and returns the code from the redirect
+func authorize(t *testing.T, provider *Provider, state *models.OAuthFlowState) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), testRedirectURI) {
+		t.Fatalf("authorization redirected to %q", resp.Header.Get("Location"))
+	}
+	if location.Query().Get("state") != state.StateID {
+		t.Fatalf("state came back as %q", location.Query().Get("state"))
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in %s", location)
+	}
+	return code
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			_, provider, _ := mockProvider(t, alg)
+			state := &models.OAuthFlowState{StateID: "state-1", CodeVerifier: "verifier-0123456789-0123456789-0123456789", Nonce: "nonce-1"}
+
+			code := authorize(t, provider, state)
+			if _, err := provider.Exchange(code, "another-verifier"); err == nil {
+				t.Fatal("code exchanged with the wrong PKCE verifier")
+			}
+
+			code = authorize(t, provider, state)
+			token, err := provider.Exchange(code, state.CodeVerifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := provider.Identity(token, state.Nonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := models.OAuthIdentity{
+				Provider:       "mock",
+				ProviderUserID: "mock-user-1",
+				Email:          "alice@example.com",
+				EmailVerified:  true,
+				Username:       "alice",
+				FirstName:      "Alice",
+				LastName:       "Liddell",
+			}
+			if *identity != want {
+				t.Errorf("Identity() = %+v, want %+v", *identity, want)
+			}
+
+			if _, err := provider.Identity(token, "nonce-of-another-login"); err == nil {
+				t.Error("ID token accepted for another login's nonce")
+			}
+		})
+	}
+}
+
+// encodeSegment encodes a JWT header or claims segment
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	idp, provider, _ := mockProvider(t, "RS256")
+	if _, err := provider.resolveEndpoints(); err != nil {
+		t.Fatal(err)
+	}
+	const nonce = "nonce-1"
+
+	signed := func(change func(claims map[string]interface{})) string {
+		claims := idp.IDTokenClaims(nonce)
+		change(claims)
+		token, err := idp.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	unsigned := func(alg string) string {
+		header := encodeSegment(t, map[string]string{"alg": alg, "typ": "JWT"})
+		return header + "." + encodeSegment(t, idp.IDTokenClaims(nonce))
+	}
+
+	// HS256 with the client secret as key: the classic confusion attack on verifiers that
+	// accept whatever algorithm the token names
+	hs256 := unsigned("HS256")
+	mac := hmac.New(sha256.New, []byte(testClientSecret))
+	mac.Write([]byte(hs256))
+	hs256 += "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	valid := signed(func(map[string]interface{}) {})
+	parts := strings.Split(valid, ".")
+	tamperedClaims := idp.IDTokenClaims(nonce)
+	tamperedClaims["sub"] = "someone-else"
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{"alg none", unsigned("none") + ".", nonce, "unsupported ID token algorithm"},
+		{"alg none with signature", unsigned("none") + "." + parts[2], nonce, "unsupported ID token algorithm"},
+		{"HS256 with client secret", hs256, nonce, "unsupported ID token algorithm"},
+		{"tampered claims", parts[0] + "." + encodeSegment(t, tamperedClaims) + "." + parts[2], nonce, "invalid ID token signature"},
+		{"malformed", "not-a-token", nonce, "malformed"},
+		{"wrong issuer", signed(func(c map[string]interface{}) { c["iss"] = "https://evil.example" }), nonce, "issued by"},
+		{"wrong audience", signed(func(c map[string]interface{}) { c["aud"] = "another-client" }), nonce, "not meant for this client"},
+		{
+			"several audiences without azp",
+			signed(func(c map[string]interface{}) { c["aud"] = []string{testClientID, "another-client"} }),
+			nonce, "authorized party",
+		},
+		{
+			"several audiences with wrong azp",
+			signed(func(c map[string]interface{}) {
+				c["aud"] = []string{testClientID, "another-client"}
+				c["azp"] = "another-client"
+			}),
+			nonce, "authorized party",
+		},
+		{
+			"expired",
+			signed(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix() }),
+			nonce, "expired",
+		},
+		{"no expiry", signed(func(c map[string]interface{}) { delete(c, "exp") }), nonce, "expired"},
+		{
+			"issued in the future",
+			signed(func(c map[string]interface{}) { c["iat"] = time.Now().Add(clockSkew + time.Minute).Unix() }),
+			nonce, "issued in the future",
+		},
+		{
+			"not valid yet",
+			signed(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(clockSkew + time.Minute).Unix() }),
+			nonce, "not valid yet",
+		},
+		{"no subject", signed(func(c map[string]interface{}) { delete(c, "sub") }), nonce, "no subject"},
+		{"nonce mismatch", valid, "nonce-2", "nonce does not match"},
+		{"no nonce in token", signed(func(c map[string]interface{}) { delete(c, "nonce") }), nonce, "nonce does not match"},
+		{"no nonce expected", valid, "", "nonce does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.verifyIDToken(tt.token, tt.nonce)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifyIDToken() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The control: the same provider accepts an untouched token, also within the clock skew
+	for name, token := range map[string]string{
+		"valid": valid,
+		"several audiences with azp": signed(func(c map[string]interface{}) {
+			c["aud"] = []string{testClientID, "another-client"}
+			c["azp"] = testClientID
+		}),
+		"expired within skew": signed(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-clockSkew / 2).Unix() }),
+	} {
+		if _, err := provider.verifyIDToken(token, nonce); err != nil {
+			t.Errorf("%s token rejected: %v", name, err)
+		}
+	}
+}
+
+func TestVerifyIDTokenRefetchesKeysForUnknownKeyID(t *testing.T) {
+	idp, provider, jwksRequests := mockProvider(t, "RS256")
+	if _, err := provider.resolveEndpoints(); err != nil {
+		t.Fatal(err)
+	}
+	const nonce = "nonce-1"
+
+	sign := func() string {
+		token, err := idp.Sign(idp.IDTokenClaims(nonce))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	if _, err := provider.verifyIDToken(sign(), nonce); err != nil {
+		t.Fatal(err)
+	}
+	if n := jwksRequests.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times for the first token, want 1", n)
+	}
+
+	// The provider rolls its key. Right after a fetch, an unknown key ID does not trigger another
+	// one, so forged tokens cannot make the server hammer the issuer.
+	if err := idp.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	rotated := sign()
+	if _, err := provider.verifyIDToken(rotated, nonce); err == nil || !strings.Contains(err.Error(), "no signing key") {
+		t.Errorf("token with an unknown key ID within keySetMinRefresh: error = %v", err)
+	}
+	if n := jwksRequests.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times within keySetMinRefresh, want 1", n)
+	}
+
+	// Once keySetMinRefresh has passed, the unknown key ID makes the key set be fetched again
+	provider.keys.mutex.Lock()
+	provider.keys.fetchedAt = time.Now().Add(-keySetMinRefresh - time.Second)
+	provider.keys.mutex.Unlock()
+
+	if _, err := provider.verifyIDToken(rotated, nonce); err != nil {
+		t.Errorf("token signed with the rotated key: %v", err)
+	}
+	if n := jwksRequests.Load(); n != 2 {
+		t.Errorf("JWKS fetched %d times after the key rotation, want 2", n)
+	}
+
+	// Known key IDs are served from the cache
+	if _, err := provider.verifyIDToken(sign(), nonce); err != nil {
+		t.Fatal(err)
+	}
+	if n := jwksRequests.Load(); n != 2 {
+		t.Errorf("JWKS fetched %d times for a known key ID, want 2", n)
+	}
+}
@@ -0,0 +1,340 @@
+// Package oauth signs users in with external OAuth 2.0 and OpenID Connect providers. Providers
+// come from config.Config.OAuthProviders; OpenID Connect providers are set up from the issuer's
+// discovery document and their ID tokens are verified against the issuer's published keys.
+package oauth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+)
+
+// maxResponseSize limits what is read from a provider's token and user info endpoints
+const maxResponseSize = 1 << 20
+
+// httpClient is shared by all provider requests
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	order     []string // as listed in OAUTH_PROVIDERS
+}
+
+// NewRegistry creates a provider for every configuration. Nothing is fetched yet: OpenID Connect
+// providers are discovered on first use, so a provider that is down does not stop the server.
+func NewRegistry(configs []config.OAuthProviderConfig) *Registry {
+	registry := &Registry{providers: make(map[string]*Provider)}
+	for _, cfg := range configs {
+		registry.providers[cfg.Name] = &Provider{cfg: cfg}
+		registry.order = append(registry.order, cfg.Name)
+	}
+	return registry
+}
+
+// Get returns the provider called name
+func (r *Registry) Get(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+	return provider, nil
+}
+
+// List describes the providers for the login page, in configuration order
+func (r *Registry) List() []models.OAuthProviderInfo {
+	list := []models.OAuthProviderInfo{}
+	for _, name := range r.order {
+		p := r.providers[name]
+		list = append(list, models.OAuthProviderInfo{
+			Name:        p.cfg.Name,
+			DisplayName: p.cfg.DisplayName,
+			LoginURL:    strings.TrimSuffix(config.Config.BackendBaseURL, "/") + "/api/auth/" + p.cfg.Name + "/login",
+		})
+	}
+	return list
+}
+
+// Provider runs the authorization code flow against one configured provider
+type Provider struct {
+	cfg config.OAuthProviderConfig
+
+	mutex        sync.Mutex
+	endpoints    endpoints
+	discoveredAt time.Time // zero until the discovery document was loaded (OpenID Connect only)
+	keys         *keySet
+}
+
+// endpoints are the URLs the flow talks to, from the configuration or the discovery document
+type endpoints struct {
+	issuer   string
+	auth     string
+	token    string
+	userInfo string
+	jwks     string
+}
+
+// Token is the answer of the token endpoint
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"` // OpenID Connect only
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Name returns the configured provider name
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to. It carries the state, the PKCE
+// S256 challenge of the state's code verifier and, for OpenID Connect, the nonce.
+func (p *Provider) AuthCodeURL(state *models.OAuthFlowState) (string, error) {
+	ep, err := p.resolveEndpoints()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(ep.auth)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	params := authURL.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURI)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state.StateID)
+	params.Set("code_challenge", codeChallenge(state.CodeVerifier))
+	params.Set("code_challenge_method", "S256")
+	if p.cfg.Type == config.OAuthTypeOIDC {
+		params.Set("nonce", state.Nonce)
+	}
+	authURL.RawQuery = params.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange trades the authorization code from the callback for tokens, proving with the PKCE
+// code verifier that the code was requested by this server
+func (p *Provider) Exchange(code, codeVerifier string) (*Token, error) {
+	ep, err := p.resolveEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", p.cfg.RedirectURI)
+	data.Set("code_verifier", codeVerifier)
+	data.Set("client_id", p.cfg.ClientID)
+	data.Set("client_secret", p.cfg.ClientSecret)
+
+	req, err := http.NewRequest(http.MethodPost, ep.token, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token Token
+	status, err := doJSON(req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("%s token error: %s - %s", p.cfg.Name, token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("no access token received from %s (status %d)", p.cfg.Name, status)
+	}
+	if p.cfg.Type == config.OAuthTypeOIDC && token.IDToken == "" {
+		return nil, fmt.Errorf("no ID token received from %s", p.cfg.Name)
+	}
+
+	return &token, nil
+}
+
+// Identity returns the account behind the tokens. OpenID Connect identities come from the
+// verified ID token (completed from the user info endpoint when claims are missing); other
+// providers are asked through their user info endpoint. The ID token must carry the nonce
+// that was sent with the authorization request.
+func (p *Provider) Identity(token *Token, nonce string) (*models.OAuthIdentity, error) {
+	ep, err := p.resolveEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if p.cfg.Type == config.OAuthTypeOIDC {
+		if claims, err = p.verifyIDToken(token.IDToken, nonce); err != nil {
+			return nil, err
+		}
+		if ep.userInfo != "" && (claimString(claims, p.cfg.EmailClaim) == "" || claimString(claims, p.cfg.UsernameClaim) == "") {
+			if err := p.mergeUserInfo(ep.userInfo, token.AccessToken, claims); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := getJSON(ep.userInfo, token.AccessToken, &claims); err != nil {
+			return nil, fmt.Errorf("user info request failed: %w", err)
+		}
+	}
+
+	identity := &models.OAuthIdentity{
+		Provider:       p.cfg.Name,
+		ProviderUserID: claimString(claims, p.cfg.IDClaim),
+		Email:          claimString(claims, p.cfg.EmailClaim),
+		AvatarURL:      claimString(claims, p.cfg.AvatarClaim),
+	}
+	if identity.ProviderUserID == "" {
+		return nil, fmt.Errorf("%s did not return the %q claim", p.cfg.Name, p.cfg.IDClaim)
+	}
+
+	if verified, ok := claimBool(claims, "email_verified"); ok {
+		identity.EmailVerified = verified
+	} else if p.cfg.Type == config.OAuthTypeOAuth2 {
+		identity.EmailVerified = p.cfg.TrustEmail
+	}
+
+	if p.cfg.Type == config.OAuthTypeGitHub {
+		if err := p.completeGitHubEmail(ep.userInfo, token.AccessToken, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	identity.Username = firstClaim(claims, p.cfg.UsernameClaim, "preferred_username", "nickname", "login", "name")
+	if identity.Username == "" && identity.Email != "" {
+		identity.Username = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	// Standard profile claims; providers that only have a full name get it split once
+	identity.FirstName = claimString(claims, "given_name")
+	identity.LastName = claimString(claims, "family_name")
+	if identity.FirstName == "" && identity.LastName == "" {
+		fullName := strings.Fields(claimString(claims, "name"))
+		if len(fullName) > 0 {
+			identity.FirstName = fullName[0]
+			identity.LastName = strings.Join(fullName[1:], " ")
+		}
+	}
+
+	return identity, nil
+}
+
+// codeChallenge derives the PKCE S256 challenge from a code verifier (RFC 7636 4.2)
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// mergeUserInfo adds the user info claims that the ID token did not carry
+func (p *Provider) mergeUserInfo(userInfoURL, accessToken string, claims map[string]interface{}) error {
+	var info map[string]interface{}
+	if err := getJSON(userInfoURL, accessToken, &info); err != nil {
+		return fmt.Errorf("user info request failed: %w", err)
+	}
+
+	// The user info must describe the same user as the ID token (OpenID Connect Core 5.3.2)
+	if claimString(info, "sub") != claimString(claims, "sub") {
+		return errors.New("user info subject does not match the ID token")
+	}
+
+	for key, value := range info {
+		if _, ok := claims[key]; !ok {
+			claims[key] = value
+		}
+	}
+	return nil
+}
+
+// getJSON fetches a provider API with the access token and decodes the JSON answer
+func getJSON(rawURL, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	status, err := doJSON(req, v)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, status)
+	}
+	return nil
+}
+
+// doJSON sends req and decodes the body into v, keeping numbers exact (IDs can exceed float64 precision).
+// A body that is not JSON is only an error for successful responses.
+func doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// claimString returns a string or numeric claim as a string
+func claimString(claims map[string]interface{}, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+	return ""
+}
+
+// claimBool reads a boolean claim; some providers send "true" as a string
+func claimBool(claims map[string]interface{}, name string) (bool, bool) {
+	switch value := claims[name].(type) {
+	case bool:
+		return value, true
+	case string:
+		return value == "true", true
+	}
+	return false, false
+}
+
+// firstClaim returns the first non-empty string claim
+func firstClaim(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if value := claimString(claims, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
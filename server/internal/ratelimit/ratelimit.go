@@ -0,0 +1,117 @@
+// Package ratelimit implements token buckets: every key may make Policy.Requests requests at
+// once and regains that capacity evenly over Policy.Window, using constant memory per key.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Policy allows Requests requests per Window; Requests <= 0 disables the limit
+type Policy struct {
+	Requests int
+	Window   time.Duration
+}
+
+// String formats the policy for the RateLimit-Policy header ("100;w=60")
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Requests, int(math.Ceil(p.Window.Seconds())))
+}
+
+// Result is the outcome of Limiter.Allow
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // whole requests left in the bucket
+	RetryAfter time.Duration // until the next request is allowed, 0 if Allowed
+	ResetAfter time.Duration // until the bucket is full again
+}
+
+// bucket is the state of one key
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps one token bucket per key for a policy
+type Limiter struct {
+	policy    Policy
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+	now       func() time.Time // the clock, replaced in tests
+}
+
+// NewLimiter creates a Limiter for policy
+func NewLimiter(policy Policy) *Limiter {
+	return &Limiter{
+		policy:    policy,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Policy returns the policy of the limiter
+func (l *Limiter) Policy() Policy {
+	return l.policy
+}
+
+// Allow takes a token from the bucket of key if one is available
+func (l *Limiter) Allow(key string) Result {
+	if l.policy.Requests <= 0 || l.policy.Window <= 0 {
+		return Result{Allowed: true, Limit: l.policy.Requests}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	capacity := float64(l.policy.Requests)
+	rate := capacity / l.policy.Window.Seconds() // tokens per second
+
+	l.sweep(now, capacity, rate)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+
+	// Refill for the time since the last request
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Limit: l.policy.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / rate)
+	return result
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket starts out full anyway.
+// It runs at most once per window so Allow stays O(1) on average.
+func (l *Limiter) sweep(now time.Time, capacity, rate float64) {
+	if now.Sub(l.lastSweep) < l.policy.Window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*rate >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// secondsToDuration converts fractional seconds to a Duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
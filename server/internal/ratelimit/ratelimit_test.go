@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when the test advances it
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestLimiter creates a Limiter that reads the time from clock
+func newTestLimiter(policy Policy, clock *fakeClock) *Limiter {
+	l := NewLimiter(policy)
+	l.now = clock.Now
+	l.lastSweep = clock.Now()
+	return l
+}
+
+func TestAllow(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	// 3 requests at once, one token back per second
+	l := newTestLimiter(Policy{Requests: 3, Window: 3 * time.Second}, clock)
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    Result
+	}{
+		{"first request", 0, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: time.Second}},
+		{"second request", 0, Result{Allowed: true, Limit: 3, Remaining: 1, ResetAfter: 2 * time.Second}},
+		{"bucket emptied", 0, Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 3 * time.Second}},
+		{"empty bucket", 0, Result{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: time.Second, ResetAfter: 3 * time.Second}},
+		{
+			"half a token refilled", 500 * time.Millisecond,
+			Result{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: 500 * time.Millisecond, ResetAfter: 2500 * time.Millisecond},
+		},
+		{"one token refilled", 500 * time.Millisecond, Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 3 * time.Second}},
+		{"refill clamped to capacity", 10 * time.Second, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: time.Second}},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		if got := l.Allow("client"); got != step.want {
+			t.Errorf("%s: Allow() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAllowKeepsKeysApart(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newTestLimiter(Policy{Requests: 1, Window: time.Minute}, clock)
+
+	if !l.Allow("a").Allowed {
+		t.Fatal("first request of a rejected")
+	}
+	if l.Allow("a").Allowed {
+		t.Error("second request of a allowed")
+	}
+	if !l.Allow("b").Allowed {
+		t.Error("b was limited by the requests of a")
+	}
+}
+
+func TestAllowDisabledPolicy(t *testing.T) {
+	for _, policy := range []Policy{{Requests: 0, Window: time.Minute}, {Requests: 5, Window: 0}} {
+		l := newTestLimiter(policy, &fakeClock{})
+		for i := 0; i < 10; i++ {
+			if result := l.Allow("client"); !result.Allowed || result.Limit != policy.Requests {
+				t.Fatalf("policy %+v: request %d = %+v, want allowed without a limit", policy, i+1, result)
+			}
+		}
+		if len(l.buckets) != 0 {
+			t.Errorf("policy %+v kept %d buckets", policy, len(l.buckets))
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	// 2 requests at once, one token back every 30 seconds
+	l := newTestLimiter(Policy{Requests: 2, Window: time.Minute}, clock)
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+
+	clock.Advance(30 * time.Second)
+	l.Allow("b")
+	if got := bucketKeys(l); len(got) != 2 {
+		t.Fatalf("buckets swept before a window passed: %q", got)
+	}
+
+	// A window after the last sweep, a is full again and dropped, b is not
+	clock.Advance(31 * time.Second)
+	l.Allow("b")
+	if got := bucketKeys(l); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("buckets after the sweep = %q, want [b]", got)
+	}
+
+	// A dropped bucket starts out full, as if it had been kept
+	if result := l.Allow("a"); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Allow(a) after the sweep = %+v, want a full bucket", result)
+	}
+}
+
+// bucketKeys returns the keys the limiter keeps a bucket for, sorted
+func bucketKeys(l *Limiter) []string {
+	keys := make([]string, 0, len(l.buckets))
+	for key := range l.buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestPolicyString(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   string
+	}{
+		{Policy{Requests: 100, Window: time.Minute}, "100;w=60"},
+		{Policy{Requests: 5, Window: 1500 * time.Millisecond}, "5;w=2"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
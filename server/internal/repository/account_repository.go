@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// AccountRepository collects and removes all personal data of a user
+type AccountRepository struct {
+	DB *sql.DB
+}
+
+// NewAccountRepository creates a new AccountRepository
+func NewAccountRepository(db *sql.DB) *AccountRepository {
+	return &AccountRepository{DB: db}
+}
+
+// GetDataExport gathers everything stored about a user.
+// Image URLs of posts and of messages the user sent point to files the user uploaded.
+func (ar *AccountRepository) GetDataExport(userID string) (*models.DataExport, error) {
+	export := &models.DataExport{}
+
+	if err := ar.exportProfile(userID, &export.Profile); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if export.Posts, err = ar.exportPosts(userID); err != nil {
+		return nil, err
+	}
+	if export.Comments, err = ar.exportComments(userID); err != nil {
+		return nil, err
+	}
+	if export.Reactions, err = ar.exportReactions(userID); err != nil {
+		return nil, err
+	}
+	if export.Notifications, err = ar.exportNotifications(userID); err != nil {
+		return nil, err
+	}
+	if export.Messages, err = ar.exportMessages(userID); err != nil {
+		return nil, err
+	}
+	if export.Bookmarks, err = ar.exportBookmarks(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (ar *AccountRepository) exportProfile(userID string, profile *models.ExportProfile) error {
+	err := ar.DB.QueryRow(`
+		SELECT user_id, username, age, gender, first_name, last_name, email, role, avatar_url, bio, location, created_at
+		FROM users WHERE user_id = ?`, userID,
+	).Scan(&profile.ID, &profile.Username, &profile.Age, &profile.Gender, &profile.FirstName,
+		&profile.LastName, &profile.Email, &profile.Role, &profile.AvatarURL, &profile.Bio, &profile.Location, &profile.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	rows, err := ar.DB.Query(`
+		SELECT provider, COALESCE(provider_username, ''), COALESCE(provider_email, ''), created_at
+		FROM oauth_user_accounts WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	profile.ConnectedAccounts = []models.ExportConnectedAccount{}
+	for rows.Next() {
+		var account models.ExportConnectedAccount
+		if err := rows.Scan(&account.Provider, &account.ProviderUsername, &account.ProviderEmail, &account.CreatedAt); err != nil {
+			return err
+		}
+		profile.ConnectedAccounts = append(profile.ConnectedAccounts, account)
+	}
+	return rows.Err()
+}
+
+func (ar *AccountRepository) exportPosts(userID string) ([]models.ExportPost, error) {
+	rows, err := ar.DB.Query(`
+		SELECT
+			p.post_id, COALESCE(p.title, ''), p.content, p.status, p.publish_at, p.created_at, p.updated_at, p.deleted_at,
+			COALESCE((SELECT GROUP_CONCAT(c.category_name, '|') FROM post_categories pc
+				JOIN categories c ON pc.category_id = c.category_id WHERE pc.post_id = p.post_id), ''),
+			COALESCE((SELECT GROUP_CONCAT(pi.image_url, '|') FROM post_images pi WHERE pi.post_id = p.post_id), '')
+		FROM posts p
+		WHERE p.user_id = ?
+		ORDER BY p.created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []models.ExportPost{}
+	for rows.Next() {
+		var post models.ExportPost
+		var publishAt, updatedAt, deletedAt sql.NullTime
+		var categories, images string
+		err := rows.Scan(&post.PostID, &post.Title, &post.Content, &post.Status, &publishAt, &post.CreatedAt,
+			&updatedAt, &deletedAt, &categories, &images)
+		if err != nil {
+			return nil, err
+		}
+		post.PublishAt = nullTimePtr(publishAt)
+		post.UpdatedAt = nullTimePtr(updatedAt)
+		post.DeletedAt = nullTimePtr(deletedAt)
+		post.Categories = splitConcat(categories)
+		post.Images = splitConcat(images)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (ar *AccountRepository) exportComments(userID string) ([]models.ExportComment, error) {
+	rows, err := ar.DB.Query(`
+		SELECT comment_id, post_id, content, created_at, updated_at, deleted_at
+		FROM comments WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.ExportComment{}
+	for rows.Next() {
+		var comment models.ExportComment
+		var updatedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&comment.CommentID, &comment.PostID, &comment.Content, &comment.CreatedAt, &updatedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+		comment.UpdatedAt = nullTimePtr(updatedAt)
+		comment.DeletedAt = nullTimePtr(deletedAt)
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (ar *AccountRepository) exportReactions(userID string) ([]models.ExportReaction, error) {
+	rows, err := ar.DB.Query(`
+		SELECT 'post', post_id, reaction_type, created_at FROM post_reactions WHERE user_id = ?
+		UNION ALL
+		SELECT 'comment', comment_id, reaction_type, created_at FROM comment_reactions WHERE user_id = ?
+		ORDER BY 4`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []models.ExportReaction{}
+	for rows.Next() {
+		var reaction models.ExportReaction
+		if err := rows.Scan(&reaction.TargetType, &reaction.TargetID, &reaction.ReactionType, &reaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+func (ar *AccountRepository) exportNotifications(userID string) ([]models.ExportNotification, error) {
+	rows, err := ar.DB.Query(`
+		SELECT notification_id, trigger_username, post_id, action, is_read, created_at
+		FROM notifications WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.ExportNotification{}
+	for rows.Next() {
+		var n models.ExportNotification
+		if err := rows.Scan(&n.NotificationID, &n.TriggerUsername, &n.PostID, &n.Action, &n.IsRead, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (ar *AccountRepository) exportMessages(userID string) ([]models.ExportMessage, error) {
+	rows, err := ar.DB.Query(`
+		SELECT
+			m.message_id,
+			CASE WHEN m.sender_id = ? THEN 'sent' ELSE 'received' END,
+			u.user_id, u.username, m.content, m.is_read, m.created_at,
+			COALESCE((SELECT GROUP_CONCAT(mi.image_url, '|') FROM message_images mi WHERE mi.message_id = m.message_id), '')
+		FROM messages m
+		JOIN users u ON u.user_id = CASE WHEN m.sender_id = ? THEN m.recipient_id ELSE m.sender_id END
+		WHERE m.sender_id = ? OR m.recipient_id = ?
+		ORDER BY m.created_at`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.ExportMessage{}
+	for rows.Next() {
+		var message models.ExportMessage
+		var images string
+		err := rows.Scan(&message.MessageID, &message.Direction, &message.OtherUserID, &message.OtherUsername,
+			&message.Content, &message.IsRead, &message.CreatedAt, &images)
+		if err != nil {
+			return nil, err
+		}
+		message.Images = splitConcat(images)
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (ar *AccountRepository) exportBookmarks(userID string) ([]models.ExportBookmark, error) {
+	rows, err := ar.DB.Query("SELECT post_id, created_at FROM bookmarks WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []models.ExportBookmark{}
+	for rows.Next() {
+		var bookmark models.ExportBookmark
+		if err := rows.Scan(&bookmark.PostID, &bookmark.CreatedAt); err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, rows.Err()
+}
+
+// DeleteAccount closes an account according to the deletion policy and returns the
+// uploaded images whose files must be removed from disk.
+//
+// Both policies remove sessions, OAuth links, notifications, bookmarks, private messages
+// (in both directions) and posts that never went public. "anonymize" keeps published posts,
+// comments and reactions under a scrubbed account; "delete" removes the user row and with it
+// everything the user created.
+func (ar *AccountRepository) DeleteAccount(userID, policy string) ([]models.PostImage, error) {
+	if policy != models.AccountDeletionAnonymize && policy != models.AccountDeletionDelete {
+		return nil, errors.New("invalid deletion policy")
+	}
+
+	return utils.ExecuteInTransactionWithResult(ar.DB, func(tx *sql.Tx) ([]models.PostImage, error) {
+		var username string
+		err := tx.QueryRow("SELECT username FROM users WHERE user_id = ? AND deleted_at IS NULL", userID).Scan(&username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("user not found")
+			}
+			return nil, err
+		}
+
+		// Posts that go away with the account: all of them, or only the ones that were never public
+		postFilter := "user_id = ?"
+		if policy == models.AccountDeletionAnonymize {
+			postFilter = "user_id = ? AND (status = 'draft' OR deleted_at IS NOT NULL)"
+		}
+
+		images, err := collectImages(tx, `
+			SELECT image_url FROM post_images WHERE post_id IN (SELECT post_id FROM posts WHERE `+postFilter+`)
+			UNION ALL
+			SELECT image_url FROM message_images WHERE message_id IN (
+				SELECT message_id FROM messages WHERE sender_id = ? OR recipient_id = ?
+			)
+			UNION ALL
+			SELECT avatar_url FROM users WHERE user_id = ? AND avatar_url != ''`, userID, userID, userID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		statements := []accountStatement{
+			{"DELETE FROM sessions WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM pending_logins WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM user_totp WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM recovery_codes WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM api_tokens WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM login_failures WHERE failure_key = ?", []interface{}{"user:" + userID}},
+			{"DELETE FROM oauth_user_accounts WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM notifications WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM bookmarks WHERE user_id = ?", []interface{}{userID}},
+			{"DELETE FROM mentions WHERE mentioned_user_id = ?", []interface{}{userID}},
+			{"DELETE FROM messages WHERE sender_id = ? OR recipient_id = ?", []interface{}{userID, userID}},
+			// Other users' notifications about posts that are going away
+			{"DELETE FROM notifications WHERE post_id IN (SELECT post_id FROM posts WHERE " + postFilter + ")", []interface{}{userID}},
+			{"DELETE FROM posts WHERE " + postFilter, []interface{}{userID}},
+		}
+
+		if policy == models.AccountDeletionDelete {
+			statements = append(statements,
+				accountStatement{"DELETE FROM notifications WHERE trigger_username = ?", []interface{}{username}},
+				accountStatement{"DELETE FROM users WHERE user_id = ?", []interface{}{userID}},
+			)
+		} else {
+			// The row stays so kept content has an author, but nothing identifies the person anymore.
+			// The empty password hash never matches, so the account cannot be logged into.
+			anonymousName := "deleted_" + strings.ReplaceAll(utils.GenerateUUIDToken(), "-", "")[:7]
+			statements = append(statements,
+				accountStatement{"DELETE FROM comments WHERE user_id = ? AND deleted_at IS NOT NULL", []interface{}{userID}},
+				accountStatement{"UPDATE notifications SET trigger_username = ? WHERE trigger_username = ?", []interface{}{anonymousName, username}},
+				accountStatement{`UPDATE users SET username = ?, email = ?, first_name = 'Deleted', last_name = 'User',
+					gender = 'Other', age = 13, password_hash = '', role = 'user', avatar_url = '', bio = '', location = '',
+					deleted_at = ?
+					WHERE user_id = ?`, []interface{}{anonymousName, anonymousName + "@deleted.invalid", time.Now(), userID}},
+			)
+		}
+
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+				return nil, err
+			}
+		}
+
+		return images, nil
+	})
+}
+
+// accountStatement is one step of an account deletion
+type accountStatement struct {
+	query string
+	args  []interface{}
+}
+
+// collectImages returns the image URLs selected by query as PostImage values for DeleteImageFilesFromDisk
+func collectImages(tx *sql.Tx, query string, args ...interface{}) ([]models.PostImage, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.PostImage
+	for rows.Next() {
+		var img models.PostImage
+		if err := rows.Scan(&img.ImageURL); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// nullTimePtr converts a nullable timestamp column to an optional time
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// splitConcat splits a GROUP_CONCAT(..., '|') result, returning an empty slice for no rows
+func splitConcat(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, "|")
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// apiTokenUsageInterval limits how often last_used_at is written for a busy token
+const apiTokenUsageInterval = time.Minute
+
+// APITokenRepository handles personal access tokens
+type APITokenRepository struct {
+	DB *sql.DB
+}
+
+// NewAPITokenRepository creates a new APITokenRepository
+func NewAPITokenRepository(db *sql.DB) *APITokenRepository {
+	return &APITokenRepository{DB: db}
+}
+
+// CreateToken issues a new token and returns it in plain text; only its hash is stored
+func (ar *APITokenRepository) CreateToken(userID, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error) {
+	var plain string
+	token, err := utils.ExecuteInTransactionWithResult(ar.DB, func(tx *sql.Tx) (*models.APIToken, error) {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE user_id = ?", userID).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count >= config.Config.APITokenMaxPerUser {
+			return nil, errors.New("too many tokens")
+		}
+
+		secret, err := utils.GenerateSecureToken()
+		if err != nil {
+			return nil, err
+		}
+		plain = models.APITokenPrefix + secret
+
+		now := time.Now()
+		token := &models.APIToken{
+			ID:        utils.GenerateUUIDToken(),
+			UserID:    userID,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO api_tokens (token_id, user_id, name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			token.ID, userID, name, utils.HashToken(plain), strings.Join(scopes, ","), token.CreatedAt, token.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return token, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// GetTokensByUser lists a user's tokens, newest first, including expired ones
+func (ar *APITokenRepository) GetTokensByUser(userID string) ([]models.APIToken, error) {
+	rows, err := ar.DB.Query(`
+		SELECT token_id, user_id, name, scopes, created_at, expires_at, last_used_at, last_used_ip
+		FROM api_tokens WHERE user_id = ?
+		ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteToken revokes one of the user's tokens
+func (ar *APITokenRepository) DeleteToken(userID, tokenID string) error {
+	result, err := ar.DB.Exec("DELETE FROM api_tokens WHERE token_id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// Authenticate looks up a token presented as "Authorization: Bearer" and records its use
+func (ar *APITokenRepository) Authenticate(plain, ipAddress string) (*models.APIToken, error) {
+	if !strings.HasPrefix(plain, models.APITokenPrefix) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	token, err := scanAPIToken(ar.DB.QueryRow(`
+		SELECT token_id, user_id, name, scopes, created_at, expires_at, last_used_at, last_used_ip
+		FROM api_tokens WHERE token_hash = ?`,
+		utils.HashToken(plain),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(token.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	// Usage is recorded at most once per apiTokenUsageInterval so scripts polling the API
+	// don't turn every request into a write
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenUsageInterval || token.LastUsedIP != ipAddress {
+		_, err = ar.DB.Exec("UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE token_id = ?", now, ipAddress, token.ID)
+		if err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+		token.LastUsedIP = ipAddress
+	}
+
+	return token, nil
+}
+
+// scanAPIToken reads a row selected as token_id, user_id, name, scopes, created_at,
+// expires_at, last_used_at, last_used_ip
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &token.ExpiresAt, &lastUsedAt, &token.LastUsedIP)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// AuditRepository stores security events such as account lockouts
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// Record adds an event to the audit log; userID may be empty when no account is involved
+func (ar *AuditRepository) Record(event, userID, ipAddress, details string) error {
+	var user sql.NullString
+	if userID != "" {
+		user = sql.NullString{String: userID, Valid: true}
+	}
+
+	_, err := ar.DB.Exec(
+		"INSERT INTO audit_log (event_id, event, user_id, ip_address, details, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		utils.GenerateUUIDToken(), event, user, ipAddress, details, time.Now(),
+	)
+	return err
+}
+
+// CountEvents returns the number of entries in the audit log
+func (ar *AuditRepository) CountEvents() (int, error) {
+	var count int
+	err := ar.DB.QueryRow("SELECT COUNT(*) FROM audit_log").Scan(&count)
+	return count, err
+}
+
+// GetEvents returns a page of the audit log, newest first
+func (ar *AuditRepository) GetEvents(limit, offset int) ([]*models.AuditEvent, error) {
+	rows, err := ar.DB.Query(
+		"SELECT event_id, event, user_id, ip_address, details, created_at FROM audit_log ORDER BY created_at DESC LIMIT ? OFFSET ?",
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var userID sql.NullString
+		if err := rows.Scan(&event.ID, &event.Event, &userID, &event.IPAddress, &event.Details, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.UserID = userID.String
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+	"real-time-forum/queries"
+)
+
+type BookmarkRepository struct {
+	db *sql.DB
+}
+
+// NewBookmarkRepository creates a new BookmarkRepository
+func NewBookmarkRepository(db *sql.DB) *BookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+// ToggleBookmark saves a post for the user, or removes it if it was already saved
+func (br *BookmarkRepository) ToggleBookmark(userID, postID string) (*models.BookmarkResult, error) {
+	return utils.ExecuteInTransactionWithResult(br.db, func(tx *sql.Tx) (*models.BookmarkResult, error) {
+		// Validate that the post exists
+		var exists int
+		err := tx.QueryRow("SELECT COUNT(*) FROM posts p WHERE p.post_id = ? AND p.deleted_at IS NULL AND "+queries.PublishedCondition, postID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			return nil, errors.New("post not found")
+		}
+
+		// Remove the bookmark if it exists
+		result, err := tx.Exec("DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?", userID, postID)
+		if err != nil {
+			return nil, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected > 0 {
+			return models.NewBookmarkResult(models.ActionBookmarkRemoved), nil
+		}
+
+		// Otherwise create it
+		_, err = tx.Exec(
+			"INSERT INTO bookmarks (user_id, post_id, created_at) VALUES (?, ?, ?)",
+			userID, postID, time.Now(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return models.NewBookmarkResult(models.ActionBookmarkAdded), nil
+	})
+}
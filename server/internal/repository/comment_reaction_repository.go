@@ -115,7 +115,7 @@ func (crr *CommentReactionRepository) changeCommentReaction(tx *sql.Tx, userID,
 // Helper method to validate that a comment exists
 func (crr *CommentReactionRepository) validateCommentExists(tx *sql.Tx, commentID string) error {
 	var exists int
-	err := tx.QueryRow("SELECT COUNT(*) FROM comments WHERE comment_id = ?", commentID).Scan(&exists)
+	err := tx.QueryRow("SELECT COUNT(*) FROM comments WHERE comment_id = ? AND deleted_at IS NULL", commentID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"time"
 
+	"real-time-forum/internal/markdown"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/utils"
+	"real-time-forum/queries"
 )
 
 type CommentRepository struct {
@@ -22,7 +24,7 @@ func (cor *CommentRepository) CreateComment(postID, userID, content string) (*mo
 	return utils.ExecuteInTransactionWithResult(cor.db, func(tx *sql.Tx) (*models.CreateCommentResponse, error) {
 		// Check if post exists
 		var exists int
-		err := tx.QueryRow("SELECT COUNT(*) FROM posts WHERE post_id = ?", postID).Scan(&exists)
+		err := tx.QueryRow("SELECT COUNT(*) FROM posts p WHERE p.post_id = ? AND p.deleted_at IS NULL AND "+queries.PublishedCondition, postID).Scan(&exists)
 		if err != nil {
 			return nil, err
 		}
@@ -56,8 +58,8 @@ func (cor *CommentRepository) CreateComment(postID, userID, content string) (*mo
 func (cor *CommentRepository) UpdateComment(commentID, userID, content string) error {
 	return utils.ExecuteInTransaction(cor.db, func(tx *sql.Tx) error {
 		// Check if comment exists and user owns it
-		var ownerID string
-		err := tx.QueryRow("SELECT user_id FROM comments WHERE comment_id = ?", commentID).Scan(&ownerID)
+		var ownerID, oldContent string
+		err := tx.QueryRow("SELECT user_id, content FROM comments WHERE comment_id = ? AND deleted_at IS NULL", commentID).Scan(&ownerID, &oldContent)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return errors.New("comment not found")
@@ -70,6 +72,13 @@ func (cor *CommentRepository) UpdateComment(commentID, userID, content string) e
 			return errors.New("unauthorized: you can only update your own comments")
 		}
 		now := time.Now()
+
+		// Keep the previous content in the edit history
+		if oldContent != content {
+			if err := saveCommentRevision(tx, commentID, oldContent, userID, now); err != nil {
+				return err
+			}
+		}
 		// Update comment content and set updated_at
 		_, err = tx.Exec(
 			"UPDATE comments SET content = ?, updated_at = ? WHERE comment_id = ?",
@@ -87,7 +96,7 @@ func (cor *CommentRepository) DeleteComment(commentID, userID string) error {
 	return utils.ExecuteInTransaction(cor.db, func(tx *sql.Tx) error {
 		// Check if comment exists and user owns it
 		var ownerID string
-		err := tx.QueryRow("SELECT user_id FROM comments WHERE comment_id = ?", commentID).Scan(&ownerID)
+		err := tx.QueryRow("SELECT user_id FROM comments WHERE comment_id = ? AND deleted_at IS NULL", commentID).Scan(&ownerID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return errors.New("comment not found")
@@ -100,8 +109,8 @@ func (cor *CommentRepository) DeleteComment(commentID, userID string) error {
 			return errors.New("unauthorized: you can only delete your own comments")
 		}
 
-		// Delete comment
-		_, err = tx.Exec("DELETE FROM comments WHERE comment_id = ?", commentID)
+		// Soft delete: the author can restore it until it gets purged
+		_, err = tx.Exec("UPDATE comments SET deleted_at = ? WHERE comment_id = ?", time.Now(), commentID)
 		if err != nil {
 			return err
 		}
@@ -110,11 +119,56 @@ func (cor *CommentRepository) DeleteComment(commentID, userID string) error {
 	})
 }
 
+// RestoreComment undoes a soft delete if the grace period has not expired yet
+func (cor *CommentRepository) RestoreComment(commentID, userID string, gracePeriod time.Duration) error {
+	return utils.ExecuteInTransaction(cor.db, func(tx *sql.Tx) error {
+		var ownerID string
+		var deletedAt sql.NullTime
+		err := tx.QueryRow("SELECT user_id, deleted_at FROM comments WHERE comment_id = ?", commentID).Scan(&ownerID, &deletedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("comment not found")
+			}
+			return err
+		}
+
+		// Check ownership
+		if ownerID != userID {
+			return errors.New("unauthorized: you can only restore your own comments")
+		}
+		if !deletedAt.Valid {
+			return errors.New("comment is not deleted")
+		}
+		if time.Since(deletedAt.Time) > gracePeriod {
+			return errors.New("restore period has expired")
+		}
+
+		_, err = tx.Exec("UPDATE comments SET deleted_at = NULL WHERE comment_id = ?", commentID)
+		return err
+	})
+}
+
+// PurgeDeletedComments permanently removes comments soft-deleted before the cutoff
+func (cor *CommentRepository) PurgeDeletedComments(cutoff time.Time) (int64, error) {
+	result, err := cor.db.Exec("DELETE FROM comments WHERE deleted_at IS NOT NULL AND julianday(deleted_at) <= julianday(?)", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // Get []*comments for PostID
-func (cor *CommentRepository) GetCommentsByPostID(postID string, limit, offset int, userID string, options utils.SortOptions) ([]*models.Comment, error) {
+// When a cursor is given the page starts right after it and offset is ignored
+func (cor *CommentRepository) GetCommentsByPostID(postID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Comment, string, error) {
 
 	// Build dynamic query with sorting using unified system - UNCHANGED
 	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypeComments)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypeComments)
+
+	// Keyset pagination replaces the offset
+	if cursor != nil {
+		offset = 0
+	}
 
 	query := `
 		SELECT 
@@ -122,6 +176,7 @@ func (cor *CommentRepository) GetCommentsByPostID(postID string, limit, offset i
 			c.post_id,
 			c.user_id,
 			u.username,
+			u.avatar_url,
 			c.content,
 			c.created_at,
 			c.updated_at,
@@ -143,13 +198,16 @@ func (cor *CommentRepository) GetCommentsByPostID(postID string, limit, offset i
 			GROUP BY comment_id
 		) dislike_counts ON c.comment_id = dislike_counts.comment_id
 		LEFT JOIN comment_reactions ur ON c.comment_id = ur.comment_id AND ur.user_id = ?
-		WHERE c.post_id = ?
+		WHERE c.post_id = ? AND c.deleted_at IS NULL` + keysetClause + `
 		` + orderClause + `
 		LIMIT ? OFFSET ?`
 
-	rows, err := cor.db.Query(query, userID, postID, limit, offset)
+	// Fetch one extra row to know whether another page exists
+	args := append([]interface{}{userID, postID}, keysetArgs...)
+	args = append(args, limit+1, offset)
+	rows, err := cor.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -157,24 +215,35 @@ func (cor *CommentRepository) GetCommentsByPostID(postID string, limit, offset i
 	for rows.Next() {
 		comment, err := cor.scanCommentRow(rows, userID)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		comments = append(comments, comment)
 	}
 
 	// Check for iteration errors
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return comments, nil
+	if len(comments) <= limit {
+		return comments, "", nil
+	}
+	comments = comments[:limit]
+
+	// Build the next-page token from the last comment
+	last := comments[len(comments)-1]
+	count := 0
+	if options.SortBy == "likes" {
+		count = last.LikeCount
+	}
+	return comments, utils.NewCursor(options.SortBy, count, last.CreatedAt, last.ID), nil
 }
 
 
 // COUNT comments methods
 func (cor *CommentRepository) GetCommentCountByPost(postID string) (int, error) {
 	var count int
-	err := cor.db.QueryRow("SELECT COUNT(*) FROM comments WHERE post_id = ?", postID).Scan(&count)
+	err := cor.db.QueryRow("SELECT COUNT(*) FROM comments WHERE post_id = ? AND deleted_at IS NULL", postID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -195,6 +264,7 @@ func (cor *CommentRepository) scanCommentRow(scanner interface{}, userID string)
 			&comment.PostID,
 			&comment.UserID,
 			&comment.Username,
+			&comment.AvatarURL,
 			&comment.Content,
 			&comment.CreatedAt,
 			&updatedAt,
@@ -208,6 +278,7 @@ func (cor *CommentRepository) scanCommentRow(scanner interface{}, userID string)
 			&comment.PostID,
 			&comment.UserID,
 			&comment.Username,
+			&comment.AvatarURL,
 			&comment.Content,
 			&comment.CreatedAt,
 			&updatedAt,
@@ -223,6 +294,9 @@ func (cor *CommentRepository) scanCommentRow(scanner interface{}, userID string)
 		return nil, err
 	}
 
+	comment.ContentHTML = markdown.Render(comment.Content)
+	comment.LinkPreviews = linkPreviewsFor(cor.db, comment.Content)
+
 	// Handle UpdatedAt
 	if updatedAt.Valid {
 		comment.UpdatedAt = &updatedAt.Time
@@ -253,6 +327,7 @@ func (cor *CommentRepository) GetCommentByID(commentID string, userID string) (*
 			c.post_id,
 			c.user_id,
 			u.username,
+			u.avatar_url,
 			c.content,
 			c.created_at,
 			c.updated_at,
@@ -274,7 +349,7 @@ func (cor *CommentRepository) GetCommentByID(commentID string, userID string) (*
 			GROUP BY comment_id
 		) dislike_counts ON c.comment_id = dislike_counts.comment_id
 		LEFT JOIN comment_reactions ur ON c.comment_id = ur.comment_id AND ur.user_id = ?
-		WHERE c.comment_id = ?`
+		WHERE c.comment_id = ? AND c.deleted_at IS NULL`
 
 	row := cor.db.QueryRow(query, userID, commentID)
 	comment, err := cor.scanCommentRow(row, userID)
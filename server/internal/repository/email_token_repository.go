@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// EmailTokenRepository handles password reset and email verification tokens
+type EmailTokenRepository struct {
+	DB *sql.DB
+}
+
+// NewEmailTokenRepository creates a new EmailTokenRepository
+func NewEmailTokenRepository(db *sql.DB) *EmailTokenRepository {
+	return &EmailTokenRepository{DB: db}
+}
+
+// CreateToken issues a new token and returns it in plain text; only its hash is stored.
+// Earlier unused tokens of the same purpose stop working, and each address may only
+// receive EmailTokenRateLimit tokens per purpose within EmailTokenRateWindow.
+func (er *EmailTokenRepository) CreateToken(userID, email, purpose string, ttl time.Duration) (string, error) {
+	email = strings.ToLower(email)
+
+	return utils.ExecuteInTransactionWithResult(er.DB, func(tx *sql.Tx) (string, error) {
+		now := time.Now()
+
+		var recent int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM email_tokens
+			WHERE email = ? AND purpose = ? AND julianday(created_at) > julianday(?)`,
+			email, purpose, now.Add(-config.Config.EmailTokenRateWindow),
+		).Scan(&recent)
+		if err != nil {
+			return "", err
+		}
+		if recent >= config.Config.EmailTokenRateLimit {
+			return "", errors.New("too many requests")
+		}
+
+		_, err = tx.Exec(
+			"UPDATE email_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL",
+			now, userID, purpose,
+		)
+		if err != nil {
+			return "", err
+		}
+
+		token, err := utils.GenerateSecureToken()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO email_tokens (token_hash, user_id, email, purpose, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			utils.HashToken(token), userID, email, purpose, now, now.Add(ttl),
+		)
+		if err != nil {
+			return "", err
+		}
+
+		return token, nil
+	})
+}
+
+// ConsumeToken validates a token and marks it used so it cannot be redeemed twice
+func (er *EmailTokenRepository) ConsumeToken(token, purpose string) (*models.EmailToken, error) {
+	return utils.ExecuteInTransactionWithResult(er.DB, func(tx *sql.Tx) (*models.EmailToken, error) {
+		tokenHash := utils.HashToken(token)
+
+		var emailToken models.EmailToken
+		var usedAt sql.NullTime
+		err := tx.QueryRow(`
+			SELECT user_id, email, purpose, created_at, expires_at, used_at
+			FROM email_tokens WHERE token_hash = ? AND purpose = ?`,
+			tokenHash, purpose,
+		).Scan(&emailToken.UserID, &emailToken.Email, &emailToken.Purpose, &emailToken.CreatedAt, &emailToken.ExpiresAt, &usedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("invalid or expired token")
+			}
+			return nil, err
+		}
+
+		if usedAt.Valid || time.Now().After(emailToken.ExpiresAt) {
+			return nil, errors.New("invalid or expired token")
+		}
+
+		_, err = tx.Exec("UPDATE email_tokens SET used_at = ? WHERE token_hash = ?", time.Now(), tokenHash)
+		if err != nil {
+			return nil, err
+		}
+
+		return &emailToken, nil
+	})
+}
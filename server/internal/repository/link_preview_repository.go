@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/unfurl"
+)
+
+type LinkPreviewRepository struct {
+	DB *sql.DB
+}
+
+// NewLinkPreviewRepository creates a new LinkPreviewRepository
+func NewLinkPreviewRepository(db *sql.DB) *LinkPreviewRepository {
+	return &LinkPreviewRepository{DB: db}
+}
+
+// GetPreviews returns the cached previews of urls, failed ones included, keyed by URL.
+// URLs that were never fetched are missing from the map.
+func (lpr *LinkPreviewRepository) GetPreviews(urls []string) (map[string]models.LinkPreview, error) {
+	return getLinkPreviews(lpr.DB, urls)
+}
+
+// SavePreview stores the result of fetching a URL, replacing an older one
+func (lpr *LinkPreviewRepository) SavePreview(preview *models.LinkPreview) error {
+	_, err := lpr.DB.Exec(`
+		INSERT INTO link_previews (url, status, title, description, image_url, site_name, error, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			status = excluded.status,
+			title = excluded.title,
+			description = excluded.description,
+			image_url = excluded.image_url,
+			site_name = excluded.site_name,
+			error = excluded.error,
+			fetched_at = excluded.fetched_at`,
+		preview.URL, preview.Status, preview.Title, preview.Description, preview.ImageURL, preview.SiteName, preview.Error, preview.FetchedAt,
+	)
+	return err
+}
+
+// linkPreviewsFor returns the previews found so far for the links in text, in the order they are
+// written. Links still waiting for the unfurler or that failed are left out; errors too, like
+// missing images, so a post or message still loads without its previews.
+func linkPreviewsFor(db *sql.DB, text string) []models.LinkPreview {
+	previews := []models.LinkPreview{}
+
+	links := unfurl.LinksIn(text)
+	if len(links) == 0 {
+		return previews
+	}
+
+	cached, err := getLinkPreviews(db, links)
+	if err != nil {
+		return previews
+	}
+	for _, link := range links {
+		if preview, ok := cached[link]; ok && preview.Status == models.LinkPreviewOK {
+			previews = append(previews, preview)
+		}
+	}
+	return previews
+}
+
+func getLinkPreviews(db *sql.DB, urls []string) (map[string]models.LinkPreview, error) {
+	previews := map[string]models.LinkPreview{}
+	if len(urls) == 0 {
+		return previews, nil
+	}
+
+	args := make([]interface{}, len(urls))
+	for i, url := range urls {
+		args[i] = url
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(urls)), ",")
+
+	rows, err := db.Query(
+		"SELECT url, status, title, description, image_url, site_name, error, fetched_at FROM link_previews WHERE url IN ("+placeholders+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var preview models.LinkPreview
+		err := rows.Scan(&preview.URL, &preview.Status, &preview.Title, &preview.Description, &preview.ImageURL, &preview.SiteName, &preview.Error, &preview.FetchedAt)
+		if err != nil {
+			return nil, err
+		}
+		previews[preview.URL] = preview
+	}
+	return previews, rows.Err()
+}
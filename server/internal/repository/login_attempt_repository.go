@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// LoginAttemptRepository tracks failed logins per account and per IP address. Accounts get an
+// exponential backoff and a temporary lockout, IP addresses only the (higher) lockout so users
+// behind a shared address are not slowed down by each other.
+type LoginAttemptRepository struct {
+	DB    *sql.DB
+	audit *AuditRepository
+}
+
+// NewLoginAttemptRepository creates a new LoginAttemptRepository
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{DB: db, audit: NewAuditRepository(db)}
+}
+
+// loginFailure is the state of one failure counter
+type loginFailure struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   sql.NullTime
+}
+
+// CheckLogin returns how long a client has to wait before it may try to log in to identifier
+// from ip; zero means the attempt is allowed
+func (lr *LoginAttemptRepository) CheckLogin(identifier, ip string) (time.Duration, error) {
+	key, _, err := lr.accountKey(identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+
+	account, err := lr.getFailure(key, now)
+	if err != nil {
+		return 0, err
+	}
+	if account != nil {
+		wait = max(wait, lockoutRemaining(account, now))
+		wait = max(wait, account.lastFailureAt.Add(backoffDelay(account.failures)).Sub(now))
+	}
+
+	address, err := lr.getFailure(ipKey(ip), now)
+	if err != nil {
+		return 0, err
+	}
+	if address != nil {
+		wait = max(wait, lockoutRemaining(address, now))
+	}
+
+	return wait, nil
+}
+
+// RecordFailure counts a failed login against the account and the IP address and locks either
+// one when it reaches its threshold. Lockouts are written to the audit log.
+func (lr *LoginAttemptRepository) RecordFailure(identifier, ip string) error {
+	key, userID, err := lr.accountKey(identifier)
+	if err != nil {
+		return err
+	}
+
+	accountLocked, err := lr.incrementFailures(key, config.Config.LoginLockoutThreshold)
+	if err != nil {
+		return err
+	}
+	ipLocked, err := lr.incrementFailures(ipKey(ip), config.Config.LoginIPLockoutThreshold)
+	if err != nil {
+		return err
+	}
+
+	if accountLocked {
+		details := fmt.Sprintf("%d failed logins, locked for %s", config.Config.LoginLockoutThreshold, config.Config.LoginLockoutDuration)
+		if userID == "" {
+			details = fmt.Sprintf("unknown account %q: %s", identifier, details)
+		}
+		if err := lr.audit.Record(models.AuditEventAccountLocked, userID, ip, details); err != nil {
+			return err
+		}
+	}
+	if ipLocked {
+		details := fmt.Sprintf("%d failed logins, locked for %s", config.Config.LoginIPLockoutThreshold, config.Config.LoginLockoutDuration)
+		if err := lr.audit.Record(models.AuditEventIPLocked, "", ip, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess resets the account's counter after a complete login. The IP counter is kept,
+// otherwise an attacker could clear it by logging in to an account of their own.
+func (lr *LoginAttemptRepository) RecordSuccess(identifier string) error {
+	key, _, err := lr.accountKey(identifier)
+	if err != nil {
+		return err
+	}
+	_, err = lr.DB.Exec("DELETE FROM login_failures WHERE failure_key = ?", key)
+	return err
+}
+
+// accountKey returns the counter key for a login identifier (username or email). Unknown
+// identifiers get their own key so guessing usernames is throttled the same way.
+func (lr *LoginAttemptRepository) accountKey(identifier string) (string, string, error) {
+	var userID string
+	err := lr.DB.QueryRow(
+		"SELECT user_id FROM users WHERE (LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)) AND deleted_at IS NULL",
+		identifier, identifier,
+	).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "identifier:" + strings.ToLower(identifier), "", nil
+		}
+		return "", "", err
+	}
+	return "user:" + userID, userID, nil
+}
+
+// ipKey returns the counter key for an IP address
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// getFailure returns the counter for key, or nil if there is none or it has expired
+func (lr *LoginAttemptRepository) getFailure(key string, now time.Time) (*loginFailure, error) {
+	var failure loginFailure
+	err := lr.DB.QueryRow(
+		"SELECT failures, last_failure_at, locked_until FROM login_failures WHERE failure_key = ?", key,
+	).Scan(&failure.failures, &failure.lastFailureAt, &failure.lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if lockoutRemaining(&failure, now) == 0 && now.Sub(failure.lastFailureAt) > config.Config.LoginFailureWindow {
+		return nil, nil
+	}
+	return &failure, nil
+}
+
+// incrementFailures adds a failure to the counter for key and locks it once lockoutThreshold
+// is reached (0 disables the lockout). It reports whether the key was locked by this failure.
+func (lr *LoginAttemptRepository) incrementFailures(key string, lockoutThreshold int) (bool, error) {
+	return utils.ExecuteInTransactionWithResult(lr.DB, func(tx *sql.Tx) (bool, error) {
+		now := time.Now()
+
+		// Forget counters that expired, so the table does not grow with every guessed username
+		_, err := tx.Exec(
+			"DELETE FROM login_failures WHERE julianday(last_failure_at) < julianday(?) AND (locked_until IS NULL OR julianday(locked_until) < julianday(?))",
+			now.Add(-config.Config.LoginFailureWindow), now,
+		)
+		if err != nil {
+			return false, err
+		}
+
+		var failures int
+		err = tx.QueryRow("SELECT failures FROM login_failures WHERE failure_key = ?", key).Scan(&failures)
+		if err != nil && err != sql.ErrNoRows {
+			return false, err
+		}
+		failures++
+
+		if lockoutThreshold > 0 && failures >= lockoutThreshold {
+			// Counting starts over once the lockout has passed
+			_, err = tx.Exec(
+				"INSERT OR REPLACE INTO login_failures (failure_key, failures, last_failure_at, locked_until) VALUES (?, 0, ?, ?)",
+				key, now, now.Add(config.Config.LoginLockoutDuration),
+			)
+			return err == nil, err
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO login_failures (failure_key, failures, last_failure_at) VALUES (?, ?, ?)
+			ON CONFLICT(failure_key) DO UPDATE SET failures = excluded.failures, last_failure_at = excluded.last_failure_at`,
+			key, failures, now,
+		)
+		return false, err
+	})
+}
+
+// lockoutRemaining returns how long the counter stays locked
+func lockoutRemaining(failure *loginFailure, now time.Time) time.Duration {
+	if !failure.lockedUntil.Valid || !failure.lockedUntil.Time.After(now) {
+		return 0
+	}
+	return failure.lockedUntil.Time.Sub(now)
+}
+
+// backoffDelay returns the minimum time between the last failure and the next attempt:
+// nothing below LoginBackoffThreshold failures, then LoginBackoffBase doubling with every
+// further failure up to LoginBackoffMax
+func backoffDelay(failures int) time.Duration {
+	threshold := config.Config.LoginBackoffThreshold
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	delay := config.Config.LoginBackoffBase
+	for i := threshold; i < failures && delay < config.Config.LoginBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, config.Config.LoginBackoffMax)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+type MentionRepository struct {
+	DB *sql.DB
+}
+
+// NewMentionRepository creates a new MentionRepository
+func NewMentionRepository(db *sql.DB) *MentionRepository {
+	return &MentionRepository{DB: db}
+}
+
+// mentionSourceColumns maps a mention source to its column in the mentions table
+var mentionSourceColumns = map[string]string{
+	models.MentionSourcePost:    "post_id",
+	models.MentionSourceComment: "comment_id",
+	models.MentionSourceMessage: "message_id",
+}
+
+// SetMentions replaces the users mentioned in a post, comment or message with userIDs and
+// returns the ones that were never mentioned there before, so an edit only notifies new mentions.
+// Mentions an edit takes out are marked removed rather than deleted: putting one back restores it
+// without notifying that user a second time.
+func (mr *MentionRepository) SetMentions(source, sourceID, authorID string, userIDs []string) ([]string, error) {
+	column, ok := mentionSourceColumns[source]
+	if !ok {
+		return nil, errors.New("invalid mention source")
+	}
+
+	return utils.ExecuteInTransactionWithResult(mr.DB, func(tx *sql.Tx) ([]string, error) {
+		rows, err := tx.Query("SELECT mentioned_user_id, removed_at IS NOT NULL FROM mentions WHERE "+column+" = ?", sourceID)
+		if err != nil {
+			return nil, err
+		}
+		previous := make(map[string]bool) // user ID -> mention currently removed
+		for rows.Next() {
+			var userID string
+			var removed bool
+			if err := rows.Scan(&userID, &removed); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			previous[userID] = removed
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		current := make(map[string]bool)
+		added := []string{}
+		now := time.Now()
+		for _, userID := range userIDs {
+			current[userID] = true
+			removed, seen := previous[userID]
+			if seen {
+				if removed {
+					_, err := tx.Exec("UPDATE mentions SET removed_at = NULL WHERE "+column+" = ? AND mentioned_user_id = ?", sourceID, userID)
+					if err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+			_, err := tx.Exec(
+				"INSERT INTO mentions (mention_id, mentioned_user_id, author_id, "+column+", created_at) VALUES (?, ?, ?, ?, ?)",
+				utils.GenerateUUIDToken(), userID, authorID, sourceID, now,
+			)
+			if err != nil {
+				return nil, err
+			}
+			added = append(added, userID)
+		}
+
+		// Mentions taken out by an edit are marked, not forgotten
+		for userID, removed := range previous {
+			if current[userID] || removed {
+				continue
+			}
+			_, err := tx.Exec("UPDATE mentions SET removed_at = ? WHERE "+column+" = ? AND mentioned_user_id = ?", now, sourceID, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return added, nil
+	})
+}
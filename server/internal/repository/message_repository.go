@@ -27,7 +27,7 @@ func (mr *MessageRepository) SaveMessage(senderID, recipientID, content string)
 	return utils.ExecuteInTransactionWithResult(mr.db, func(tx *sql.Tx) (*models.SendMessageResponse, error) {
 		// Check if recipient exists
 		var exists int
-		err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE user_id = ?", recipientID).Scan(&exists)
+		err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE user_id = ? AND deleted_at IS NULL", recipientID).Scan(&exists)
 		if err != nil {
 			return nil, err
 		}
@@ -61,7 +61,7 @@ func (mr *MessageRepository) SaveMessageWithImages(senderID, recipientID, conten
 	return utils.ExecuteInTransactionWithResult(mr.db, func(tx *sql.Tx) (*models.SendMessageResponse, error) {
 		// Check if recipient exists
 		var exists int
-		err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE user_id = ?", recipientID).Scan(&exists)
+		err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE user_id = ? AND deleted_at IS NULL", recipientID).Scan(&exists)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +111,7 @@ func (mr *MessageRepository) GetMessages(currentUserID, otherUserID string, limi
 
 	// Build query based on whether we have a beforeTimestamp (for pagination)
 	baseQuery := `
-		SELECT m.message_id, m.sender_id, u.username, m.recipient_id, m.content, m.created_at, m.is_read
+		SELECT m.message_id, m.sender_id, u.username, u.avatar_url, m.recipient_id, m.content, m.created_at, m.is_read
 		FROM messages m
 		JOIN users u ON m.sender_id = u.user_id
 		WHERE ((m.sender_id = ? AND m.recipient_id = ?) OR (m.sender_id = ? AND m.recipient_id = ?))
@@ -139,6 +139,7 @@ func (mr *MessageRepository) GetMessages(currentUserID, otherUserID string, limi
 			&msg.MessageID,
 			&msg.SenderID,
 			&msg.SenderName,
+			&msg.SenderAvatarURL,
 			&msg.RecipientID,
 			&msg.Content,
 			&msg.CreatedAt,
@@ -160,13 +161,14 @@ func (mr *MessageRepository) GetMessages(currentUserID, otherUserID string, limi
 		messages = messages[:limit] // Trim to requested limit
 	}
 
-	// Fetch images for each message
+	// Fetch images and link previews for each message
 	for i := range messages {
 		images, err := mr.messageImageRepository.GetImagesForMessage(messages[i].MessageID)
 		if err != nil {
 			return nil, err
 		}
 		messages[i].Images = images
+		messages[i].LinkPreviews = linkPreviewsFor(mr.db, messages[i].Content)
 	}
 
 	return &models.GetMessagesResponse{
@@ -237,6 +239,7 @@ func (mr *MessageRepository) GetConversations(currentUserID string) ([]models.Co
 		SELECT
 			u.user_id,
 			u.username,
+			u.avatar_url,
 			lmd.last_message_content,
 			lmd.last_message_created_at,
 			lmd.is_from_me,
@@ -244,7 +247,7 @@ func (mr *MessageRepository) GetConversations(currentUserID string) ([]models.Co
 		FROM users u
 		LEFT JOIN last_message_details lmd ON u.user_id = lmd.other_user_id
 		LEFT JOIN unread_counts uc ON u.user_id = uc.other_user_id
-		WHERE u.user_id != ?
+		WHERE u.user_id != ? AND u.deleted_at IS NULL
 		ORDER BY
 			CASE WHEN lmd.last_message_time IS NULL THEN 1 ELSE 0 END,
 			lmd.last_message_time DESC,
@@ -271,6 +274,7 @@ func (mr *MessageRepository) GetConversations(currentUserID string) ([]models.Co
 		err := rows.Scan(
 			&conv.UserID,
 			&conv.Username,
+			&conv.AvatarURL,
 			&lastMsgContent,
 			&lastMsgCreatedAt,
 			&isFromMe,
@@ -44,10 +44,11 @@ func (nr *NotificationRepository) CreateNotification(notification *models.Notifi
 // GetUserNotifications retrieves all notifications for a specific user, ordered by newest first
 func (nr *NotificationRepository) GetUserNotifications(userID string) ([]*models.Notification, error) {
 	query := `
-		SELECT notification_id, user_id, trigger_username, post_content_preview, post_id, action, is_read, created_at
-		FROM notifications
-		WHERE user_id = ?
-		ORDER BY created_at DESC
+		SELECT n.notification_id, n.user_id, n.trigger_username, COALESCE(u.avatar_url, ''), n.post_content_preview, n.post_id, n.action, n.is_read, n.created_at
+		FROM notifications n
+		LEFT JOIN users u ON u.username = n.trigger_username
+		WHERE n.user_id = ?
+		ORDER BY n.created_at DESC
 	`
 
 	rows, err := nr.DB.Query(query, userID)
@@ -63,6 +64,7 @@ func (nr *NotificationRepository) GetUserNotifications(userID string) ([]*models
 			&notification.NotificationID,
 			&notification.UserID,
 			&notification.TriggerUsername,
+			&notification.TriggerAvatarURL,
 			&notification.PostContentPreview,
 			&notification.PostID,
 			&notification.Action,
@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
+	"real-time-forum/config"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/utils"
 )
 
+// OAuthStateTTL is how long a user has to finish signing in at the provider
+const OAuthStateTTL = 15 * time.Minute
+
 type OAuthRepository struct {
 	DB *sql.DB
 }
@@ -23,43 +28,56 @@ func NewOAuthRepository(db *sql.DB) *OAuthRepository {
 // OAUTH FLOW STATES OPERATIONS
 // ================================
 
-// CreateOAuthState creates a new OAuth flow state for CSRF protection
-func (r *OAuthRepository) CreateOAuthState(provider string) (*models.OAuthFlowState, error) {
+// CreateOAuthState creates a new OAuth flow state for CSRF protection. The state is bound to the
+// browser's OAuth cookie value and carries a fresh PKCE verifier and OpenID Connect nonce.
+// linkUserID is set when a signed-in user links the provider instead of logging in.
+func (r *OAuthRepository) CreateOAuthState(provider, browserBinding, linkUserID string) (*models.OAuthFlowState, error) {
 	// Generate secure random state ID
 	stateID := utils.GenerateUUIDToken()
+	codeVerifier, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PKCE verifier: %w", err)
+	}
+	nonce, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
 	now := time.Now()
-	expiresAt := now.Add(15 * time.Minute) // States expire in 15 minutes
+	expiresAt := now.Add(OAuthStateTTL)
 
 	// Insert state into database
-	_, err := r.DB.Exec(`
-		INSERT INTO oauth_flow_states (state_id, provider, created_at, expires_at)
-		VALUES (?, ?, ?, ?)`,
-		stateID, provider, now, expiresAt)
+	_, err = r.DB.Exec(`
+		INSERT INTO oauth_flow_states (state_id, provider, browser_binding_hash, code_verifier, nonce, user_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)`,
+		stateID, provider, utils.HashToken(browserBinding), codeVerifier, nonce, linkUserID, now, expiresAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OAuth state: %w", err)
 	}
 
 	return &models.OAuthFlowState{
-		StateID:   stateID,
-		Provider:  provider,
-		CreatedAt: now,
-		ExpiresAt: expiresAt,
+		StateID:      stateID,
+		Provider:     provider,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
 	}, nil
 }
 
-// ValidateOAuthState validates and consumes an OAuth state
-func (r *OAuthRepository) ValidateOAuthState(stateID, provider string) error {
-	return utils.ExecuteInTransaction(r.DB, func(tx *sql.Tx) error {
+// ConsumeOAuthState validates and consumes an OAuth state. It must have been created for provider
+// in the browser that presents browserBinding, so a state cannot be completed by someone else.
+func (r *OAuthRepository) ConsumeOAuthState(stateID, provider, browserBinding string) (*models.OAuthFlowState, error) {
+	state := &models.OAuthFlowState{StateID: stateID}
+	var bindingHash string
+	err := utils.ExecuteInTransaction(r.DB, func(tx *sql.Tx) error {
 		// Check if state exists and is valid
-		var dbProvider string
-		var expiresAt time.Time
-
 		err := tx.QueryRow(`
-			SELECT provider, expires_at 
+			SELECT provider, browser_binding_hash, code_verifier, nonce, COALESCE(user_id, ''), created_at, expires_at
 			FROM oauth_flow_states 
 			WHERE state_id = ?`,
-			stateID).Scan(&dbProvider, &expiresAt)
+			stateID).Scan(&state.Provider, &bindingHash, &state.CodeVerifier, &state.Nonce, &state.LinkUserID, &state.CreatedAt, &state.ExpiresAt)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -68,24 +86,33 @@ func (r *OAuthRepository) ValidateOAuthState(stateID, provider string) error {
 			return fmt.Errorf("failed to validate OAuth state: %w", err)
 		}
 
-		// Check if state expired
-		if time.Now().After(expiresAt) {
-			return errors.New("OAuth state expired")
-		}
-
-		// Check if provider matches
-		if dbProvider != provider {
-			return errors.New("OAuth state provider mismatch")
-		}
-
-		// Delete the state (one-time use)
+		// Delete the state (one-time use, also when the checks below fail)
 		_, err = tx.Exec("DELETE FROM oauth_flow_states WHERE state_id = ?", stateID)
 		if err != nil {
 			return fmt.Errorf("failed to consume OAuth state: %w", err)
 		}
-
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if state expired
+	if time.Now().After(state.ExpiresAt) {
+		return nil, errors.New("OAuth state expired")
+	}
+
+	// Check if provider matches
+	if state.Provider != provider {
+		return nil, errors.New("OAuth state provider mismatch")
+	}
+
+	// Check that the login finishes in the browser that started it
+	if browserBinding == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(browserBinding)), []byte(bindingHash)) != 1 {
+		return nil, errors.New("OAuth state belongs to another browser")
+	}
+
+	return state, nil
 }
 
 // ================================
@@ -95,6 +122,7 @@ func (r *OAuthRepository) ValidateOAuthState(stateID, provider string) error {
 // GetOAuthAccountByProvider gets OAuth account by provider and provider user ID
 func (r *OAuthRepository) GetOAuthAccountByProvider(provider, providerUserID string) (*models.OAuthUserAccount, error) {
 	var account models.OAuthUserAccount
+	var encryptedToken string
 
 	err := r.DB.QueryRow(`
 		SELECT user_id, provider, provider_user_id, provider_email, provider_username, access_token, created_at
@@ -103,7 +131,7 @@ func (r *OAuthRepository) GetOAuthAccountByProvider(provider, providerUserID str
 		provider, providerUserID).Scan(
 		&account.UserID, &account.Provider, &account.ProviderUserID,
 		&account.ProviderEmail, &account.ProviderUsername,
-		&account.AccessToken, &account.CreatedAt)
+		&encryptedToken, &account.CreatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -112,25 +140,22 @@ func (r *OAuthRepository) GetOAuthAccountByProvider(provider, providerUserID str
 		return nil, fmt.Errorf("failed to get OAuth account: %w", err)
 	}
 
+	// A token sealed under an older key is left empty; it is replaced at the next sign-in
+	account.AccessToken, _ = utils.DecryptSecret(encryptedToken, config.Config.OAuthTokenKey)
+
 	return &account, nil
 }
 
-// CreateOAuthAccount creates a new OAuth account link (UPDATED for both GitHub and Google)
-func (r *OAuthRepository) CreateOAuthAccount(userID string, userData interface{}, accessToken string) error {
+// CreateOAuthAccount links a provider identity to a user; the access token is stored encrypted
+func (r *OAuthRepository) CreateOAuthAccount(userID string, identity *models.OAuthIdentity, accessToken string) error {
+	encryptedToken, err := utils.EncryptSecret(accessToken, config.Config.OAuthTokenKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt OAuth token: %w", err)
+	}
+
 	return utils.ExecuteInTransaction(r.DB, func(tx *sql.Tx) error {
-		var provider, providerUserID, providerEmail, providerUsername string
-
-		// Handle different provider types
-		switch user := userData.(type) {
-		case *models.GitHubUser:
-			provider = "github"
-			providerUserID, providerEmail, providerUsername = models.ConvertGitHubUserToGeneric(user)
-		case *models.GoogleUser:
-			provider = "google"
-			providerUserID, providerEmail, providerUsername = models.ConvertGoogleUserToGeneric(user)
-		default:
-			return fmt.Errorf("unsupported OAuth provider type")
-		}
+		provider, providerUserID := identity.Provider, identity.ProviderUserID
+		providerEmail, providerUsername := identity.Email, identity.Username
 
 		// Check if this OAuth account is already linked to another user
 		var existingUserID string
@@ -142,25 +167,35 @@ func (r *OAuthRepository) CreateOAuthAccount(userID string, userData interface{}
 		if err == nil {
 			// OAuth account already linked
 			if existingUserID != userID {
-				return fmt.Errorf("%s account already linked to different user", provider)
+				return errors.New("OAuth account linked to another user")
 			}
 			// Already linked to same user - update token
 			_, err = tx.Exec(`
 				UPDATE oauth_user_accounts 
 				SET access_token = ?, provider_email = ?, provider_username = ? 
 				WHERE user_id = ? AND provider = ?`,
-				accessToken, providerEmail, providerUsername, userID, provider)
+				encryptedToken, providerEmail, providerUsername, userID, provider)
 			return err
 		} else if err != sql.ErrNoRows {
 			return fmt.Errorf("failed to check existing OAuth account: %w", err)
 		}
 
+		// A user links one account per provider
+		var linkedCount int
+		err = tx.QueryRow("SELECT COUNT(*) FROM oauth_user_accounts WHERE user_id = ? AND provider = ?", userID, provider).Scan(&linkedCount)
+		if err != nil {
+			return fmt.Errorf("failed to check linked providers: %w", err)
+		}
+		if linkedCount > 0 {
+			return errors.New("provider already linked")
+		}
+
 		// Create new OAuth account link
 		_, err = tx.Exec(`
 			INSERT INTO oauth_user_accounts 
 			(user_id, provider, provider_user_id, provider_email, provider_username, access_token, created_at)
 			VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			userID, provider, providerUserID, providerEmail, providerUsername, accessToken, time.Now())
+			userID, provider, providerUserID, providerEmail, providerUsername, encryptedToken, time.Now())
 
 		if err != nil {
 			return fmt.Errorf("failed to create OAuth account: %w", err)
@@ -170,13 +205,75 @@ func (r *OAuthRepository) CreateOAuthAccount(userID string, userData interface{}
 	})
 }
 
-// UpdateOAuthToken updates the access token for an OAuth account
+// ListOAuthAccounts returns the provider accounts linked to a user, oldest first
+func (r *OAuthRepository) ListOAuthAccounts(userID string) ([]*models.OAuthUserAccount, error) {
+	rows, err := r.DB.Query(`
+		SELECT user_id, provider, provider_user_id, COALESCE(provider_email, ''), COALESCE(provider_username, ''), created_at
+		FROM oauth_user_accounts
+		WHERE user_id = ?
+		ORDER BY created_at ASC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OAuth accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []*models.OAuthUserAccount{}
+	for rows.Next() {
+		var account models.OAuthUserAccount
+		if err := rows.Scan(&account.UserID, &account.Provider, &account.ProviderUserID,
+			&account.ProviderEmail, &account.ProviderUsername, &account.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan OAuth account: %w", err)
+		}
+		accounts = append(accounts, &account)
+	}
+	return accounts, rows.Err()
+}
+
+// UnlinkOAuthAccount removes a linked provider account. Unless the user has a password, another
+// provider account must remain; the check and the delete are one statement so that two parallel
+// unlinks cannot remove the last two login methods.
+func (r *OAuthRepository) UnlinkOAuthAccount(userID, provider string, hasPassword bool) error {
+	result, err := r.DB.Exec(`
+		DELETE FROM oauth_user_accounts
+		WHERE user_id = ? AND provider = ?
+		AND (? OR (SELECT COUNT(*) FROM oauth_user_accounts WHERE user_id = ?) > 1)`,
+		userID, provider, hasPassword, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink OAuth account: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var count int
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM oauth_user_accounts WHERE user_id = ? AND provider = ?", userID, provider).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check OAuth account: %w", err)
+	}
+	if count == 0 {
+		return errors.New("OAuth account not found")
+	}
+	return errors.New("last login method")
+}
+
+// UpdateOAuthToken updates the (encrypted) access token for an OAuth account
 func (r *OAuthRepository) UpdateOAuthToken(userID, provider, newToken string) error {
+	encryptedToken, err := utils.EncryptSecret(newToken, config.Config.OAuthTokenKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt OAuth token: %w", err)
+	}
+
 	result, err := r.DB.Exec(`
 		UPDATE oauth_user_accounts 
 		SET access_token = ? 
 		WHERE user_id = ? AND provider = ?`,
-		newToken, userID, provider)
+		encryptedToken, userID, provider)
 
 	if err != nil {
 		return fmt.Errorf("failed to update OAuth token: %w", err)
@@ -198,7 +295,8 @@ func (r *OAuthRepository) UpdateOAuthToken(userID, provider, newToken string) er
 // EMAIL CONFLICT CHECKING
 // ================================
 
-// CheckEmailConflict checks if an email from OAuth conflicts with existing users
+// CheckEmailConflict checks if an email from OAuth conflicts with existing users.
+// Only verified addresses count; unverified ones are released with ReleaseUnverifiedEmail.
 func (r *OAuthRepository) CheckEmailConflict(email string) (*models.User, error) {
 	if email == "" {
 		return nil, nil // No email, no conflict
@@ -208,7 +306,7 @@ func (r *OAuthRepository) CheckEmailConflict(email string) (*models.User, error)
 	err := r.DB.QueryRow(`
 		SELECT user_id, username, email, created_at
 		FROM users 
-		WHERE LOWER(email) = LOWER(?)`,
+		WHERE LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL`,
 		email).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
 
 	if err != nil {
@@ -220,3 +318,31 @@ func (r *OAuthRepository) CheckEmailConflict(email string) (*models.User, error)
 
 	return &user, nil // Conflict found
 }
+
+// ReleaseUnverifiedEmail frees an address that a local account registered but never verified,
+// so a provider-verified OAuth sign-up with the same address is not blocked by it. Accounts from
+// before email verification existed were marked verified by migration 7 and are never released.
+// The local account keeps working with its username and can set a new email later.
+func (r *OAuthRepository) ReleaseUnverifiedEmail(email string) error {
+	return utils.ExecuteInTransaction(r.DB, func(tx *sql.Tx) error {
+		var userID string
+		err := tx.QueryRow(`
+			SELECT user_id FROM users
+			WHERE LOWER(email) = LOWER(?) AND email_verified_at IS NULL`,
+			email).Scan(&userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("failed to check unverified email: %w", err)
+		}
+
+		_, err = tx.Exec("UPDATE users SET email = ? WHERE user_id = ?", "unverified+"+userID+"@invalid", userID)
+		if err != nil {
+			return fmt.Errorf("failed to release unverified email: %w", err)
+		}
+
+		_, err = tx.Exec("DELETE FROM email_tokens WHERE user_id = ? AND purpose = ?", userID, models.EmailTokenEmailVerification)
+		return err
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/utils"
+	"real-time-forum/queries"
 )
 
 type PostReactionRepository struct {
@@ -115,7 +116,7 @@ func (prr *PostReactionRepository) changePostReaction(tx *sql.Tx, userID, postID
 // Helper method to validate that a post exists
 func (prr *PostReactionRepository) validatePostExists(tx *sql.Tx, postID string) error {
 	var exists int
-	err := tx.QueryRow("SELECT COUNT(*) FROM posts WHERE post_id = ?", postID).Scan(&exists)
+	err := tx.QueryRow("SELECT COUNT(*) FROM posts p WHERE p.post_id = ? AND p.deleted_at IS NULL AND "+queries.PublishedCondition, postID).Scan(&exists)
 	if err != nil {
 		return err
 	}
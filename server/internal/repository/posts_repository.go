@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"real-time-forum/internal/markdown"
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/utils"
 	"real-time-forum/queries"
@@ -21,16 +22,20 @@ func NewPostsRepository(db *sql.DB) *PostsRepository {
 }
 
 // CRUD methods
-func (pr *PostsRepository) CreatePost(userID string, content string, categoryIDs []string, images []models.PostImage) (*models.CreatePostResponse, error) {
+// A scheduled post gets its publish time as created_at so it shows up at the top of the feed once due
+func (pr *PostsRepository) CreatePost(userID string, content string, categoryIDs []string, images []models.PostImage, publishing models.PostPublishing) (*models.CreatePostResponse, error) {
 	return utils.ExecuteInTransactionWithResult(pr.db, func(tx *sql.Tx) (*models.CreatePostResponse, error) {
 		// Generate UUID for the post
 		postID := utils.GenerateUUIDToken()
 		createdAt := time.Now()
+		if publishing.PublishAt != nil && publishing.PublishAt.After(createdAt) {
+			createdAt = *publishing.PublishAt
+		}
 
 		// Insert post
 		_, err := tx.Exec(
-			"INSERT INTO posts (post_id, user_id, content, created_at) VALUES (?, ?, ?, ?)",
-			postID, userID, content, createdAt,
+			"INSERT INTO posts (post_id, user_id, title, content, status, publish_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			postID, userID, nullIfEmpty(publishing.Title), content, publishing.Status, publishing.PublishAt, createdAt,
 		)
 		if err != nil {
 			return nil, err
@@ -60,19 +65,26 @@ func (pr *PostsRepository) CreatePost(userID string, content string, categoryIDs
 			}
 		}
 
-		// Return lightweight response - just ID and timestamp
+		// Return lightweight response - just ID, status and timestamp
 		return &models.CreatePostResponse{
 			PostID:    postID,
+			Status:    publishing.Status,
+			PublishAt: publishing.PublishAt,
 			CreatedAt: createdAt,
 		}, nil
 	})
 }
 
-func (pr *PostsRepository) UpdatePost(postID, userID, content string, categoryIDs []string) error {
+// Also used to autosave drafts; publishing a draft (or rescheduling it) moves created_at to its publish time
+func (pr *PostsRepository) UpdatePost(postID, userID, content string, categoryIDs []string, publishing models.PostPublishing) error {
 	return utils.ExecuteInTransaction(pr.db, func(tx *sql.Tx) error {
 		// Check if user owns the post
-		var ownerID string
-		err := tx.QueryRow("SELECT user_id FROM posts WHERE post_id = ?", postID).Scan(&ownerID)
+		var ownerID, oldContent string
+		var current models.PostPublishing
+		var publishAt sql.NullTime
+		err := tx.QueryRow(
+			"SELECT user_id, content, status, publish_at FROM posts WHERE post_id = ? AND deleted_at IS NULL", postID,
+		).Scan(&ownerID, &oldContent, &current.Status, &publishAt)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return errors.New("post not found")
@@ -85,13 +97,42 @@ func (pr *PostsRepository) UpdatePost(postID, userID, content string, categoryID
 		}
 
 		now := time.Now()
+		if publishAt.Valid {
+			current.PublishAt = &publishAt.Time
+		}
+		wasLive := current.IsLive(now)
+		if wasLive && !publishing.IsLive(now) {
+			return errors.New("published posts cannot be turned back into drafts")
+		}
+
+		// Keep the previous content in the edit history (drafts are not versioned)
+		if wasLive && oldContent != content {
+			if err := savePostRevision(tx, postID, oldContent, userID, now); err != nil {
+				return err
+			}
+		}
 
 		// 1. Update post content AND set updated_at
-		_, err = tx.Exec("UPDATE posts SET content = ?, updated_at = ? WHERE post_id = ?", content, now, postID)
+		_, err = tx.Exec(
+			"UPDATE posts SET title = ?, content = ?, status = ?, publish_at = ?, updated_at = ? WHERE post_id = ?",
+			nullIfEmpty(publishing.Title), content, publishing.Status, publishing.PublishAt, now, postID,
+		)
 		if err != nil {
 			return err
 		}
 
+		// Not live before: the post is (re)published now or at its scheduled time
+		if !wasLive && publishing.Status == models.PostStatusPublished {
+			publishedAt := now
+			if publishing.PublishAt != nil && publishing.PublishAt.After(now) {
+				publishedAt = *publishing.PublishAt
+			}
+			_, err = tx.Exec("UPDATE posts SET created_at = ?, updated_at = NULL WHERE post_id = ?", publishedAt, postID)
+			if err != nil {
+				return err
+			}
+		}
+
 		// 2. UPDATE CATEGORIES - Delete existing categories first
 		_, err = tx.Exec("DELETE FROM post_categories WHERE post_id = ?", postID)
 		if err != nil {
@@ -116,7 +157,7 @@ func (pr *PostsRepository) UpdatePost(postID, userID, content string, categoryID
 func (pr *PostsRepository) DeletePost(postID, userID string) error {
 	return utils.ExecuteInTransaction(pr.db, func(tx *sql.Tx) error {
 		var ownerID string
-		err := tx.QueryRow("SELECT user_id FROM posts WHERE post_id = ?", postID).Scan(&ownerID)
+		err := tx.QueryRow("SELECT user_id FROM posts WHERE post_id = ? AND deleted_at IS NULL", postID).Scan(&ownerID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return errors.New("post not found")
@@ -128,8 +169,8 @@ func (pr *PostsRepository) DeletePost(postID, userID string) error {
 			return errors.New("unauthorized: you can only delete your own posts")
 		}
 
-		// Delete the post (CASCADE will handle related records)
-		_, err = tx.Exec("DELETE FROM posts WHERE post_id = ?", postID)
+		// Soft delete: comments, reactions and images stay until the post is purged
+		_, err = tx.Exec("UPDATE posts SET deleted_at = ? WHERE post_id = ?", time.Now(), postID)
 		if err != nil {
 			return err
 		}
@@ -138,135 +179,236 @@ func (pr *PostsRepository) DeletePost(postID, userID string) error {
 	})
 }
 
-// GET METHODS - Using Queries Package
+// RestorePost undoes a soft delete if the grace period has not expired yet
+func (pr *PostsRepository) RestorePost(postID, userID string, gracePeriod time.Duration) error {
+	return utils.ExecuteInTransaction(pr.db, func(tx *sql.Tx) error {
+		var ownerID string
+		var deletedAt sql.NullTime
+		err := tx.QueryRow("SELECT user_id, deleted_at FROM posts WHERE post_id = ?", postID).Scan(&ownerID, &deletedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("post not found")
+			}
+			return err
+		}
 
-func (pr *PostsRepository) GetPostByID(postID string, userID string) (*models.Post, error) {
+		if ownerID != userID {
+			return errors.New("unauthorized: you can only restore your own posts")
+		}
+		if !deletedAt.Valid {
+			return errors.New("post is not deleted")
+		}
+		if time.Since(deletedAt.Time) > gracePeriod {
+			return errors.New("restore period has expired")
+		}
 
-	row := pr.db.QueryRow(queries.GetPostByIDQuery, userID, postID)
-	post, err := pr.scanAndParsePost(row, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("post not found")
+		_, err = tx.Exec("UPDATE posts SET deleted_at = NULL WHERE post_id = ?", postID)
+		return err
+	})
+}
+
+// PurgeDeletedPosts permanently removes posts soft-deleted before the cutoff (CASCADE handles related records).
+// Returns the images of the purged posts so their files can be removed from disk.
+func (pr *PostsRepository) PurgeDeletedPosts(cutoff time.Time) ([]models.PostImage, int64, error) {
+	var images []models.PostImage
+	var purged int64
+
+	err := utils.ExecuteInTransaction(pr.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT pi.image_id, pi.post_id, pi.image_url, pi.original_filename, pi.uploaded_at
+			FROM post_images pi
+			JOIN posts p ON pi.post_id = p.post_id
+			WHERE p.deleted_at IS NOT NULL AND julianday(p.deleted_at) <= julianday(?)`, cutoff)
+		if err != nil {
+			return err
 		}
-		return nil, err
+		defer rows.Close()
+
+		for rows.Next() {
+			var img models.PostImage
+			if err := rows.Scan(&img.ImageID, &img.PostID, &img.ImageURL, &img.OriginalFilename, &img.UploadedAt); err != nil {
+				return err
+			}
+			images = append(images, img)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM posts WHERE deleted_at IS NOT NULL AND julianday(deleted_at) <= julianday(?)", cutoff)
+		if err != nil {
+			return err
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return post, nil
+	return images, purged, nil
 }
 
-// GetAllPosts retrieves all posts (sorted by newest first by default, or custom sorting)
-func (pr *PostsRepository) GetAllPosts(limit, offset int, userID string, options utils.SortOptions) ([]*models.Post, error) {
-
-	// Build dynamic query with sort options
-	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
-	query := queries.GetAllPostsWithSortQuery(orderClause)
+// ClaimAnnouncement marks a live post as announced and reports whether this call did it, so the
+// post.created webhook and mention notifications go out once even if the author's request and the
+// scheduled post publisher get there at the same time
+func (pr *PostsRepository) ClaimAnnouncement(postID string, now time.Time) (bool, error) {
+	result, err := pr.db.Exec(`
+		UPDATE posts SET announced_at = ?
+		WHERE post_id = ? AND announced_at IS NULL AND deleted_at IS NULL AND status = 'published'
+			AND (publish_at IS NULL OR julianday(publish_at) <= julianday(?))`,
+		now, postID, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	return claimed == 1, err
+}
 
-	rows, err := pr.db.Query(query, userID, limit, offset)
+// GetDueScheduledPosts returns the scheduled posts whose publication time has come and that
+// were not announced yet, oldest first. Only the fields needed to announce them are set.
+func (pr *PostsRepository) GetDueScheduledPosts(now time.Time) ([]models.Post, error) {
+	rows, err := pr.db.Query(`
+		SELECT p.post_id, p.user_id, u.username, COALESCE(p.title, ''), p.content, p.status, p.publish_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.user_id
+		WHERE p.announced_at IS NULL AND p.publish_at IS NOT NULL AND p.status = 'published'
+			AND p.deleted_at IS NULL AND julianday(p.publish_at) <= julianday(?)
+		ORDER BY p.publish_at`, now)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []*models.Post
+	var posts []models.Post
 	for rows.Next() {
-		post, err := pr.scanAndParsePost(rows, userID)
-		if err != nil {
+		var post models.Post
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Username, &post.Title, &post.Content, &post.Status, &post.PublishAt); err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
 	}
-
-	return posts, nil
+	return posts, rows.Err()
 }
 
-// GetPostsByCategory retrieves posts by category (sorted by newest first by default, or custom sorting)
-func (pr *PostsRepository) GetPostsByCategory(categoryID string, limit, offset int, userID string, options utils.SortOptions) ([]*models.Post, error) {
+// GET METHODS - Using Queries Package
 
-	// Build dynamic query with sort options
-	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
-	query := queries.GetPostsByCategoryWithSortQuery(orderClause)
+func (pr *PostsRepository) GetPostByID(postID string, userID string) (*models.Post, error) {
 
-	rows, err := pr.db.Query(query, userID, categoryID, limit, offset)
+	// The author can also see their own drafts and scheduled posts
+	row := pr.db.QueryRow(queries.GetPostByIDQuery, userID, userID, postID, userID)
+	post, err := pr.scanAndParsePost(row, userID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("post not found")
+		}
 		return nil, err
 	}
-	defer rows.Close()
 
-	var posts []*models.Post
-	for rows.Next() {
-		post, err := pr.scanAndParsePost(rows, userID)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
+	return post, nil
+}
+
+// GetAllPosts retrieves all posts (sorted by newest first by default, or custom sorting)
+// When a cursor is given the page starts right after it and offset is ignored
+func (pr *PostsRepository) GetAllPosts(limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
+
+	// Build dynamic query with sort options
+	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetAllPostsWithSortQuery(orderClause, keysetClause)
 
-	return posts, nil
+	args := append([]interface{}{userID, userID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
+}
+
+// GetPostsByCategory retrieves posts by category (sorted by newest first by default, or custom sorting)
+func (pr *PostsRepository) GetPostsByCategory(categoryID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
+
+	// Build dynamic query with sort options
+	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetPostsByCategoryWithSortQuery(orderClause, keysetClause)
+
+	args := append([]interface{}{userID, userID, categoryID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
 }
 
 // ...
 // Profiling methods for user-specific posts
 // ...
 // GetPostsByUser retrieves posts by user (sorted by newest first by default, or custom sorting)
-func (pr *PostsRepository) GetPostsByUser(targetUserID string, limit, offset int, userID string, options utils.SortOptions) ([]*models.Post, error) {
+func (pr *PostsRepository) GetPostsByUser(targetUserID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
 
 	// Build dynamic query with sort options
 	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
-	query := queries.GetPostsByUserWithSortQuery(orderClause)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetPostsByUserWithSortQuery(orderClause, keysetClause)
 
-	rows, err := pr.db.Query(query, userID, targetUserID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	args := append([]interface{}{userID, userID, targetUserID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
+}
 
-	var posts []*models.Post
-	for rows.Next() {
-		post, err := pr.scanAndParsePost(rows, userID)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
+// GetDraftsByUser retrieves the user's drafts and scheduled posts that are not published yet
+func (pr *PostsRepository) GetDraftsByUser(userID string, limit, offset int, cursor *utils.Cursor, options utils.SortOptions) ([]*models.Post, string, error) {
 
-	return posts, nil
+	// Build dynamic query with sort options
+	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetDraftsByUserWithSortQuery(orderClause, keysetClause)
+
+	args := append([]interface{}{userID, userID, userID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
 }
 
 // GetPostsLikedByUser retrieves posts liked by user (sorted by newest first by default, or custom sorting)
-func (pr *PostsRepository) GetPostsLikedByUser(targetUserID string, limit, offset int, userID string, options utils.SortOptions) ([]*models.Post, error) {
+func (pr *PostsRepository) GetPostsLikedByUser(targetUserID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
 
 	// Build dynamic query with sort options
 	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
-	query := queries.GetPostsLikedByUserWithSortQuery(orderClause)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetPostsLikedByUserWithSortQuery(orderClause, keysetClause)
 
-	rows, err := pr.db.Query(query, userID, targetUserID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	args := append([]interface{}{userID, userID, targetUserID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
+}
 
-	var posts []*models.Post
-	for rows.Next() {
-		post, err := pr.scanAndParsePost(rows, userID)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
+// GetPostsCommentedByUser retrieves posts commented by user (sorted by newest first by default, or custom sorting)
+func (pr *PostsRepository) GetPostsCommentedByUser(targetUserID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
+
+	// Build dynamic query with sort options
+	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetPostsCommentedByUserWithSortQuery(orderClause, keysetClause)
 
-	return posts, nil
+	args := append([]interface{}{userID, userID, targetUserID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
 }
 
-// GetPostsCommentedByUser retrieves posts commented by user (sorted by newest first by default, or custom sorting)
-func (pr *PostsRepository) GetPostsCommentedByUser(targetUserID string, limit, offset int, userID string, options utils.SortOptions) ([]*models.Post, error) {
+// GetPostsBookmarkedByUser retrieves posts saved by user (sorted by newest first by default, or custom sorting)
+func (pr *PostsRepository) GetPostsBookmarkedByUser(targetUserID string, limit, offset int, cursor *utils.Cursor, userID string, options utils.SortOptions) ([]*models.Post, string, error) {
 
 	// Build dynamic query with sort options
 	orderClause := utils.BuildOrderClause(options.SortBy, utils.ContentTypePosts)
-	query := queries.GetPostsCommentedByUserWithSortQuery(orderClause)
+	keysetClause, keysetArgs := utils.BuildKeysetClause(cursor, utils.ContentTypePosts)
+	query := queries.GetPostsBookmarkedByUserWithSortQuery(orderClause, keysetClause)
 
-	rows, err := pr.db.Query(query, userID, targetUserID, limit, offset)
+	args := append([]interface{}{userID, userID, targetUserID}, keysetArgs...)
+	return pr.queryPostsPage(query, args, limit, offset, cursor, userID, options.SortBy)
+}
+
+// queryPostsPage runs a listing query and returns one page plus the cursor for the next one.
+// It fetches limit+1 rows (like GetMessages) to know whether another page exists.
+func (pr *PostsRepository) queryPostsPage(query string, args []interface{}, limit, offset int, cursor *utils.Cursor, userID, sortBy string) ([]*models.Post, string, error) {
+	// Keyset pagination replaces the offset
+	if cursor != nil {
+		offset = 0
+	}
+
+	args = append(args, limit+1, offset)
+	rows, err := pr.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -274,26 +416,52 @@ func (pr *PostsRepository) GetPostsCommentedByUser(targetUserID string, limit, o
 	for rows.Next() {
 		post, err := pr.scanAndParsePost(rows, userID)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		posts = append(posts, post)
 	}
 
-	return posts, nil
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	// Check if there are more posts
+	if len(posts) <= limit {
+		return posts, "", nil
+	}
+	posts = posts[:limit]
+
+	return posts, newPostCursor(posts[len(posts)-1], sortBy), nil
+}
+
+// newPostCursor builds the next-page token from the last post of a page
+func newPostCursor(post *models.Post, sortBy string) string {
+	count := 0
+	switch sortBy {
+	case "likes":
+		count = post.LikeCount
+	case "comments":
+		count = post.CommentCount
+	}
+	return utils.NewCursor(sortBy, count, post.CreatedAt, post.ID)
 }
 
 // HELPER METHODS FOR POST COUNT
 // get all the post count
 func (pr *PostsRepository) GetCountTotalPosts() (int, error) {
 	var count int
-	err := pr.db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&count)
+	err := pr.db.QueryRow("SELECT COUNT(*) FROM posts p " + queries.BaseWhere).Scan(&count)
 	return count, err
 }
 
 // get all the post count by category
 func (pr *PostsRepository) GetCountPostByCategory(categoryID string) (int, error) {
 	var count int
-	err := pr.db.QueryRow("SELECT COUNT(*) FROM post_categories WHERE category_id = ?", categoryID).Scan(&count)
+	err := pr.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM post_categories pc
+		JOIN posts p ON pc.post_id = p.post_id
+		WHERE pc.category_id = ? AND p.deleted_at IS NULL AND `+queries.PublishedCondition, categoryID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -304,7 +472,7 @@ func (pr *PostsRepository) GetCountPostByCategory(categoryID string) (int, error
 // Get post count created by the user
 func (pr *PostsRepository) GetCountPostByUser(userID string) (int, error) {
 	var count int
-	err := pr.db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ?", userID).Scan(&count)
+	err := pr.db.QueryRow("SELECT COUNT(*) FROM posts p WHERE p.user_id = ? AND p.deleted_at IS NULL AND "+queries.PublishedCondition, userID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -319,8 +487,7 @@ func (pr *PostsRepository) GetCountCommentedPostByUser(userID string) (int, erro
 		SELECT COUNT(DISTINCT p.post_id) 
 		FROM posts p
 		JOIN comments c ON p.post_id = c.post_id
-		WHERE c.user_id = ?
-	`, userID).Scan(&count)
+		WHERE c.user_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL AND `+queries.PublishedCondition, userID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -333,15 +500,50 @@ func (pr *PostsRepository) GetCountLikedPostByUser(userID string) (int, error) {
 	// FIXED: use post_reactions table instead of non-existent reactions table
 	err := pr.db.QueryRow(`
 		SELECT COUNT(*) 
-		FROM post_reactions 
-		WHERE user_id = ? AND reaction_type = 1
-	`, userID).Scan(&count)
+		FROM post_reactions r
+		JOIN posts p ON r.post_id = p.post_id
+		WHERE r.user_id = ? AND r.reaction_type = 1 AND p.deleted_at IS NULL AND `+queries.PublishedCondition, userID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetCountBookmarkedPostByUser returns the total number of posts saved by a specific user
+func (pr *PostsRepository) GetCountBookmarkedPostByUser(userID string) (int, error) {
+	var count int
+	err := pr.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM bookmarks b
+		JOIN posts p ON b.post_id = p.post_id
+		WHERE b.user_id = ? AND p.deleted_at IS NULL AND `+queries.PublishedCondition, userID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
+// GetCountDraftsByUser returns the number of the user's drafts and scheduled posts
+func (pr *PostsRepository) GetCountDraftsByUser(userID string) (int, error) {
+	var count int
+	err := pr.db.QueryRow(
+		"SELECT COUNT(*) FROM posts p WHERE p.user_id = ? AND p.deleted_at IS NULL AND NOT ("+queries.PublishedCondition+")",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// nullIfEmpty stores empty optional text as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 //...
 // Helper method to parse categories , handle updated_at and user reaction
 //...
@@ -352,6 +554,8 @@ func (pr *PostsRepository) scanAndParsePost(rows interface{}, userID string) (*m
 	var categoriesStr sql.NullString
 	var userReaction sql.NullInt64
 	var updatedAt sql.NullTime
+	var title sql.NullString
+	var publishAt sql.NullTime
 
 	var err error
 
@@ -362,28 +566,38 @@ func (pr *PostsRepository) scanAndParsePost(rows interface{}, userID string) (*m
 			&post.ID,
 			&post.UserID,
 			&post.Username,
+			&post.AvatarURL,
 			&post.Content,
 			&post.CreatedAt,
 			&updatedAt,
+			&title,
+			&post.Status,
+			&publishAt,
 			&post.LikeCount,
 			&post.DislikeCount,
 			&post.CommentCount,
 			&categoriesStr,
 			&userReaction,
+			&post.IsBookmarked,
 		)
 	case *sql.Rows:
 		err = v.Scan(
 			&post.ID,
 			&post.UserID,
 			&post.Username,
+			&post.AvatarURL,
 			&post.Content,
 			&post.CreatedAt,
 			&updatedAt,
+			&title,
+			&post.Status,
+			&publishAt,
 			&post.LikeCount,
 			&post.DislikeCount,
 			&post.CommentCount,
 			&categoriesStr,
 			&userReaction,
+			&post.IsBookmarked,
 		)
 	default:
 		return nil, errors.New("invalid row type")
@@ -414,6 +628,14 @@ func (pr *PostsRepository) scanAndParsePost(rows interface{}, userID string) (*m
 		post.UpdatedAt = nil
 	}
 
+	post.ContentHTML = markdown.Render(post.Content)
+
+	// Optional title and scheduled publication time
+	post.Title = title.String
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+
 	// Handle UserReaction - NO CHANGE NEEDED
 	if userReaction.Valid {
 		reactionType := int(userReaction.Int64)
@@ -442,5 +664,7 @@ func (pr *PostsRepository) scanAndParsePost(rows interface{}, userID string) (*m
 		}
 	}
 
+	post.LinkPreviews = linkPreviewsFor(pr.db, post.Content)
+
 	return &post, nil
 }
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// TestPostsPagingAcrossEqualTimestamps pages through posts that share created_at, stored both
+// the way Go writes times and the way CURRENT_TIMESTAMP does, and checks that every sort returns
+// each post exactly once and in order, whatever the page size
+func TestPostsPagingAcrossEqualTimestamps(t *testing.T) {
+	if err := config.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.DBPath = filepath.Join(t.TempDir(), "forum.db")
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	user, err := NewUserRepository(db).CreateUser(models.UserRegistration{
+		Username: "alice", Age: 30, Gender: "Other", FirstName: "Alice", LastName: "Liddell",
+		Email: "alice@example.com", Password: "Secret1!pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tie := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	createdAt := []interface{}{
+		tie, tie, tie, // written by Go: "2024-01-01 12:00:00+00:00"
+		"2024-01-01 12:00:00", "2024-01-01 12:00:00", // written by CURRENT_TIMESTAMP
+		time.Date(2024, 1, 1, 13, 0, 0, 0, time.FixedZone("", 2*60*60)), // 11:00 UTC, after the tie as text
+		"2024-01-01 12:30:00",
+	}
+	likes := map[int]bool{1: true, 3: true, 6: true}
+
+	pr := NewPostsRepository(db)
+	prr := NewPostReactionRepository(db)
+	type row struct {
+		id        string
+		createdAt time.Time
+		likes     int
+	}
+	var rows []row
+	for i, value := range createdAt {
+		post, err := pr.CreatePost(user.ID, "post", nil, nil, models.PostPublishing{Status: models.PostStatusPublished})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("UPDATE posts SET created_at = ? WHERE post_id = ?", value, post.PostID); err != nil {
+			t.Fatal(err)
+		}
+		r := row{id: post.PostID, createdAt: tie}
+		switch v := value.(type) {
+		case time.Time:
+			r.createdAt = v
+		case string:
+			r.createdAt, _ = time.Parse("2006-01-02 15:04:05", v)
+		}
+		if likes[i] {
+			if _, err := prr.TogglePostReaction(user.ID, post.PostID, models.ReactionTypeLike); err != nil {
+				t.Fatal(err)
+			}
+			r.likes = 1
+		}
+		rows = append(rows, r)
+	}
+
+	// The order each sort must produce, with the post ID breaking ties
+	newest := func(a, b row) bool {
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		return a.id > b.id
+	}
+	tests := []struct {
+		sortBy string
+		less   func(a, b row) bool
+	}{
+		{"newest", newest},
+		{"oldest", func(a, b row) bool { return newest(b, a) }},
+		{"likes", func(a, b row) bool {
+			if a.likes != b.likes {
+				return a.likes > b.likes
+			}
+			return newest(a, b)
+		}},
+		{"comments", newest}, // no comments: every count ties
+	}
+	for _, tt := range tests {
+		sorted := append([]row(nil), rows...)
+		sort.Slice(sorted, func(i, j int) bool { return tt.less(sorted[i], sorted[j]) })
+		want := make([]string, len(sorted))
+		for i, r := range sorted {
+			want[i] = r.id
+		}
+
+		for _, limit := range []int{1, 2, 3, len(rows)} {
+			var got []string
+			var cursor *utils.Cursor
+			for page := 0; page <= len(rows); page++ {
+				posts, next, err := pr.GetAllPosts(limit, 0, cursor, user.ID, utils.SortOptions{SortBy: tt.sortBy})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, post := range posts {
+					got = append(got, post.ID)
+				}
+				if next == "" {
+					break
+				}
+				if cursor, err = utils.DecodeCursor(next); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("sort %s, %d per page: got %q, want %q", tt.sortBy, limit, got, want)
+			}
+		}
+	}
+}
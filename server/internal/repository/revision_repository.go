@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+	"real-time-forum/queries"
+)
+
+type RevisionRepository struct {
+	db *sql.DB
+}
+
+// NewRevisionRepository creates a new RevisionRepository
+func NewRevisionRepository(db *sql.DB) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// savePostRevision stores the content a post had before an edit (called inside UpdatePost)
+func savePostRevision(tx *sql.Tx, postID, oldContent, editorID string, editedAt time.Time) error {
+	_, err := tx.Exec(
+		"INSERT INTO post_revisions (revision_id, post_id, content, edited_by, edited_at) VALUES (?, ?, ?, ?, ?)",
+		utils.GenerateUUIDToken(), postID, oldContent, editorID, editedAt,
+	)
+	return err
+}
+
+// saveCommentRevision stores the content a comment had before an edit (called inside UpdateComment)
+func saveCommentRevision(tx *sql.Tx, commentID, oldContent, editorID string, editedAt time.Time) error {
+	_, err := tx.Exec(
+		"INSERT INTO comment_revisions (revision_id, comment_id, content, edited_by, edited_at) VALUES (?, ?, ?, ?, ?)",
+		utils.GenerateUUIDToken(), commentID, oldContent, editorID, editedAt,
+	)
+	return err
+}
+
+// GetPostRevisions returns every version of a post, oldest first, ending with the current content
+func (rr *RevisionRepository) GetPostRevisions(postID string) ([]models.Revision, error) {
+	current, err := rr.getCurrentVersion(`
+		SELECT p.content, p.user_id, u.username, p.created_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.user_id
+		WHERE p.post_id = ? AND p.deleted_at IS NULL AND `+queries.PublishedCondition, postID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("post not found")
+		}
+		return nil, err
+	}
+
+	return rr.buildRevisions(current, `
+		SELECT r.content, r.edited_by, u.username, r.edited_at
+		FROM post_revisions r
+		JOIN users u ON r.edited_by = u.user_id
+		WHERE r.post_id = ?
+		ORDER BY r.edited_at ASC, r.rowid ASC`, postID)
+}
+
+// GetCommentRevisions returns every version of a comment, oldest first, ending with the current content
+func (rr *RevisionRepository) GetCommentRevisions(commentID string) ([]models.Revision, error) {
+	current, err := rr.getCurrentVersion(`
+		SELECT c.content, c.user_id, u.username, c.created_at
+		FROM comments c
+		JOIN users u ON c.user_id = u.user_id
+		WHERE c.comment_id = ? AND c.deleted_at IS NULL`, commentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("comment not found")
+		}
+		return nil, err
+	}
+
+	return rr.buildRevisions(current, `
+		SELECT r.content, r.edited_by, u.username, r.edited_at
+		FROM comment_revisions r
+		JOIN users u ON r.edited_by = u.user_id
+		WHERE r.comment_id = ?
+		ORDER BY r.edited_at ASC, r.rowid ASC`, commentID)
+}
+
+// getCurrentVersion loads the live content together with the author and creation time
+func (rr *RevisionRepository) getCurrentVersion(query, id string) (models.Revision, error) {
+	var rev models.Revision
+	err := rr.db.QueryRow(query, id).Scan(&rev.Content, &rev.EditorID, &rev.EditorUsername, &rev.CreatedAt)
+	return rev, err
+}
+
+// buildRevisions turns the stored edits into a list of versions.
+// Each stored row holds the content replaced by an edit, so version N's content is row N
+// (or the live content for the last version), while its editor and time come from row N-1.
+func (rr *RevisionRepository) buildRevisions(current models.Revision, query, id string) ([]models.Revision, error) {
+	rows, err := rr.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []models.Revision
+	for rows.Next() {
+		var edit models.Revision
+		if err := rows.Scan(&edit.Content, &edit.EditorID, &edit.EditorUsername, &edit.CreatedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	revisions := make([]models.Revision, 0, len(edits)+1)
+	for i := 0; i <= len(edits); i++ {
+		rev := models.Revision{Version: i + 1}
+		if i == 0 {
+			// The first version is authored by the owner at creation time
+			rev.EditorID, rev.EditorUsername, rev.CreatedAt = current.EditorID, current.EditorUsername, current.CreatedAt
+		} else {
+			prev := edits[i-1]
+			rev.EditorID, rev.EditorUsername, rev.CreatedAt = prev.EditorID, prev.EditorUsername, prev.CreatedAt
+		}
+
+		if i < len(edits) {
+			rev.Content = edits[i].Content
+		} else {
+			rev.Content = current.Content
+			rev.IsCurrent = true
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, nil
+}
@@ -35,6 +35,12 @@ func (r *SessionRepository) CreateSession(userID, ipAddress string) (*models.Ses
 			return nil, err
 		}
 
+		// Synchronizer token for state-changing requests of this session
+		csrfToken, err := utils.GenerateSecureToken()
+		if err != nil {
+			return nil, err
+		}
+
 		expiresAt := utils.CalculateSessionExpiry()
 		now := time.Now()
 
@@ -43,8 +49,8 @@ func (r *SessionRepository) CreateSession(userID, ipAddress string) (*models.Ses
 
 		// Insert the new session with clean IP
 		_, err = tx.Exec(
-			"INSERT INTO sessions (user_id, session_id, ip_address, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
-			userID, sessionID, cleanIP, now, expiresAt, // ← Use cleanIP here
+			"INSERT INTO sessions (user_id, session_id, ip_address, csrf_token, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			userID, sessionID, cleanIP, csrfToken, now, expiresAt, // ← Use cleanIP here
 		)
 		if err != nil {
 			return nil, err
@@ -55,6 +61,7 @@ func (r *SessionRepository) CreateSession(userID, ipAddress string) (*models.Ses
 			UserID:    userID,
 			SessionID: sessionID,
 			IPAddress: cleanIP, // ← Use cleanIP here too
+			CSRFToken: csrfToken,
 			CreatedAt: now,
 			ExpiresAt: expiresAt,
 		}
@@ -78,9 +85,9 @@ func (sr *SessionRepository) GetBySessionID(sessionID string) (*models.Session,
 	var session models.Session
 
 	err := sr.DB.QueryRow(
-		"SELECT user_id, session_id, ip_address, created_at, expires_at FROM sessions WHERE session_id = ?",
+		"SELECT user_id, session_id, ip_address, csrf_token, created_at, expires_at FROM sessions WHERE session_id = ?",
 		sessionID,
-	).Scan(&session.UserID, &session.SessionID, &session.IPAddress, &session.CreatedAt, &session.ExpiresAt)
+	).Scan(&session.UserID, &session.SessionID, &session.IPAddress, &session.CSRFToken, &session.CreatedAt, &session.ExpiresAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("session not found")
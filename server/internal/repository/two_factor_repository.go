@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/totp"
+	"real-time-forum/internal/utils"
+)
+
+// totpSkew accepts codes from one time step before and after the current one (clock drift)
+const totpSkew = 1
+
+// TwoFactorRepository handles TOTP secrets, recovery codes and logins waiting for a second factor
+type TwoFactorRepository struct {
+	DB *sql.DB
+}
+
+// NewTwoFactorRepository creates a new TwoFactorRepository
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{DB: db}
+}
+
+// BeginEnrollment stores a new unconfirmed TOTP secret for the user, replacing an unfinished setup
+func (tr *TwoFactorRepository) BeginEnrollment(userID string) (string, error) {
+	enabled, err := tr.IsEnabled(userID)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", errors.New("two-factor already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tr.DB.Exec(
+		"INSERT OR REPLACE INTO user_totp (user_id, secret, confirmed_at, last_used_step, created_at) VALUES (?, ?, NULL, 0, ?)",
+		userID, secret, time.Now(),
+	)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves their app produces
+// valid codes, and returns the first set of recovery codes
+func (tr *TwoFactorRepository) ConfirmEnrollment(userID, code string) ([]string, error) {
+	return utils.ExecuteInTransactionWithResult(tr.DB, func(tx *sql.Tx) ([]string, error) {
+		var secret string
+		err := tx.QueryRow("SELECT secret FROM user_totp WHERE user_id = ? AND confirmed_at IS NULL", userID).Scan(&secret)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("two-factor setup not started")
+			}
+			return nil, err
+		}
+
+		step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+		if !ok {
+			return nil, errors.New("invalid code")
+		}
+
+		_, err = tx.Exec("UPDATE user_totp SET confirmed_at = ?, last_used_step = ? WHERE user_id = ?", time.Now(), step, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return replaceRecoveryCodes(tx, userID)
+	})
+}
+
+// IsEnabled reports whether the user has a confirmed TOTP secret
+func (tr *TwoFactorRepository) IsEnabled(userID string) (bool, error) {
+	var enabled bool
+	err := tr.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM user_totp WHERE user_id = ? AND confirmed_at IS NOT NULL)", userID,
+	).Scan(&enabled)
+	return enabled, err
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (tr *TwoFactorRepository) CountRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := tr.DB.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count)
+	return count, err
+}
+
+// VerifyCode accepts a current TOTP code or an unused recovery code. TOTP codes cannot be
+// reused (each time step is accepted once) and recovery codes are marked used.
+func (tr *TwoFactorRepository) VerifyCode(userID, code string) error {
+	return utils.ExecuteInTransaction(tr.DB, func(tx *sql.Tx) error {
+		var secret string
+		var lastUsedStep int64
+		err := tx.QueryRow(
+			"SELECT secret, last_used_step FROM user_totp WHERE user_id = ? AND confirmed_at IS NOT NULL", userID,
+		).Scan(&secret, &lastUsedStep)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("two-factor not enabled")
+			}
+			return err
+		}
+
+		if step, ok := totp.Validate(secret, code, time.Now(), totpSkew); ok {
+			if step <= lastUsedStep {
+				return errors.New("invalid code")
+			}
+			_, err = tx.Exec("UPDATE user_totp SET last_used_step = ? WHERE user_id = ?", step, userID)
+			return err
+		}
+
+		result, err := tx.Exec(
+			"UPDATE recovery_codes SET used_at = ? WHERE code_hash = ? AND user_id = ? AND used_at IS NULL",
+			time.Now(), utils.HashToken(utils.NormalizeRecoveryCode(code)), userID,
+		)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("invalid code")
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes invalidates all recovery codes of the user and issues new ones
+func (tr *TwoFactorRepository) RegenerateRecoveryCodes(userID string) ([]string, error) {
+	return utils.ExecuteInTransactionWithResult(tr.DB, func(tx *sql.Tx) ([]string, error) {
+		return replaceRecoveryCodes(tx, userID)
+	})
+}
+
+// Disable removes the TOTP secret, the recovery codes and any pending logins of the user
+func (tr *TwoFactorRepository) Disable(userID string) error {
+	return utils.ExecuteInTransaction(tr.DB, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			"DELETE FROM user_totp WHERE user_id = ?",
+			"DELETE FROM recovery_codes WHERE user_id = ?",
+			"DELETE FROM pending_logins WHERE user_id = ?",
+		} {
+			if _, err := tx.Exec(query, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores the hashes of new ones
+func replaceRecoveryCodes(tx *sql.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, config.Config.TwoFactorRecoveryCodes)
+	for i := 0; i < config.Config.TwoFactorRecoveryCodes; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO recovery_codes (code_hash, user_id, created_at) VALUES (?, ?, ?)",
+			utils.HashToken(utils.NormalizeRecoveryCode(code)), userID, time.Now(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// CreatePendingLogin records a login that passed the password check and returns the token
+// the client exchanges for a session together with a code. Expired entries are cleaned up here.
+func (tr *TwoFactorRepository) CreatePendingLogin(userID string) (string, time.Time, error) {
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(config.Config.TwoFactorLoginTTL)
+
+	err = utils.ExecuteInTransaction(tr.DB, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM pending_logins WHERE julianday(expires_at) < julianday(?)", now); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"INSERT INTO pending_logins (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+			utils.HashToken(token), userID, now, expiresAt,
+		)
+		return err
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ReservePendingLoginAttempt counts one code attempt against a pending login and returns its
+// user. Counting happens before the code is checked, in a single statement, so concurrent requests
+// cannot try more than TwoFactorMaxAttempts codes between them. Expired or used up pending logins fail.
+func (tr *TwoFactorRepository) ReservePendingLoginAttempt(token string) (string, error) {
+	tokenHash := utils.HashToken(token)
+	result, err := tr.DB.Exec(
+		"UPDATE pending_logins SET attempts = attempts + 1 WHERE token_hash = ? AND attempts < ? AND julianday(expires_at) > julianday(?)",
+		tokenHash, config.Config.TwoFactorMaxAttempts, time.Now(),
+	)
+	if err != nil {
+		return "", err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if rowsAffected == 0 {
+		return "", errors.New("invalid or expired token")
+	}
+
+	var userID string
+	err = tr.DB.QueryRow("SELECT user_id FROM pending_logins WHERE token_hash = ?", tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Used by a concurrent request that got the code right
+			return "", errors.New("invalid or expired token")
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// ConsumePendingLogin deletes a pending login after the second factor was accepted. It fails
+// if the token was already used, so a pending login can only ever produce one session.
+func (tr *TwoFactorRepository) ConsumePendingLogin(token string) error {
+	result, err := tr.DB.Exec("DELETE FROM pending_logins WHERE token_hash = ?", utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("invalid or expired token")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/totp"
+)
+
+func TestVerifyCodeRejectsReuse(t *testing.T) {
+	if err := config.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.DBPath = filepath.Join(t.TempDir(), "forum.db")
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	user, err := NewUserRepository(db).CreateUser(models.UserRegistration{
+		Username: "alice", Age: 30, Gender: "Other", FirstName: "Alice", LastName: "Liddell",
+		Email: "alice@example.com", Password: "Secret1!pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tfr := NewTwoFactorRepository(db)
+	secret, err := tfr.BeginEnrollment(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codeAt := func(step int64) string {
+		code, err := totp.CodeAt(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	// Confirming the setup uses up the current step
+	step := totp.Step(time.Now())
+	recoveryCodes, err := tfr.ConfirmEnrollment(user.ID, codeAt(step))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tfr.VerifyCode(user.ID, codeAt(step)); err == nil {
+		t.Error("the code used to confirm the setup was accepted again")
+	}
+
+	// The next step is within the skew: accepted once, then neither it nor older steps are
+	if err := tfr.VerifyCode(user.ID, codeAt(step+1)); err != nil {
+		t.Fatalf("code of the next step: %v", err)
+	}
+	if err := tfr.VerifyCode(user.ID, codeAt(step+1)); err == nil {
+		t.Error("a code was accepted twice")
+	}
+	if err := tfr.VerifyCode(user.ID, codeAt(step-1)); err == nil {
+		t.Error("a code older than the last used one was accepted")
+	}
+
+	// Outside the skew window
+	if err := tfr.VerifyCode(user.ID, codeAt(step+3)); err == nil {
+		t.Error("a code three steps ahead was accepted")
+	}
+
+	// Recovery codes work once
+	if err := tfr.VerifyCode(user.ID, recoveryCodes[0]); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if err := tfr.VerifyCode(user.ID, recoveryCodes[0]); err == nil {
+		t.Error("a recovery code was accepted twice")
+	}
+}
@@ -3,10 +3,12 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"real-time-forum/internal/models"
 	"real-time-forum/internal/utils"
+	"real-time-forum/queries"
 )
 
 // UserRepository handles user-related database operations
@@ -44,10 +46,13 @@ func (ur *UserRepository) CreateUser(reg models.UserRegistration) (*models.User,
 		userID := utils.GenerateUUIDToken()
 		createdAt := time.Now()
 
-		// Hash the password
-		hashedPassword, err := utils.HashPassword(reg.Password)
-		if err != nil {
-			return nil, err
+		// Hash the password; OAuth sign-ups have none until they set one
+		hashedPassword := ""
+		if reg.Password != "" {
+			hashedPassword, err = utils.HashPassword(reg.Password)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// Insert user record
@@ -78,9 +83,143 @@ func (ur *UserRepository) GetUserBySessionID(id string) (*models.User, error) {
 	var user models.User
 
 	err := ur.DB.QueryRow(
-		"SELECT user_id, username, email, created_at FROM users WHERE user_id = ?",
+		`SELECT user_id, username, email, email_verified_at IS NOT NULL,
+			EXISTS(SELECT 1 FROM user_totp t WHERE t.user_id = users.user_id AND t.confirmed_at IS NOT NULL),
+			role, avatar_url, created_at
+		FROM users WHERE user_id = ?`,
 		id,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.TwoFactorEnabled, &user.Role, &user.AvatarURL, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+
+}
+
+// SetUserRole changes the role of the user with the given username
+func (ur *UserRepository) SetUserRole(username, role string) error {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return errors.New("invalid role")
+	}
+
+	result, err := ur.DB.Exec("UPDATE users SET role = ? WHERE username = ?", role, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// UsernameExists reports whether any account, including closed ones, uses the username
+func (ur *UserRepository) UsernameExists(username string) (bool, error) {
+	var count int
+	err := ur.DB.QueryRow("SELECT COUNT(*) FROM users WHERE LOWER(username) = LOWER(?)", username).Scan(&count)
+	return count > 0, err
+}
+
+// HasPassword reports whether the user can sign in with a password. OAuth sign-ups from before
+// passwordless accounts existed hold the hash of an empty password, which does not count.
+func (ur *UserRepository) HasPassword(userID string) (bool, error) {
+	auth, err := ur.GetAuthByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+	return auth.PasswordHash != "" && !utils.CheckPasswordHash("", auth.PasswordHash), nil
+}
+
+// GetUsersByUsernames returns the active accounts among usernames (case-insensitive), e.g. to
+// resolve @mentions; names without an account are left out
+func (ur *UserRepository) GetUsersByUsernames(usernames []string) ([]models.UserSearchResult, error) {
+	users := []models.UserSearchResult{}
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	args := make([]interface{}, len(usernames))
+	for i, username := range usernames {
+		args[i] = strings.ToLower(username)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(usernames)), ",")
+
+	rows, err := ur.DB.Query(
+		"SELECT user_id, username, avatar_url FROM users WHERE LOWER(username) IN ("+placeholders+") AND deleted_at IS NULL",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.UserSearchResult
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// SearchUsersByPrefix returns active users whose username starts with prefix (case-insensitive),
+// alphabetically. Usernames only hold [a-zA-Z0-9_], so the match is split into one range per case
+// of the first character; both ranges are answered from the UNIQUE username index instead of
+// scanning every user.
+func (ur *UserRepository) SearchUsersByPrefix(prefix string, limit int) ([]models.UserSearchResult, error) {
+	users := []models.UserSearchResult{}
+	if prefix == "" {
+		return users, nil
+	}
+
+	lower := strings.ToLower(prefix[:1])
+	upper := strings.ToUpper(prefix[:1])
+
+	rows, err := ur.DB.Query(`
+		SELECT user_id, username, avatar_url FROM users
+		WHERE ((username >= ? AND username < ?) OR (username >= ? AND username < ?))
+			AND substr(LOWER(username), 1, ?) = LOWER(?)
+			AND deleted_at IS NULL
+		ORDER BY LOWER(username)
+		LIMIT ?`,
+		lower, nextChar(lower), upper, nextChar(upper), len(prefix), prefix, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.UserSearchResult
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// nextChar returns the single-byte string right after c, the exclusive end of a prefix range
+func nextChar(c string) string {
+	return string([]byte{c[0] + 1})
+}
+
+// GetUserByID retrieves all account fields of a user
+func (ur *UserRepository) GetUserByID(userID string) (*models.User, error) {
+	var user models.User
+
+	err := ur.DB.QueryRow(
+		"SELECT user_id, username, age, gender, first_name, last_name, email, email_verified_at IS NOT NULL, role, avatar_url, bio, location, created_at FROM users WHERE user_id = ? AND deleted_at IS NULL",
+		userID,
+	).Scan(&user.ID, &user.Username, &user.Age, &user.Gender, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerified, &user.Role, &user.AvatarURL, &user.Bio, &user.Location, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
@@ -89,7 +228,108 @@ func (ur *UserRepository) GetUserBySessionID(id string) (*models.User, error) {
 	}
 
 	return &user, nil
+}
 
+// UpdateUser saves the editable profile fields of user.
+// A new email address is unverified until the user confirms it again.
+func (ur *UserRepository) UpdateUser(user *models.User) error {
+	return utils.ExecuteInTransaction(ur.DB, func(tx *sql.Tx) error {
+		var emailCount int
+		err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE LOWER(email) = LOWER(?) AND user_id != ?", user.Email, user.ID).Scan(&emailCount)
+		if err != nil {
+			return err
+		}
+		if emailCount > 0 {
+			return errors.New("email already taken")
+		}
+
+		_, err = tx.Exec(`
+			UPDATE users SET
+				first_name = ?, last_name = ?, age = ?, gender = ?, bio = ?, location = ?,
+				email_verified_at = CASE WHEN LOWER(email) = LOWER(?) THEN email_verified_at ELSE NULL END,
+				email = ?
+			WHERE user_id = ? AND deleted_at IS NULL`,
+			user.FirstName, user.LastName, user.Age, user.Gender, user.Bio, user.Location, user.Email, user.Email, user.ID,
+		)
+		return err
+	})
+}
+
+// SetAvatar replaces the user's avatar ("" removes it) and returns the previous avatar URL
+// so the caller can delete the old file
+func (ur *UserRepository) SetAvatar(userID, avatarURL string) (string, error) {
+	return utils.ExecuteInTransactionWithResult(ur.DB, func(tx *sql.Tx) (string, error) {
+		var previous string
+		err := tx.QueryRow("SELECT avatar_url FROM users WHERE user_id = ? AND deleted_at IS NULL", userID).Scan(&previous)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return "", errors.New("user not found")
+			}
+			return "", err
+		}
+
+		_, err = tx.Exec("UPDATE users SET avatar_url = ? WHERE user_id = ?", avatarURL, userID)
+		if err != nil {
+			return "", err
+		}
+		return previous, nil
+	})
+}
+
+// UpdatePassword sets a new password and revokes every session except keepSessionID
+// (pass "" to sign the user out everywhere, e.g. after a password reset). Access tokens and
+// logins waiting for their second factor go too: they may have been created by whoever the
+// password change is meant to lock out.
+func (ur *UserRepository) UpdatePassword(userID, newPassword, keepSessionID string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return utils.ExecuteInTransaction(ur.DB, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE users SET password_hash = ? WHERE user_id = ? AND deleted_at IS NULL", hashedPassword, userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("user not found")
+		}
+
+		_, err = tx.Exec("DELETE FROM sessions WHERE user_id = ? AND session_id != ?", userID, keepSessionID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM api_tokens WHERE user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM pending_logins WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+// MarkEmailVerified records that the user proved ownership of email.
+// It fails if the account's address changed after the verification link was sent.
+func (ur *UserRepository) MarkEmailVerified(userID, email string) error {
+	result, err := ur.DB.Exec(
+		"UPDATE users SET email_verified_at = ? WHERE user_id = ? AND LOWER(email) = LOWER(?) AND deleted_at IS NULL",
+		time.Now(), userID, email,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("email changed")
+	}
+	return nil
 }
 
 // GetAuthByUserID retrieves user authentication data by user ID
@@ -116,9 +356,9 @@ func (ur *UserRepository) GetUserByEmailOrUsername(identifier string) (*models.U
 	var user models.User
 
 	err := ur.DB.QueryRow(
-		"SELECT user_id, username, age, gender, first_name, last_name, email, created_at FROM users WHERE LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)",
+		"SELECT user_id, username, age, gender, first_name, last_name, email, avatar_url, created_at FROM users WHERE (LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)) AND deleted_at IS NULL",
 		identifier, identifier,
-	).Scan(&user.ID, &user.Username, &user.Age, &user.Gender, &user.FirstName, &user.LastName, &user.Email, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.Age, &user.Gender, &user.FirstName, &user.LastName, &user.Email, &user.AvatarURL, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
@@ -157,9 +397,9 @@ func (ur *UserRepository) GetCurrentUser(userID string) (*models.User, error) {
 	var user models.User
 
 	err := ur.DB.QueryRow(
-		"SELECT user_id, username, email, created_at FROM users WHERE user_id = ?",
+		"SELECT user_id, username, email, avatar_url, bio, location, created_at FROM users WHERE user_id = ?",
 		userID,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.AvatarURL, &user.Bio, &user.Location, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
@@ -189,6 +429,9 @@ func (ur *UserRepository) GetUserProfile(userID string) (*models.UserProfile, er
 		ID:        user.ID,
 		Username:  user.Username,
 		Email:     user.Email,
+		AvatarURL: user.AvatarURL,
+		Bio:       user.Bio,
+		Location:  user.Location,
 		CreatedAt: user.CreatedAt,
 		Stats:     *stats,
 	}
@@ -201,13 +444,13 @@ func (ur *UserRepository) GetProfileStats(userID string) (*models.ProfileStats,
 	stats := &models.ProfileStats{}
 
 	// 1. Count total posts by user
-	err := ur.DB.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ?", userID).Scan(&stats.TotalPosts)
+	err := ur.DB.QueryRow("SELECT COUNT(*) FROM posts p WHERE p.user_id = ? AND p.deleted_at IS NULL AND "+queries.PublishedCondition, userID).Scan(&stats.TotalPosts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. Count total comments by user
-	err = ur.DB.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ?", userID).Scan(&stats.TotalComments)
+	err = ur.DB.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ? AND deleted_at IS NULL", userID).Scan(&stats.TotalComments)
 	if err != nil {
 		return nil, err
 	}
@@ -215,9 +458,9 @@ func (ur *UserRepository) GetProfileStats(userID string) (*models.ProfileStats,
 	// 3. Count posts this user has liked - FIXED: use post_reactions table
 	err = ur.DB.QueryRow(`
 		SELECT COUNT(*) 
-		FROM post_reactions 
-		WHERE user_id = ? AND reaction_type = 1
-	`, userID).Scan(&stats.PostsLiked)
+		FROM post_reactions r
+		JOIN posts p ON r.post_id = p.post_id
+		WHERE r.user_id = ? AND r.reaction_type = 1 AND p.deleted_at IS NULL AND `+queries.PublishedCondition, userID).Scan(&stats.PostsLiked)
 	if err != nil {
 		return nil, err
 	}
@@ -227,8 +470,7 @@ func (ur *UserRepository) GetProfileStats(userID string) (*models.ProfileStats,
 		SELECT COUNT(DISTINCT p.post_id) 
 		FROM posts p
 		JOIN comments c ON p.post_id = c.post_id
-		WHERE c.user_id = ?
-	`, userID).Scan(&stats.PostsCommentedOn)
+		WHERE c.user_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL AND `+queries.PublishedCondition, userID).Scan(&stats.PostsCommentedOn)
 	if err != nil {
 		return nil, err
 	}
@@ -238,11 +480,11 @@ func (ur *UserRepository) GetProfileStats(userID string) (*models.ProfileStats,
 		SELECT (
 			SELECT COALESCE(COUNT(*), 0) FROM post_reactions pr 
 			JOIN posts p ON pr.post_id = p.post_id 
-			WHERE pr.reaction_type = 1 AND p.user_id = ?
+			WHERE pr.reaction_type = 1 AND p.user_id = ? AND p.deleted_at IS NULL
 		) + (
 			SELECT COALESCE(COUNT(*), 0) FROM comment_reactions cr 
 			JOIN comments c ON cr.comment_id = c.comment_id 
-			WHERE cr.reaction_type = 1 AND c.user_id = ?
+			WHERE cr.reaction_type = 1 AND c.user_id = ? AND c.deleted_at IS NULL
 		)
 	`, userID, userID).Scan(&stats.LikesReceived)
 	if err != nil {
@@ -254,11 +496,11 @@ func (ur *UserRepository) GetProfileStats(userID string) (*models.ProfileStats,
 		SELECT (
 			SELECT COALESCE(COUNT(*), 0) FROM post_reactions pr 
 			JOIN posts p ON pr.post_id = p.post_id 
-			WHERE pr.reaction_type = 2 AND p.user_id = ?
+			WHERE pr.reaction_type = 2 AND p.user_id = ? AND p.deleted_at IS NULL
 		) + (
 			SELECT COALESCE(COUNT(*), 0) FROM comment_reactions cr 
 			JOIN comments c ON cr.comment_id = c.comment_id 
-			WHERE cr.reaction_type = 2 AND c.user_id = ?
+			WHERE cr.reaction_type = 2 AND c.user_id = ? AND c.deleted_at IS NULL
 		)
 	`, userID, userID).Scan(&stats.DislikesReceived)
 	if err != nil {
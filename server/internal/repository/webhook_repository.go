@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+// webhookSecretPrefix makes webhook secrets recognizable, like personal access tokens
+const webhookSecretPrefix = "whsec_"
+
+// WebhookRepository handles webhook endpoints and their delivery queue
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// CreateWebhook registers an endpoint with a new signing secret; the returned webhook includes the secret
+func (wr *WebhookRepository) CreateWebhook(url string, events []string, createdBy string) (*models.Webhook, error) {
+	secret, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := &models.Webhook{
+		ID:        utils.GenerateUUIDToken(),
+		URL:       url,
+		Events:    events,
+		Active:    true,
+		Secret:    webhookSecretPrefix + secret,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err = wr.DB.Exec(
+		"INSERT INTO webhooks (webhook_id, url, events, secret, active, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, 1, ?, ?, ?)",
+		webhook.ID, url, strings.Join(events, ","), webhook.Secret, createdBy, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// GetWebhooks lists all webhooks without their secrets
+func (wr *WebhookRepository) GetWebhooks() ([]models.Webhook, error) {
+	rows, err := wr.DB.Query("SELECT webhook_id, url, events, active, created_by, created_at, updated_at FROM webhooks ORDER BY created_at ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhookByID returns a webhook without its secret
+func (wr *WebhookRepository) GetWebhookByID(webhookID string) (*models.Webhook, error) {
+	webhook, err := scanWebhook(wr.DB.QueryRow(
+		"SELECT webhook_id, url, events, active, created_by, created_at, updated_at FROM webhooks WHERE webhook_id = ?",
+		webhookID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook saves the URL, events and active flag of a webhook
+func (wr *WebhookRepository) UpdateWebhook(webhook *models.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+	result, err := wr.DB.Exec(
+		"UPDATE webhooks SET url = ?, events = ?, active = ?, updated_at = ? WHERE webhook_id = ?",
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.Active, webhook.UpdatedAt, webhook.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, "webhook not found")
+}
+
+// DeleteWebhook removes a webhook together with its queued and logged deliveries
+func (wr *WebhookRepository) DeleteWebhook(webhookID string) error {
+	result, err := wr.DB.Exec("DELETE FROM webhooks WHERE webhook_id = ?", webhookID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, "webhook not found")
+}
+
+// Enqueue queues a delivery of event for every active webhook subscribed to it
+func (wr *WebhookRepository) Enqueue(event string, data interface{}) error {
+	webhooks, err := wr.GetWebhooks()
+	if err != nil {
+		return err
+	}
+
+	return utils.ExecuteInTransaction(wr.DB, func(tx *sql.Tx) error {
+		now := time.Now()
+		for _, webhook := range webhooks {
+			if !webhook.Active || !webhook.Subscribes(event) {
+				continue
+			}
+
+			deliveryID := utils.GenerateUUIDToken()
+			payload, err := json.Marshal(models.WebhookPayload{ID: deliveryID, Event: event, CreatedAt: now, Data: data})
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(
+				"INSERT INTO webhook_deliveries (delivery_id, webhook_id, event, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				deliveryID, webhook.ID, event, string(payload), models.WebhookDeliveryPending, now, now,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetDueDeliveries returns pending deliveries of active webhooks whose next attempt is due,
+// with the URL and secret needed to send them
+func (wr *WebhookRepository) GetDueDeliveries(limit int) ([]models.WebhookDelivery, error) {
+	rows, err := wr.DB.Query(`
+		SELECT d.delivery_id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.webhook_id = d.webhook_id
+		WHERE d.status = ? AND w.active = 1 AND julianday(d.next_attempt_at) <= julianday(?)
+		ORDER BY d.next_attempt_at ASC
+		LIMIT ?`,
+		models.WebhookDeliveryPending, time.Now(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Status = models.WebhookDeliveryPending
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt stores the outcome of a delivery attempt (status, attempts, next attempt and last response)
+func (wr *WebhookRepository) RecordAttempt(delivery *models.WebhookDelivery) error {
+	_, err := wr.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE delivery_id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode, delivery.LastError, delivery.DeliveredAt, delivery.ID,
+	)
+	return err
+}
+
+// CountDeliveries returns the number of logged deliveries of a webhook
+func (wr *WebhookRepository) CountDeliveries(webhookID string) (int, error) {
+	var count int
+	err := wr.DB.QueryRow("SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?", webhookID).Scan(&count)
+	return count, err
+}
+
+// GetDeliveries returns a page of a webhook's delivery log, newest first
+func (wr *WebhookRepository) GetDeliveries(webhookID string, limit, offset int) ([]models.WebhookDelivery, error) {
+	rows, err := wr.DB.Query(`
+		SELECT delivery_id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?`,
+		webhookID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var nextAttemptAt, deliveredAt sql.NullTime
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &nextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		if nextAttemptAt.Valid {
+			d.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver puts a logged delivery back in the queue with a fresh set of attempts. The payload
+// and delivery ID stay the same so receivers can recognize the event.
+func (wr *WebhookRepository) Redeliver(webhookID, deliveryID string) error {
+	result, err := wr.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = 0, next_attempt_at = ?, delivered_at = NULL
+		WHERE delivery_id = ? AND webhook_id = ?`,
+		models.WebhookDeliveryPending, time.Now(), deliveryID, webhookID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, "delivery not found")
+}
+
+// PruneDeliveries removes finished deliveries created before the cutoff
+func (wr *WebhookRepository) PruneDeliveries(before time.Time) (int64, error) {
+	result, err := wr.DB.Exec(
+		"DELETE FROM webhook_deliveries WHERE status != ? AND julianday(created_at) < julianday(?)",
+		models.WebhookDeliveryPending, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanWebhook reads a row selected as webhook_id, url, events, active, created_by, created_at, updated_at
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Active, &webhook.CreatedBy, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return &webhook, nil
+}
+
+// requireAffected turns an UPDATE or DELETE that matched no row into notFound
+func requireAffected(result sql.Result, notFound string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New(notFound)
+	}
+	return nil
+}
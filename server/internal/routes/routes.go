@@ -7,12 +7,64 @@ import (
 
 	"real-time-forum/config"
 	"real-time-forum/internal/handlers"
+	"real-time-forum/internal/jobs"
+	"real-time-forum/internal/mailer"
 	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/oauth"
+	"real-time-forum/internal/ratelimit"
 	"real-time-forum/internal/repository"
 	ws "real-time-forum/internal/websocket"
 )
 
-func SetupRoutes(db *sql.DB) http.Handler {
+// Route patterns with their own rate limit budget. They must match the patterns passed to
+// mux.Handle exactly, otherwise the route silently falls back to the default policy
+// (TestRateLimitedRoutesAreRegistered checks this).
+var (
+	authRateLimitedRoutes = []string{
+		"POST /api/auth/register",
+		"POST /api/auth/login",
+		"POST /api/auth/login/2fa",
+		"POST /api/auth/password/forgot",
+		"POST /api/auth/password/reset",
+		"POST /api/auth/email/verify",
+	}
+	contentRateLimitedRoutes = []string{
+		"POST /api/posts/create",
+		"POST /api/comments/create-on-post/{id}",
+	}
+	reactionRateLimitedRoutes = []string{
+		"POST /api/reactions/posts/toggle",
+		"POST /api/reactions/comments/toggle",
+		"POST /api/bookmarks/toggle",
+	}
+	messageRateLimitedRoutes = []string{
+		"POST /api/messages/send",
+	}
+)
+
+// Jobs are the background jobs of the API, started separately from serving requests so that
+// building the handler (e.g. in tests) does not run them
+type Jobs struct {
+	purger     *jobs.ContentPurger
+	backups    *jobs.BackupScheduler // nil when scheduled backups are off
+	dispatcher *jobs.WebhookDispatcher
+	unfurler   *jobs.LinkUnfurler
+	publisher  *jobs.PostPublisher
+}
+
+// Start runs every job in its own goroutine
+func (j *Jobs) Start() {
+	go j.purger.Run() // Purge soft-deleted content once its restore period is over
+	if j.backups != nil {
+		go j.backups.Run() // Scheduled backups with retention
+	}
+	go j.dispatcher.Run() // Send queued webhook deliveries and retry failed ones
+	go j.unfurler.Run()   // Fetch previews of links in new posts, comments and messages
+	go j.publisher.Run()  // Send webhooks and mention notifications for scheduled posts once they go live
+}
+
+// SetupRoutes builds the API handler and the background jobs behind it. The jobs are not started.
+func SetupRoutes(db *sql.DB) (http.Handler, *Jobs) {
 	mux := http.NewServeMux()
 
 	// ===== EXISTING REPOSITORIES =====
@@ -28,42 +80,104 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	NotificationRepo := repository.NewNotificationRepository(db)
 	MessageImageRepo := repository.NewMessageImageRepository(db)
 	MessageRepo := repository.NewMessageRepository(db, MessageImageRepo)
+	BookmarkRepo := repository.NewBookmarkRepository(db)
+	RevisionRepo := repository.NewRevisionRepository(db)
+	AccountRepo := repository.NewAccountRepository(db)
+	EmailTokenRepo := repository.NewEmailTokenRepository(db)
+	TwoFactorRepo := repository.NewTwoFactorRepository(db)
+	LoginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	AuditRepo := repository.NewAuditRepository(db)
+	APITokenRepo := repository.NewAPITokenRepository(db)
+	WebhookRepo := repository.NewWebhookRepository(db)
+	MentionRepo := repository.NewMentionRepository(db)
+	LinkPreviewRepo := repository.NewLinkPreviewRepository(db)
+
+	// ===== MAILER =====
+	Mailer := mailer.NewFromConfig()
 
 	// ===== WEBSOCKET HUB =====
 	hub := ws.NewHub()
 	go hub.Run() // Start the hub in a goroutine
 
+	// ===== BACKGROUND JOBS =====
+	// Built here because handlers queue work on them; cmd/main.go starts them with Jobs.Start
+	backgroundJobs := &Jobs{
+		purger:     jobs.NewContentPurger(PostRepo, CommentRepo),
+		dispatcher: jobs.NewWebhookDispatcher(WebhookRepo),
+		unfurler:   jobs.NewLinkUnfurler(LinkPreviewRepo, hub),
+		publisher:  jobs.NewPostPublisher(PostRepo, handlers.AnnounceScheduledPost(PostRepo, WebhookRepo, UserRepo, MentionRepo, NotificationRepo)),
+	}
+	if config.Config.BackupInterval > 0 {
+		backgroundJobs.backups = jobs.NewBackupScheduler(db)
+	}
+	unfurler := backgroundJobs.unfurler
+
 	// ===== EXISTING MIDDLEWARE =====
-	AuthMiddleware := middleware.NewMiddleware(UserRepo, SessionRepo)
-	RateLimiter := middleware.NewRateLimiter(
-		time.Duration(config.Config.RateLimitWindow)*time.Minute,
-		config.Config.RateLimitRequests,
-	)
+	AuthMiddleware := middleware.NewMiddleware(UserRepo, SessionRepo, APITokenRepo)
+	RateLimiter := middleware.NewRateLimiter(ratelimit.Policy{
+		Requests: config.Config.RateLimitRequests,
+		Window:   time.Duration(config.Config.RateLimitWindow) * time.Minute,
+	})
 
 	// ===== OAUTH HANDLER =====
-	OAuthHandler := handlers.NewOAuthHandler(OAuthRepo, UserRepo, SessionRepo, &config.Config)
+	OAuthProviders := oauth.NewRegistry(config.Config.OAuthProviders)
+	OAuthHandler := handlers.NewOAuthHandler(OAuthProviders, OAuthRepo, UserRepo, SessionRepo, TwoFactorRepo, WebhookRepo, &config.Config)
 
 	// ===== EXISTING AUTH ROUTES =====
-	mux.Handle("POST /api/auth/register", http.HandlerFunc(handlers.RegisterHandler(UserRepo)))
-	mux.Handle("POST /api/auth/login", http.HandlerFunc(handlers.LoginHandler(UserRepo, SessionRepo)))
+	mux.Handle("POST /api/auth/register", http.HandlerFunc(handlers.RegisterHandler(UserRepo, EmailTokenRepo, Mailer, WebhookRepo)))
+	mux.Handle("POST /api/auth/login", http.HandlerFunc(handlers.LoginHandler(UserRepo, SessionRepo, TwoFactorRepo, LoginAttemptRepo)))
+	mux.Handle("POST /api/auth/login/2fa", http.HandlerFunc(handlers.TwoFactorLoginHandler(UserRepo, SessionRepo, TwoFactorRepo, LoginAttemptRepo)))
 	mux.Handle("POST /api/auth/logout", AuthMiddleware.RequireAuth(handlers.LogoutHandler(UserRepo, SessionRepo)))
 	mux.Handle("POST /api/auth/me", AuthMiddleware.RequireAuth(handlers.GetCurrentUser()))
 
+	// Password reset and email verification
+	mux.Handle("POST /api/auth/password/forgot", http.HandlerFunc(handlers.ForgotPasswordHandler(UserRepo, EmailTokenRepo, Mailer)))
+	mux.Handle("POST /api/auth/password/reset", http.HandlerFunc(handlers.ResetPasswordHandler(UserRepo, EmailTokenRepo)))
+	mux.Handle("POST /api/auth/email/send-verification", AuthMiddleware.RequireAuth(handlers.SendVerificationEmailHandler(EmailTokenRepo, Mailer)))
+	mux.Handle("POST /api/auth/email/verify", http.HandlerFunc(handlers.VerifyEmailHandler(UserRepo, EmailTokenRepo)))
+
+	// Two-factor authentication (TOTP)
+	mux.Handle("GET /api/auth/2fa", AuthMiddleware.RequireAuth(handlers.GetTwoFactorStatusHandler(TwoFactorRepo)))
+	mux.Handle("POST /api/auth/2fa/setup", AuthMiddleware.RequireAuth(handlers.SetupTwoFactorHandler(UserRepo, TwoFactorRepo)))
+	mux.Handle("POST /api/auth/2fa/confirm", AuthMiddleware.RequireAuth(handlers.ConfirmTwoFactorHandler(TwoFactorRepo)))
+	mux.Handle("POST /api/auth/2fa/disable", AuthMiddleware.RequireAuth(handlers.DisableTwoFactorHandler(UserRepo, TwoFactorRepo)))
+	mux.Handle("POST /api/auth/2fa/recovery-codes", AuthMiddleware.RequireAuth(handlers.RegenerateRecoveryCodesHandler(TwoFactorRepo)))
+
 	// ===== SIMPLIFIED OAUTH ROUTES (WEB ONLY) =====
-	// GitHub OAuth initiation - SINGLE endpoint
-	mux.Handle("GET /api/auth/github/login", http.HandlerFunc(OAuthHandler.ServeGitHubLogin))
-	// GitHub OAuth callback - SINGLE endpoint
-	mux.Handle("GET /api/auth/github/callback", http.HandlerFunc(OAuthHandler.ServeGitHubCallback))
-	// Google OAuth initiation - SINGLE endpoint
-	mux.Handle("GET /api/auth/google/login", http.HandlerFunc(OAuthHandler.ServeGoogleLogin))
-	// Google OAuth callback - SINGLE endpoint
-	mux.Handle("GET /api/auth/google/callback", http.HandlerFunc(OAuthHandler.ServeGoogleCallback))
+	// Configured providers (OAUTH_PROVIDERS) for the login page
+	mux.Handle("GET /api/auth/providers", http.HandlerFunc(OAuthHandler.ServeProviders))
+	// OAuth initiation, e.g. /api/auth/github/login
+	mux.Handle("GET /api/auth/{provider}/login", http.HandlerFunc(OAuthHandler.ServeLogin))
+	// OAuth callback, e.g. /api/auth/github/callback
+	mux.Handle("GET /api/auth/{provider}/callback", http.HandlerFunc(OAuthHandler.ServeCallback))
 	// =================
 	// ===== EXISTING USER PROFILE ROUTES =====
+	mux.Handle("GET /api/users/search", AuthMiddleware.RequireAuth(handlers.SearchUsersHandler(UserRepo)))
 	mux.Handle("GET /api/users/profile/{id}", AuthMiddleware.RequireAuth(handlers.GetUserProfileHandler(UserRepo)))
 	mux.Handle("GET /api/users/posts/{id}", AuthMiddleware.RequireAuth(handlers.GetUserPostsProfileHandler(PostRepo)))
 	mux.Handle("GET /api/users/liked-posts/{id}", AuthMiddleware.RequireAuth(handlers.GetUserLikedPostsProfileHandler(PostRepo)))
 	mux.Handle("GET /api/users/commented-posts/{id}", AuthMiddleware.RequireAuth(handlers.GetUserCommentedPostsProfileHandler(PostRepo)))
+	mux.Handle("GET /api/users/bookmarks", AuthMiddleware.RequireAuth(handlers.GetUserBookmarksHandler(PostRepo)))
+	mux.Handle("GET /api/users/drafts", AuthMiddleware.RequireAuth(handlers.GetUserDraftsHandler(PostRepo)))
+
+	// ===== ACCOUNT ROUTES =====
+	mux.Handle("GET /api/users/me/export", AuthMiddleware.RequireAuth(handlers.ExportUserDataHandler(AccountRepo)))
+	mux.Handle("PUT /api/users/me", AuthMiddleware.RequireAuth(handlers.UpdateCurrentUserHandler(UserRepo, EmailTokenRepo, Mailer)))
+	mux.Handle("PUT /api/users/me/password", AuthMiddleware.RequireAuth(handlers.ChangePasswordHandler(UserRepo)))
+	mux.Handle("POST /api/users/me/password", AuthMiddleware.RequireAuth(handlers.SetPasswordHandler(UserRepo)))
+	mux.Handle("PUT /api/users/me/avatar", AuthMiddleware.RequireAuth(handlers.UploadAvatarHandler(UserRepo)))
+	mux.Handle("DELETE /api/users/me/avatar", AuthMiddleware.RequireAuth(handlers.DeleteAvatarHandler(UserRepo)))
+	mux.Handle("DELETE /api/users/me", AuthMiddleware.RequireAuth(handlers.DeleteAccountHandler(UserRepo, AccountRepo, hub)))
+
+	// Personal access tokens for scripts and bots ("Authorization: Bearer"); session login only
+	mux.Handle("GET /api/users/me/tokens", AuthMiddleware.RequireAuth(handlers.ListAPITokensHandler(APITokenRepo)))
+	mux.Handle("POST /api/users/me/tokens", AuthMiddleware.RequireAuth(handlers.CreateAPITokenHandler(APITokenRepo)))
+	mux.Handle("DELETE /api/users/me/tokens/{id}", AuthMiddleware.RequireAuth(handlers.RevokeAPITokenHandler(APITokenRepo)))
+
+	// Connected accounts: linked OAuth providers; linking continues at /api/auth/{provider}/callback
+	mux.Handle("GET /api/users/me/connected-accounts", AuthMiddleware.RequireAuth(http.HandlerFunc(OAuthHandler.ServeConnectedAccounts)))
+	mux.Handle("POST /api/users/me/connected-accounts/{provider}", AuthMiddleware.RequireAuth(http.HandlerFunc(OAuthHandler.ServeLinkStart)))
+	mux.Handle("DELETE /api/users/me/connected-accounts/{provider}", AuthMiddleware.RequireAuth(http.HandlerFunc(OAuthHandler.ServeUnlink)))
 
 	// ===== EXISTING POST ROUTES =====
 	// Private GET routes
@@ -72,11 +186,16 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	mux.Handle("GET /api/posts/by-category/{id}", AuthMiddleware.RequireAuth(http.HandlerFunc(handlers.GetPostsByCategoryHandler(PostRepo))))
 
 	// Protected POST routes (create only)
-	mux.Handle("POST /api/posts/create", AuthMiddleware.RequireAuth(handlers.CreatePostHandler(PostRepo, CategoryRepo, PostImageRepo)))
+	mux.Handle("POST /api/posts/create", AuthMiddleware.RequireAuth(handlers.CreatePostHandler(PostRepo, CategoryRepo, PostImageRepo, WebhookRepo, UserRepo, MentionRepo, NotificationRepo, unfurler)))
 
 	// Protected PUT/DELETE routes (clear naming)
-	mux.Handle("PUT /api/posts/edit/{id}", AuthMiddleware.RequireAuth(handlers.UpdatePostHandler(PostRepo, CategoryRepo, PostImageRepo)))
-	mux.Handle("DELETE /api/posts/remove/{id}", AuthMiddleware.RequireAuth(handlers.DeletePostHandler(PostRepo, CategoryRepo, PostImageRepo)))
+	mux.Handle("PUT /api/posts/edit/{id}", AuthMiddleware.RequireAuth(handlers.UpdatePostHandler(PostRepo, CategoryRepo, PostImageRepo, WebhookRepo, UserRepo, MentionRepo, NotificationRepo, unfurler)))
+	mux.Handle("DELETE /api/posts/remove/{id}", AuthMiddleware.RequireAuth(handlers.DeletePostHandler(PostRepo)))
+	mux.Handle("PUT /api/posts/restore/{id}", AuthMiddleware.RequireAuth(handlers.RestorePostHandler(PostRepo)))
+
+	// Edit history ("/api/posts/{id}/revisions" would overlap with "/api/posts/view/{id}" in ServeMux)
+	mux.Handle("GET /api/posts/revisions/{id}", AuthMiddleware.RequireAuth(handlers.GetPostRevisionsHandler(RevisionRepo)))
+	mux.Handle("GET /api/posts/revisions/{id}/diff", AuthMiddleware.RequireAuth(handlers.GetPostRevisionDiffHandler(RevisionRepo)))
 
 	// ===== EXISTING CATEGORY ROUTES =====
 	mux.Handle("GET /api/categories", http.HandlerFunc(handlers.GetAllCategoriesHandler(CategoryRepo, PostRepo)))
@@ -91,17 +210,23 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	// Serve client static assets (logos, icons, etc.)
 	mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("client/images"))))
 	// Protected routes
-	mux.Handle("POST /api/comments/create-on-post/{id}", AuthMiddleware.RequireAuth(handlers.CreateCommentHandler(CommentRepo, NotificationRepo, PostRepo, UserRepo)))
-	mux.Handle("PUT /api/comments/edit/{id}", AuthMiddleware.RequireAuth(handlers.UpdateCommentHandler(CommentRepo)))
+	mux.Handle("POST /api/comments/create-on-post/{id}", AuthMiddleware.RequireAuth(handlers.CreateCommentHandler(CommentRepo, NotificationRepo, PostRepo, UserRepo, WebhookRepo, MentionRepo, unfurler)))
+	mux.Handle("PUT /api/comments/edit/{id}", AuthMiddleware.RequireAuth(handlers.UpdateCommentHandler(CommentRepo, UserRepo, MentionRepo, NotificationRepo, unfurler)))
 	mux.Handle("DELETE /api/comments/remove/{id}", AuthMiddleware.RequireAuth(handlers.DeleteCommentHandler(CommentRepo)))
+	mux.Handle("PUT /api/comments/restore/{id}", AuthMiddleware.RequireAuth(handlers.RestoreCommentHandler(CommentRepo)))
 	mux.Handle("GET /api/comments/view/{id}", AuthMiddleware.RequireAuth(http.HandlerFunc(handlers.GetSingleCommentHandler(CommentRepo))))
+	mux.Handle("GET /api/comments/revisions/{id}", AuthMiddleware.RequireAuth(handlers.GetCommentRevisionsHandler(RevisionRepo)))
+	mux.Handle("GET /api/comments/revisions/{id}/diff", AuthMiddleware.RequireAuth(handlers.GetCommentRevisionDiffHandler(RevisionRepo)))
 
 	// ===== EXISTING REACTION ROUTES =====
 	// Post reactions
-	mux.Handle("POST /api/reactions/posts/toggle", AuthMiddleware.RequireAuth(handlers.TogglePostReactionHandler(PostReactionRepo, NotificationRepo, PostRepo, UserRepo)))
+	mux.Handle("POST /api/reactions/posts/toggle", AuthMiddleware.RequireAuth(handlers.TogglePostReactionHandler(PostReactionRepo, NotificationRepo, PostRepo, UserRepo, WebhookRepo)))
 
 	// Comment reactions
-	mux.Handle("POST /api/reactions/comments/toggle", AuthMiddleware.RequireAuth(handlers.ToggleCommentReactionHandler(CommentReactionRepo, NotificationRepo, CommentRepo, UserRepo, PostRepo)))
+	mux.Handle("POST /api/reactions/comments/toggle", AuthMiddleware.RequireAuth(handlers.ToggleCommentReactionHandler(CommentReactionRepo, NotificationRepo, CommentRepo, UserRepo, PostRepo, WebhookRepo)))
+
+	// ===== BOOKMARK ROUTES =====
+	mux.Handle("POST /api/bookmarks/toggle", AuthMiddleware.RequireAuth(handlers.ToggleBookmarkHandler(BookmarkRepo)))
 
 	// ===== NEW NOTIFICATION ROUTES =====
 	mux.Handle("GET /api/notifications", AuthMiddleware.RequireAuth(handlers.GetNotificationsHandler(NotificationRepo)))
@@ -109,11 +234,26 @@ func SetupRoutes(db *sql.DB) http.Handler {
 
 	// ===== MESSAGE ROUTES =====
 	// All routes protected - requires authentication
-	mux.Handle("POST /api/messages/send", AuthMiddleware.RequireAuth(handlers.SendMessageHandler(MessageRepo, hub)))
+	mux.Handle("POST /api/messages/send", AuthMiddleware.RequireAuth(handlers.SendMessageHandler(MessageRepo, hub, UserRepo, MentionRepo, NotificationRepo, unfurler)))
 	mux.Handle("GET /api/messages/{id}", AuthMiddleware.RequireAuth(handlers.GetMessagesHandler(MessageRepo)))
 	mux.Handle("GET /api/messages/unread-count", AuthMiddleware.RequireAuth(handlers.GetUnreadCountHandler(MessageRepo)))
 	mux.Handle("GET /api/conversations", AuthMiddleware.RequireAuth(handlers.GetConversationsHandler(MessageRepo, hub)))
 
+	// ===== ADMIN ROUTES =====
+	// Backups (restore is offline only: "go run ./cmd restore <archive>")
+	mux.Handle("POST /api/admin/backups", AuthMiddleware.RequireAdmin(handlers.CreateBackupHandler(db)))
+	mux.Handle("GET /api/admin/backups", AuthMiddleware.RequireAdmin(handlers.ListBackupsHandler()))
+	mux.Handle("GET /api/admin/backups/{name}", AuthMiddleware.RequireAdmin(handlers.DownloadBackupHandler()))
+	// Security audit log (account and IP lockouts)
+	mux.Handle("GET /api/admin/audit-log", AuthMiddleware.RequireAdmin(handlers.GetAuditLogHandler(AuditRepo)))
+	// Outgoing webhooks and their delivery log
+	mux.Handle("GET /api/admin/webhooks", AuthMiddleware.RequireAdmin(handlers.ListWebhooksHandler(WebhookRepo)))
+	mux.Handle("POST /api/admin/webhooks", AuthMiddleware.RequireAdmin(handlers.CreateWebhookHandler(WebhookRepo)))
+	mux.Handle("PUT /api/admin/webhooks/{id}", AuthMiddleware.RequireAdmin(handlers.UpdateWebhookHandler(WebhookRepo)))
+	mux.Handle("DELETE /api/admin/webhooks/{id}", AuthMiddleware.RequireAdmin(handlers.DeleteWebhookHandler(WebhookRepo)))
+	mux.Handle("GET /api/admin/webhooks/{id}/deliveries", AuthMiddleware.RequireAdmin(handlers.GetWebhookDeliveriesHandler(WebhookRepo)))
+	mux.Handle("POST /api/admin/webhooks/{id}/deliveries/{deliveryID}/redeliver", AuthMiddleware.RequireAdmin(handlers.RedeliverWebhookHandler(WebhookRepo)))
+
 	// ===== USER ROUTES =====
 	// All routes protected - requires authentication
 	// Note: User list with online/offline status is available via /api/conversations
@@ -122,9 +262,29 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	// Protected - requires authentication
 	mux.Handle("/ws", AuthMiddleware.RequireAuth(handlers.WebSocketHandler(hub)))
 
+	// ===== RATE LIMIT POLICIES =====
+	// Routes that are expensive or attractive for abuse get their own budget instead of sharing the default one
+	RateLimiter.SetRoutePolicy(
+		ratelimit.Policy{Requests: config.Config.RateLimitAuthRequests, Window: config.Config.RateLimitAuthWindow},
+		authRateLimitedRoutes...,
+	)
+	RateLimiter.SetRoutePolicy(
+		ratelimit.Policy{Requests: config.Config.RateLimitContentRequests, Window: config.Config.RateLimitContentWindow},
+		contentRateLimitedRoutes...,
+	)
+	RateLimiter.SetRoutePolicy(
+		ratelimit.Policy{Requests: config.Config.RateLimitReactionRequests, Window: config.Config.RateLimitReactionWindow},
+		reactionRateLimitedRoutes...,
+	)
+	RateLimiter.SetRoutePolicy(
+		ratelimit.Policy{Requests: config.Config.RateLimitMessageRequests, Window: config.Config.RateLimitMessageWindow},
+		messageRateLimitedRoutes...,
+	)
+
 	// ===== APPLY MIDDLEWARE =====
 	handler := RateLimiter.Limit(mux)
+	handler = middleware.CSRF(handler)
 	handler = middleware.SecurityHeaders(handler)
 	handler = middleware.CORS(handler)
-	return AuthMiddleware.Authenticate(handler)
+	return AuthMiddleware.Authenticate(handler), backgroundJobs
 }
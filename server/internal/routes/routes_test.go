@@ -0,0 +1,146 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/ratelimit"
+	"real-time-forum/internal/repository"
+)
+
+// newTestDB loads the default configuration and opens a fresh database in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := config.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Config.DBPath = filepath.Join(t.TempDir(), "forum.db")
+	config.Config.BackupDir = t.TempDir()
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// adminRoutes lists every route registered under /api/admin/
+var adminRoutes = []struct {
+	method string
+	path   string
+}{
+	{http.MethodPost, "/api/admin/backups"},
+	{http.MethodGet, "/api/admin/backups"},
+	{http.MethodGet, "/api/admin/backups/forum-backup.tar.gz"},
+	{http.MethodGet, "/api/admin/audit-log"},
+	{http.MethodGet, "/api/admin/webhooks"},
+	{http.MethodPost, "/api/admin/webhooks"},
+	{http.MethodPut, "/api/admin/webhooks/1"},
+	{http.MethodDelete, "/api/admin/webhooks/1"},
+	{http.MethodGet, "/api/admin/webhooks/1/deliveries"},
+	{http.MethodPost, "/api/admin/webhooks/1/deliveries/1/redeliver"},
+}
+
+// TestAdminRoutesRejectAccessTokens checks that a token with every scope, belonging to an admin,
+// cannot reach the admin API, while the same token works on the rest of the API
+func TestAdminRoutesRejectAccessTokens(t *testing.T) {
+	db := newTestDB(t)
+
+	userRepo := repository.NewUserRepository(db)
+	admin, err := userRepo.CreateUser(models.UserRegistration{
+		Username: "admin", Age: 30, Gender: "Other", FirstName: "Ada", LastName: "Admin",
+		Email: "admin@example.com", Password: "Secret1!pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.SetUserRole(admin.Username, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	plain, _, err := repository.NewAPITokenRepository(db).CreateToken(admin.ID, "everything",
+		[]string{models.TokenScopeRead, models.TokenScopeWrite, models.TokenScopeMessages}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, _ := SetupRoutes(db)
+	serve := func(method, path string) int {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Authorization", "Bearer "+plain)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(http.MethodGet, "/api/posts"); code != http.StatusOK {
+		t.Fatalf("GET /api/posts with the token: got %d, want 200", code)
+	}
+	for _, route := range adminRoutes {
+		code := serve(route.method, route.path)
+		if code != http.StatusUnauthorized && code != http.StatusForbidden {
+			t.Errorf("%s %s with an admin's token: got %d, want 401 or 403", route.method, route.path, code)
+		}
+	}
+}
+
+// wildcardRegex matches the wildcards of a ServeMux pattern, e.g. {id}
+var wildcardRegex = regexp.MustCompile(`\{[^}]*\}`)
+
+// TestRateLimitedRoutesAreRegistered sends a request to every route with its own rate limit
+// policy and checks that the policy applied is that one, not the default. A pattern that does not
+// match the one registered on the mux would fall back to the default policy.
+func TestRateLimitedRoutesAreRegistered(t *testing.T) {
+	db := newTestDB(t)
+
+	// Distinct budgets, so the RateLimit-Policy header tells the policies apart
+	config.Config.RateLimitRequests = 1000
+	config.Config.RateLimitWindow = 60
+	config.Config.RateLimitAuthRequests, config.Config.RateLimitAuthWindow = 11, time.Minute
+	config.Config.RateLimitContentRequests, config.Config.RateLimitContentWindow = 12, time.Minute
+	config.Config.RateLimitReactionRequests, config.Config.RateLimitReactionWindow = 13, time.Minute
+	config.Config.RateLimitMessageRequests, config.Config.RateLimitMessageWindow = 14, time.Minute
+	handler, _ := SetupRoutes(db)
+
+	groups := []struct {
+		patterns []string
+		policy   ratelimit.Policy
+	}{
+		{authRateLimitedRoutes, ratelimit.Policy{Requests: 11, Window: time.Minute}},
+		{contentRateLimitedRoutes, ratelimit.Policy{Requests: 12, Window: time.Minute}},
+		{reactionRateLimitedRoutes, ratelimit.Policy{Requests: 13, Window: time.Minute}},
+		{messageRateLimitedRoutes, ratelimit.Policy{Requests: 14, Window: time.Minute}},
+	}
+	for _, group := range groups {
+		for _, pattern := range group.patterns {
+			method, path, ok := strings.Cut(pattern, " ")
+			if !ok {
+				t.Errorf("pattern %q has no method", pattern)
+				continue
+			}
+			r := httptest.NewRequest(method, wildcardRegex.ReplaceAllString(path, "1"), nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("RateLimit-Policy"); got != group.policy.String() {
+				t.Errorf("%s: RateLimit-Policy = %q, want %q (is the pattern registered on the mux?)", pattern, got, group.policy)
+			}
+		}
+	}
+
+	// The control: any other route gets the default policy
+	r := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Header().Get("RateLimit-Policy"); got != "1000;w=3600" {
+		t.Errorf("GET /api/posts: RateLimit-Policy = %q, want the default policy", got)
+	}
+}
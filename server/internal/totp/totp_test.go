@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890" in ASCII
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// TestCodeAtRFC6238 checks the SHA-1 test vectors of RFC 6238 appendix B. The RFC lists
+// 8 digit codes; authenticator apps show the last 6 digits.
+func TestCodeAtRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.code {
+			t.Errorf("code at T=%d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestCodeAtLowercaseSecret(t *testing.T) {
+	upper, _ := CodeAt(rfcSecret, 1)
+	lower, err := CodeAt("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil || lower != upper {
+		t.Errorf("lowercase secret gave %q, %v; want %q", lower, err, upper)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	codeAt := func(step int64) string {
+		code, err := CodeAt(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), 1, current, true},
+		{"previous step within skew", codeAt(current - 1), 1, current - 1, true},
+		{"next step within skew", codeAt(current + 1), 1, current + 1, true},
+		{"two steps behind", codeAt(current - 2), 1, 0, false},
+		{"two steps ahead", codeAt(current + 2), 1, 0, false},
+		{"previous step without skew", codeAt(current - 1), 0, 0, false},
+		{"surrounding spaces", " " + codeAt(current) + " ", 1, current, true},
+		{"too short", codeAt(current)[:5], 1, 0, false},
+		{"too long", codeAt(current) + "0", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q, skew %d) = %d, %v; want %d, %v", tt.code, tt.skew, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes are the address ranges pages are never fetched from: this host, private and
+// carrier-grade NAT networks, link-local addresses (including cloud metadata endpoints),
+// multicast, and reserved or documentation ranges. IPv4-mapped IPv6 addresses are checked as IPv4.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/96"), // unspecified, loopback and IPv4-compatible addresses
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"), // 6to4 can wrap any IPv4 address
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+var errBlockedAddress = errors.New("link points to a private or reserved address")
+
+// guardedDialer opens connections for the fetcher. The check runs on the resolved address right
+// before connecting, so a hostname that resolves (or re-resolves) to an internal address is caught
+// as well as a literal IP.
+type guardedDialer struct {
+	dialer       *net.Dialer
+	allowedHosts map[string]bool // host:port pairs exempt from the check, for local test servers
+}
+
+func newGuardedDialer(allowedHosts string) *guardedDialer {
+	d := &guardedDialer{
+		allowedHosts: map[string]bool{},
+		dialer: &net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	for _, host := range strings.Split(allowedHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			d.allowedHosts[host] = true
+		}
+	}
+	return d
+}
+
+// DialContext connects to addr ("host:port" as written in the URL) unless it resolves to a blocked address
+func (d *guardedDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.allowedHosts[strings.ToLower(addr)] {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	guarded := *d.dialer
+	guarded.Control = func(_, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if isBlockedIP(addrPort.Addr()) {
+			return errBlockedAddress
+		}
+		return nil
+	}
+	return guarded.DialContext(ctx, network, addr)
+}
+
+// isBlockedIP reports whether ip is in one of the blocked ranges
+func isBlockedIP(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("") // Contains never matches an address with a zone
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
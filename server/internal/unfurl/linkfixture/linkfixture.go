@@ -0,0 +1,121 @@
+// Package linkfixture serves pages for trying out and testing link previews: pages with
+// OpenGraph, Twitter card or only plain HTML metadata, and pages the unfurler must refuse or give
+// up on (redirects to private addresses, endless bodies, slow answers, non-HTML content).
+//
+// cmd/linkfixture runs it as a local server; the unfurl tests run it in-process with httptest.
+package linkfixture
+
+import (
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options tunes the pages that are too slow or too large
+type Options struct {
+	Delay     time.Duration // how long /slow waits before answering
+	LargeSize int           // bytes of padding before the metadata of /large
+}
+
+// Pages lists the pages served, with what the unfurler should make of them
+var Pages = []struct {
+	Path        string
+	Description string
+}{
+	{"/article", "OpenGraph title, description, site name and relative image"},
+	{"/twitter", "Twitter card tags only"},
+	{"/plain", "only <title> and <meta name=description>, with HTML entities"},
+	{"/redirect", "redirects to /article"},
+	{"/no-metadata", "HTML without title or description: failed"},
+	{"/image.png", "not HTML: failed"},
+	{"/missing", "404: failed"},
+	{"/redirect-loop", "redirects to itself: too many redirects"},
+	{"/redirect-metadata", "redirects to the cloud metadata address 169.254.169.254: blocked"},
+	{"/redirect-loopback", "redirects to this server through localhost, which is not an allowed host: blocked"},
+	{"/large", "metadata after LargeSize bytes of padding: cut off, failed"},
+	{"/endless", "a body that never ends: cut off at LINK_PREVIEW_MAX_BYTES, failed"},
+	{"/slow", "answers after Delay: timed out"},
+}
+
+// NewHandler returns the handler serving Pages
+func NewHandler(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serveIndex)
+	mux.HandleFunc("GET /article", servePage(`
+		<meta property="og:title" content="Building a Real-Time Forum">
+		<meta property="og:description" content="WebSockets, SQLite and a little bit of Go.">
+		<meta property="og:site_name" content="Link Fixture">
+		<meta property="og:image" content="/image.png">
+		<title>Ignored because og:title wins</title>`))
+	mux.HandleFunc("GET /twitter", servePage(`
+		<meta name="twitter:card" content="summary_large_image">
+		<meta name="twitter:title" content="A Twitter card">
+		<meta name="twitter:description" content="Described with twitter: tags only.">
+		<meta name="twitter:image" content="https://example.com/card.png">`))
+	mux.HandleFunc("GET /plain", servePage(`
+		<title>Fish &amp; Chips &#8211; a recipe</title>
+		<meta name="description" content="Just a &lt;title&gt; and a description.">`))
+	mux.HandleFunc("GET /no-metadata", servePage(""))
+	mux.HandleFunc("GET /image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	})
+	mux.HandleFunc("GET /redirect", redirectTo("/article"))
+	mux.HandleFunc("GET /redirect-loop", redirectTo("/redirect-loop"))
+	mux.HandleFunc("GET /redirect-metadata", redirectTo("http://169.254.169.254/latest/meta-data/"))
+	mux.HandleFunc("GET /redirect-loopback", func(w http.ResponseWriter, r *http.Request) {
+		_, port, _ := net.SplitHostPort(r.Host)
+		redirectTo("http://localhost:"+port+"/article")(w, r)
+	})
+	mux.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!DOCTYPE html><html><head><!-- %s -->", strings.Repeat("x", opts.LargeSize))
+		fmt.Fprint(w, `<title>Too far down to be read</title></head><body></body></html>`)
+	})
+	mux.HandleFunc("GET /endless", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<!DOCTYPE html><html><head><!-- ")
+		padding := strings.Repeat("x", 32<<10)
+		for r.Context().Err() == nil {
+			if _, err := fmt.Fprint(w, padding); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(opts.Delay):
+			servePage(`<title>Too late</title>`)(w, r)
+		case <-r.Context().Done():
+		}
+	})
+	return mux
+}
+
+// serveIndex lists the fixture pages
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	var list strings.Builder
+	for _, page := range Pages {
+		fmt.Fprintf(&list, "<li><a href=\"%s\">%s</a> - %s</li>\n", page.Path, page.Path, html.EscapeString(page.Description))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>Link fixture</title></head><body><ul>\n%s</ul></body></html>\n", list.String())
+}
+
+// servePage answers with an HTML page whose <head> holds head
+func servePage(head string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head>%s</head><body></body></html>\n", head)
+	}
+}
+
+// redirectTo answers with a 302 to location
+func redirectTo(location string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location, http.StatusFound)
+	}
+}
@@ -0,0 +1,93 @@
+package unfurl
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"real-time-forum/internal/models"
+)
+
+// Longest texts kept from a page, in characters
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	maxSiteNameLength    = 100
+)
+
+var (
+	// metaTagRegex matches <meta ...> tags; their attributes are read with attributeRegex
+	metaTagRegex   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributeRegex = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleRegex     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// parsePage reads the preview of an HTML page served from pageURL. OpenGraph tags win over
+// Twitter card tags, which win over the plain <title> and description.
+func parsePage(page string, pageURL *url.URL) *models.LinkPreview {
+	meta := map[string]string{}
+	for _, tag := range metaTagRegex.FindAllString(page, -1) {
+		attributes := map[string]string{}
+		for _, m := range attributeRegex.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+
+		// OpenGraph uses property=, Twitter cards and the description use name=
+		key := attributes["property"]
+		if key == "" {
+			key = attributes["name"]
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = attributes["content"]
+		}
+	}
+
+	title := first(meta["og:title"], meta["twitter:title"])
+	if title == "" {
+		if m := titleRegex.FindStringSubmatch(page); m != nil {
+			title = m[1]
+		}
+	}
+
+	return &models.LinkPreview{
+		Title:       cleanText(title, maxTitleLength),
+		Description: cleanText(first(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		ImageURL:    imageURL(first(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"], meta["twitter:image:src"]), pageURL),
+		SiteName:    cleanText(meta["og:site_name"], maxSiteNameLength),
+	}
+}
+
+// first returns the first value that is not blank
+func first(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// cleanText decodes entities, drops invalid UTF-8, collapses whitespace and cuts the text to max characters
+func cleanText(text string, max int) string {
+	text = strings.ToValidUTF8(html.UnescapeString(text), "")
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > max {
+		text = strings.TrimSpace(string(runes[:max])) + "..."
+	}
+	return text
+}
+
+// imageURL resolves the image against the page and keeps it only if it is an http(s) URL
+func imageURL(raw string, pageURL *url.URL) string {
+	raw = strings.TrimSpace(html.UnescapeString(raw))
+	if raw == "" || len(raw) > maxURLLength {
+		return ""
+	}
+	u, err := pageURL.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,141 @@
+// Package unfurl fetches the OpenGraph and Twitter card metadata of pages linked from posts,
+// comments and messages.
+//
+// Fetching URLs chosen by users must not give them a way into the server's network, so every
+// connection, including those made for redirects, is checked against the blocked address ranges
+// in guard.go after DNS resolution. Pages are also limited in time, size and content type.
+package unfurl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/markdown"
+	"real-time-forum/internal/models"
+)
+
+// maxRedirects is how many redirects a page may go through before it is given up
+const maxRedirects = 5
+
+// maxURLLength skips links too long to be worth a preview (and to be a cache key)
+const maxURLLength = 2048
+
+// Fetcher downloads pages and extracts their previews
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher creates a Fetcher using the LINK_PREVIEW_* settings
+func NewFetcher() *Fetcher {
+	return &Fetcher{client: NewClient(config.Config.LinkPreviewTimeout)}
+}
+
+// NewClient returns an HTTP client for fetching URLs chosen by users or third parties: it only
+// connects to public addresses (and the hosts in LINK_PREVIEW_ALLOWED_HOSTS), never uses a proxy,
+// follows at most maxRedirects http(s) redirects and gives up after timeout.
+func NewClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		Proxy:                 nil, // a proxy would connect on our behalf, past the address checks
+		DialContext:           newGuardedDialer(config.Config.LinkPreviewAllowedHosts).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return checkURL(req.URL)
+		},
+	}
+}
+
+// Fetch downloads the page at rawURL and returns its preview. Pages without a title or
+// description have nothing to show and count as failed.
+func (f *Fetcher) Fetch(rawURL string) (*models.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.New("invalid URL")
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "RealTimeForum-LinkPreview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("page answered %s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("not an HTML page (%s)", mediaType)
+	}
+
+	// The metadata lives in the <head>, so a page cut off at the limit is still usable
+	body, err := io.ReadAll(io.LimitReader(resp.Body, config.Config.LinkPreviewMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parsePage(string(body), resp.Request.URL)
+	if preview.Title == "" && preview.Description == "" {
+		return nil, errors.New("page has no title or description")
+	}
+	preview.URL = rawURL
+	preview.Status = models.LinkPreviewOK
+	return preview, nil
+}
+
+// LinksIn returns the links of text that get a preview: external http(s) links, at most
+// LinkPreviewMaxPerItem of them
+func LinksIn(text string) []string {
+	links := []string{}
+	for _, link := range markdown.ExternalLinks(text) {
+		if len(links) == config.Config.LinkPreviewMaxPerItem {
+			break
+		}
+		if len(link) <= maxURLLength {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// checkURL allows http(s) URLs with a host and without credentials
+func checkURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return errors.New("only http and https links are previewed")
+	}
+	if u.Hostname() == "" {
+		return errors.New("URL has no host")
+	}
+	if u.User != nil {
+		return errors.New("URLs with credentials are not previewed")
+	}
+	return nil
+}
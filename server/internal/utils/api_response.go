@@ -28,13 +28,13 @@ func RespondWithSuccess(w http.ResponseWriter, status int, data interface{}) {
 }
 
 // RespondWithPaginatedPosts sends a standardized paginated posts response
-func RespondWithPaginatedPosts(w http.ResponseWriter, posts []*models.Post, totalCount, limit, offset int) {
-	response := models.NewPaginatedPostsResponse(posts, totalCount, limit, offset)
+func RespondWithPaginatedPosts(w http.ResponseWriter, posts []*models.Post, totalCount, limit, offset int, nextCursor string) {
+	response := models.NewPaginatedPostsResponse(posts, totalCount, limit, offset, nextCursor)
 	RespondWithSuccess(w, http.StatusOK, response)
 }
 
 // RespondWithPaginatedComments sends a standardized paginated comments response
-func RespondWithPaginatedComments(w http.ResponseWriter, comments []*models.Comment, totalCount, limit, offset int) {
-	response := models.NewPaginatedCommentsResponse(comments, totalCount, limit, offset)
+func RespondWithPaginatedComments(w http.ResponseWriter, comments []*models.Comment, totalCount, limit, offset int, nextCursor string) {
+	response := models.NewPaginatedCommentsResponse(comments, totalCount, limit, offset, nextCursor)
 	RespondWithSuccess(w, http.StatusOK, response)
 }
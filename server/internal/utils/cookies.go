@@ -7,15 +7,17 @@ import (
 	"real-time-forum/config"
 )
 
+// ClearSessionCookie removes the session cookie and the CSRF cookie that belongs to it
 func ClearSessionCookie(w http.ResponseWriter) {
+	ClearCSRFCookie(w)
 	http.SetCookie(w, &http.Cookie{
 		Name:     config.Config.SessionName, // Use config session name
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   false,                // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode, // Consistent with frontend
+		Secure:   config.Config.Security.SecureCookies, // COOKIE_SECURE, on in production
+		SameSite: http.SameSiteLaxMode,                 // Consistent with frontend
 	})
 }
 
@@ -27,7 +29,76 @@ func SetSessionCookie(value string, w http.ResponseWriter, r *http.Request, expi
 		Path:     "/",
 		Expires:  expiresAt,
 		HttpOnly: true,
-		Secure:   false,                // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode, // Consistent with frontend
+		Secure:   config.Config.Security.SecureCookies, // COOKIE_SECURE, on in production
+		SameSite: http.SameSiteLaxMode,                 // Consistent with frontend
+	})
+}
+
+// SetCSRFCookie stores the session's CSRF token in a cookie the frontend can read; it sends the
+// value back in the X-CSRF-Token header. Other sites can neither read the cookie nor set the header.
+func SetCSRFCookie(token string, w http.ResponseWriter, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.Config.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: false, // read by JavaScript
+		Secure:   config.Config.Security.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearCSRFCookie removes the CSRF cookie
+func ClearCSRFCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.Config.CSRFCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: false,
+		Secure:   config.Config.Security.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SetTwoFactorCookie stores the pending login of an OAuth sign-in that still needs a code, so the
+// token never appears in a URL (browser history, logs, Referer). It only lives as long as the
+// pending login.
+func SetTwoFactorCookie(token string, w http.ResponseWriter, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.Config.TwoFactorCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   config.Config.Security.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearTwoFactorCookie removes the pending login cookie
+func ClearTwoFactorCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.Config.TwoFactorCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   config.Config.Security.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SetOAuthCookie stores the browser secret that OAuth flow states are bound to. Lax still sends
+// it on the provider's top-level redirect back to the callback.
+func SetOAuthCookie(value string, w http.ResponseWriter, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.Config.OAuthCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   config.Config.Security.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cursor is the decoded form of an opaque keyset pagination token.
+// It remembers the sort it was issued for and the sort key of the last row returned,
+// so the next page starts right after that row even if new rows were inserted meanwhile.
+type Cursor struct {
+	SortBy    string    `json:"s"`
+	Count     int       `json:"n"` // like_count or comment_count, only used by count-based sorts
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// keysetColumns describes the SQL expressions a listing is ordered by. Timestamps are compared
+// as julian days: the stored text differs between rows written by Go (with a UTC offset) and by
+// CURRENT_TIMESTAMP (without one), so comparing the text would misorder them.
+type keysetColumns struct {
+	LikeCount    string
+	CommentCount string
+	CreatedAt    string
+	ID           string
+}
+
+var (
+	postKeysetColumns = keysetColumns{
+		LikeCount:    "COALESCE(like_counts.count, 0)",
+		CommentCount: "COALESCE(comment_counts.count, 0)",
+		CreatedAt:    "julianday(p.created_at)",
+		ID:           "p.post_id",
+	}
+	commentKeysetColumns = keysetColumns{
+		LikeCount: "COALESCE(like_counts.count, 0)",
+		CreatedAt: "julianday(c.created_at)",
+		ID:        "c.comment_id",
+	}
+)
+
+// keysetTerm is a single ORDER BY term with its direction
+type keysetTerm struct {
+	expr  string
+	value interface{}
+	desc  bool
+}
+
+// EncodeCursor turns a cursor into an opaque URL-safe token
+func EncodeCursor(c Cursor) string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an opaque token back into a cursor
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// ParseCursorParam extracts the optional "cursor" query parameter.
+// Returns nil when no cursor was sent; a cursor issued for a different sort is rejected.
+func ParseCursorParam(r *http.Request, sortBy string) (*Cursor, error) {
+	token := strings.TrimSpace(r.URL.Query().Get("cursor"))
+	if token == "" {
+		return nil, nil
+	}
+
+	cursor, err := DecodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.SortBy != sortBy {
+		return nil, errors.New("cursor does not match sort option")
+	}
+
+	return cursor, nil
+}
+
+// NewCursor builds the token pointing right after the given row
+func NewCursor(sortBy string, count int, createdAt time.Time, id string) string {
+	return EncodeCursor(Cursor{
+		SortBy:    sortBy,
+		Count:     count,
+		CreatedAt: createdAt,
+		ID:        id,
+	})
+}
+
+// BuildKeysetClause returns an " AND (...)" condition selecting rows after the cursor,
+// mirroring the ORDER BY produced by BuildOrderClause. Returns "" and no args for a nil cursor.
+func BuildKeysetClause(cursor *Cursor, contentType ContentType) (string, []interface{}) {
+	if cursor == nil {
+		return "", nil
+	}
+
+	var terms []keysetTerm
+	switch contentType {
+	case ContentTypeComments:
+		terms = commentKeysetTerms(cursor)
+	case ContentTypePosts:
+		fallthrough
+	default:
+		terms = postKeysetTerms(cursor)
+	}
+
+	// Expand (a, b, c) > (x, y, z) into
+	// a > x OR (a = x AND (b > y OR (b = y AND c > z))) so mixed directions work
+	clause := ""
+	var args []interface{}
+	for i := len(terms) - 1; i >= 0; i-- {
+		op := ">"
+		if terms[i].desc {
+			op = "<"
+		}
+		// Times are bound like the julianday() columns they are compared with
+		param := "?"
+		if _, ok := terms[i].value.(time.Time); ok {
+			param = "julianday(?)"
+		}
+		if clause == "" {
+			clause = terms[i].expr + " " + op + " " + param
+			args = []interface{}{terms[i].value}
+			continue
+		}
+		clause = terms[i].expr + " " + op + " " + param + " OR (" + terms[i].expr + " = " + param + " AND (" + clause + "))"
+		args = append([]interface{}{terms[i].value, terms[i].value}, args...)
+	}
+
+	return " AND (" + clause + ")", args
+}
+
+// postKeysetTerms lists the ORDER BY terms used by buildPostOrderClause
+func postKeysetTerms(cursor *Cursor) []keysetTerm {
+	cols := postKeysetColumns
+	switch cursor.SortBy {
+	case "likes":
+		return []keysetTerm{
+			{cols.LikeCount, cursor.Count, true},
+			{cols.CreatedAt, cursor.CreatedAt, true},
+			{cols.ID, cursor.ID, true},
+		}
+	case "comments":
+		return []keysetTerm{
+			{cols.CommentCount, cursor.Count, true},
+			{cols.CreatedAt, cursor.CreatedAt, true},
+			{cols.ID, cursor.ID, true},
+		}
+	case "oldest":
+		return []keysetTerm{
+			{cols.CreatedAt, cursor.CreatedAt, false},
+			{cols.ID, cursor.ID, false},
+		}
+	case "newest":
+		fallthrough
+	default:
+		return []keysetTerm{
+			{cols.CreatedAt, cursor.CreatedAt, true},
+			{cols.ID, cursor.ID, true},
+		}
+	}
+}
+
+// commentKeysetTerms lists the ORDER BY terms used by buildCommentOrderClause
+func commentKeysetTerms(cursor *Cursor) []keysetTerm {
+	cols := commentKeysetColumns
+	switch cursor.SortBy {
+	case "newest":
+		return []keysetTerm{
+			{cols.CreatedAt, cursor.CreatedAt, true},
+			{cols.ID, cursor.ID, true},
+		}
+	case "likes":
+		return []keysetTerm{
+			{cols.LikeCount, cursor.Count, true},
+			{cols.CreatedAt, cursor.CreatedAt, false},
+			{cols.ID, cursor.ID, false},
+		}
+	case "oldest":
+		fallthrough
+	default:
+		return []keysetTerm{
+			{cols.CreatedAt, cursor.CreatedAt, false},
+			{cols.ID, cursor.ID, false},
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"newest", Cursor{SortBy: "newest", CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: "post-1"}},
+		{"oldest", Cursor{SortBy: "oldest", CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: "post-1"}},
+		{"likes", Cursor{SortBy: "likes", Count: 42, CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: "post-1"}},
+		{"comments", Cursor{SortBy: "comments", Count: 3, CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: "post-1"}},
+		{
+			"nanoseconds and offset",
+			Cursor{SortBy: "newest", CreatedAt: time.Date(2024, 6, 30, 23, 59, 59, 123456789, time.FixedZone("", 2*60*60)), ID: "post-2"},
+		},
+		{"no sort", Cursor{CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: "comment-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := EncodeCursor(tt.cursor)
+			if _, err := url.ParseQuery("cursor=" + token); err != nil || url.QueryEscape(token) != token {
+				t.Errorf("token %q is not URL-safe", token)
+			}
+
+			got, err := DecodeCursor(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SortBy != tt.cursor.SortBy || got.Count != tt.cursor.Count || got.ID != tt.cursor.ID || !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("DecodeCursor(EncodeCursor(%+v)) = %+v", tt.cursor, *got)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"newest","t":"2024-01-01T12:00:00Z","id":"p"}`))},
+		{"not JSON", encode("newest,2024-01-01,p")},
+		{"no ID", encode(`{"s":"newest","t":"2024-01-01T12:00:00Z"}`)},
+		{"no time", encode(`{"s":"newest","id":"p"}`)},
+		{"invalid time", encode(`{"s":"newest","t":"yesterday","id":"p"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := DecodeCursor(tt.token); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want an error", tt.token, *cursor)
+			}
+		})
+	}
+}
+
+func TestParseCursorParam(t *testing.T) {
+	newest := NewCursor("newest", 0, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "post-1")
+	likes := NewCursor("likes", 7, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "post-1")
+
+	tests := []struct {
+		name    string
+		query   string
+		sortBy  string
+		wantID  string // "" when no cursor is expected
+		wantErr bool
+	}{
+		{"no cursor", "", "newest", "", false},
+		{"empty cursor", "cursor=", "newest", "", false},
+		{"matching sort", "cursor=" + newest, "newest", "post-1", false},
+		{"surrounding spaces", "cursor=%20" + likes + "%20", "likes", "post-1", false},
+		{"cursor of another sort", "cursor=" + newest, "oldest", "", true},
+		{"count cursor for a time sort", "cursor=" + likes, "newest", "", true},
+		{"invalid cursor", "cursor=abc", "newest", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/posts?"+tt.query, nil)
+			cursor, err := ParseCursorParam(r, tt.sortBy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCursorParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.wantID == "" && cursor != nil:
+				t.Errorf("ParseCursorParam() = %+v, want no cursor", *cursor)
+			case tt.wantID != "" && (cursor == nil || cursor.ID != tt.wantID || cursor.SortBy != tt.sortBy):
+				t.Errorf("ParseCursorParam() = %+v, want the %s cursor of %s", cursor, tt.sortBy, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestBuildKeysetClause(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		cursor      *Cursor
+		contentType ContentType
+		wantClause  string
+		wantArgs    []interface{}
+	}{
+		{"no cursor", nil, ContentTypePosts, "", nil},
+		{
+			"posts newest",
+			&Cursor{SortBy: "newest", CreatedAt: createdAt, ID: "p"},
+			ContentTypePosts,
+			" AND (julianday(p.created_at) < julianday(?) OR (julianday(p.created_at) = julianday(?) AND (p.post_id < ?)))",
+			[]interface{}{createdAt, createdAt, "p"},
+		},
+		{
+			"posts oldest",
+			&Cursor{SortBy: "oldest", CreatedAt: createdAt, ID: "p"},
+			ContentTypePosts,
+			" AND (julianday(p.created_at) > julianday(?) OR (julianday(p.created_at) = julianday(?) AND (p.post_id > ?)))",
+			[]interface{}{createdAt, createdAt, "p"},
+		},
+		{
+			"posts likes",
+			&Cursor{SortBy: "likes", Count: 3, CreatedAt: createdAt, ID: "p"},
+			ContentTypePosts,
+			" AND (COALESCE(like_counts.count, 0) < ? OR (COALESCE(like_counts.count, 0) = ? AND (" +
+				"julianday(p.created_at) < julianday(?) OR (julianday(p.created_at) = julianday(?) AND (p.post_id < ?)))))",
+			[]interface{}{3, 3, createdAt, createdAt, "p"},
+		},
+		{
+			"comments likes mix directions",
+			&Cursor{SortBy: "likes", Count: 3, CreatedAt: createdAt, ID: "c"},
+			ContentTypeComments,
+			" AND (COALESCE(like_counts.count, 0) < ? OR (COALESCE(like_counts.count, 0) = ? AND (" +
+				"julianday(c.created_at) > julianday(?) OR (julianday(c.created_at) = julianday(?) AND (c.comment_id > ?)))))",
+			[]interface{}{3, 3, createdAt, createdAt, "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := BuildKeysetClause(tt.cursor, tt.contentType)
+			if clause != tt.wantClause {
+				t.Errorf("clause\n got %s\nwant %s", clause, tt.wantClause)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+			}
+			for i := range args {
+				if args[i] != tt.wantArgs[i] {
+					t.Errorf("args[%d] = %v, want %v", i, args[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"regexp"
+
+	"real-time-forum/internal/models"
+)
+
+// diffTokenRegex splits text into words and the whitespace between them,
+// so joining the tokens back together gives the original text
+var diffTokenRegex = regexp.MustCompile(`\s+|\S+`)
+
+// DiffText computes a word-level diff between two texts using the longest common subsequence.
+// Consecutive tokens with the same operation are merged into a single segment.
+func DiffText(from, to string) []models.DiffSegment {
+	a := diffTokenRegex.FindAllString(from, -1)
+	b := diffTokenRegex.FindAllString(to, -1)
+
+	// lcs[i][j] = length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	segments := []models.DiffSegment{}
+	appendToken := func(op, text string) {
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Text += text
+			return
+		}
+		segments = append(segments, models.DiffSegment{Op: op, Text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			appendToken(models.DiffOpEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			appendToken(models.DiffOpDelete, a[i])
+			i++
+		default:
+			appendToken(models.DiffOpInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		appendToken(models.DiffOpDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		appendToken(models.DiffOpInsert, b[j])
+	}
+
+	return segments
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"real-time-forum/config"
+	"real-time-forum/internal/models"
+)
+
+// WriteDataExportZip writes a personal data export as a ZIP archive: one JSON file per kind
+// of data plus the images the user uploaded under images/
+func WriteDataExportZip(w io.Writer, export *models.DataExport) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"reactions.json", export.Reactions},
+		{"notifications.json", export.Notifications},
+		{"messages.json", export.Messages},
+		{"bookmarks.json", export.Bookmarks},
+	}
+	for _, file := range files {
+		entry, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	// The avatar plus images attached to the user's posts and to the messages they sent
+	var images []string
+	if export.Profile.AvatarURL != "" {
+		images = append(images, export.Profile.AvatarURL)
+	}
+	for _, post := range export.Posts {
+		images = append(images, post.Images...)
+	}
+	for _, message := range export.Messages {
+		if message.Direction == "sent" {
+			images = append(images, message.Images...)
+		}
+	}
+	for _, imageURL := range images {
+		if err := addUploadToZip(zw, imageURL); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// addUploadToZip copies an uploaded file ("/uploads/xyz") into the archive; files missing on disk are skipped
+func addUploadToZip(zw *zip.Writer, imageURL string) error {
+	relative := strings.TrimPrefix(imageURL, "/uploads/")
+	file, err := os.Open(config.Config.UploadDir + relative)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	entry, err := zw.Create(path.Join("images", path.Base(relative)))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
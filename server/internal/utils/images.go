@@ -1,15 +1,26 @@
 package utils
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder for avatars
+	"image/jpeg"
+	"image/png"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"real-time-forum/config"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/unfurl"
 )
 
 var allowedImageExt = map[string]bool{
@@ -52,6 +63,23 @@ func RemoveFileIfExists(path string) error {
 	return nil
 }
 
+// DeleteImageFilesFromDisk deletes the files behind uploaded images ("/uploads/xyz" -> UploadDir + "xyz")
+func DeleteImageFilesFromDisk(images []models.PostImage) {
+	for _, img := range images {
+		DeleteUploadedFile(img.ImageURL)
+	}
+}
+
+// DeleteUploadedFile deletes the file behind an upload URL, logging failures
+func DeleteUploadedFile(imageURL string) {
+	filePath := config.Config.UploadDir + strings.TrimPrefix(imageURL, "/uploads/")
+	err := RemoveFileIfExists(filePath)
+	if err != nil {
+		// Log and continue
+		fmt.Printf("Warning: failed to delete image file %s: %v\n", filePath, err)
+	}
+}
+
 // validateImageFile validates the size and type of an uploaded image file
 func validateImageFile(fileHeader *multipart.FileHeader) error {
 	// Validate size
@@ -199,3 +227,154 @@ func ProcessMessageImageUploads(files []*multipart.FileHeader, maxImages int, ma
 
 	return images, nil
 }
+
+// Avatars are stored in their own directory below UploadDir
+const (
+	avatarDir = "avatars/"
+	// maxAvatarPixels guards against decompression bombs (tiny files that decode to huge images)
+	maxAvatarPixels = 40_000_000
+)
+
+// ProcessAvatarUpload validates an uploaded avatar, crops it to a centered square of
+// AvatarSize pixels and saves it. Returns the avatar URL ("/uploads/avatars/xyz.png")
+func ProcessAvatarUpload(fileHeader *multipart.FileHeader) (string, error) {
+	if fileHeader.Size > config.Config.MaxAvatarFileSize {
+		return "", fmt.Errorf("file %s exceeds %dMB limit", fileHeader.Filename, config.Config.MaxAvatarFileSize/(1024*1024))
+	}
+	if !IsValidImageFile(fileHeader.Filename) {
+		return "", fmt.Errorf("invalid file type: %s", fileHeader.Filename)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	return saveSquareAvatar(file)
+}
+
+// avatarDownloadTimeout bounds the whole download of a provider's profile picture
+const avatarDownloadTimeout = 10 * time.Second
+
+// avatarContentTypes are the image types accepted when importing an avatar
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// ImportAvatarFromURL downloads a profile picture from an OAuth provider and stores it
+// like an uploaded avatar, so pages never load images from third-party hosts. The URL comes
+// from the provider's claims, so it is fetched like a link preview: public addresses only,
+// over https all the way through redirects.
+func ImportAvatarFromURL(imageURL string) (string, error) {
+	if !strings.HasPrefix(imageURL, "https://") {
+		return "", errors.New("avatar URL must use https")
+	}
+
+	client := unfurl.NewClient(avatarDownloadTimeout)
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errors.New("avatar URL must use https")
+		}
+		return checkRedirect(req, via)
+	}
+
+	resp, err := client.Get(imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download avatar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download avatar: status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !avatarContentTypes[mediaType] {
+		return "", fmt.Errorf("avatar is not a JPEG, PNG or GIF image (%s)", mediaType)
+	}
+
+	return saveSquareAvatar(resp.Body)
+}
+
+// saveSquareAvatar decodes a JPEG, PNG or GIF image (first frame), crops and resizes it and
+// writes it to the avatar directory. Photos are stored as JPEG, everything else as PNG.
+func saveSquareAvatar(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, config.Config.MaxAvatarFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > config.Config.MaxAvatarFileSize {
+		return "", fmt.Errorf("avatar exceeds %dMB limit", config.Config.MaxAvatarFileSize/(1024*1024))
+	}
+
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.New("invalid image")
+	}
+	if imageConfig.Width*imageConfig.Height > maxAvatarPixels {
+		return "", errors.New("image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.New("invalid image")
+	}
+	avatar := cropToSquare(img, config.Config.AvatarSize)
+
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	uniqueFilename := GenerateUUIDToken() + ext
+
+	outFile, err := CreateFile(config.Config.UploadDir + avatarDir + uniqueFilename)
+	if err != nil {
+		return "", fmt.Errorf("failed to save image: %w", err)
+	}
+	defer outFile.Close()
+
+	if ext == ".jpg" {
+		err = jpeg.Encode(outFile, avatar, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(outFile, avatar)
+	}
+	if err != nil {
+		RemoveFileIfExists(outFile.Name())
+		return "", fmt.Errorf("failed to save image data: %w", err)
+	}
+
+	return "/uploads/" + avatarDir + uniqueFilename, nil
+}
+
+// cropToSquare takes the largest centered square of src and scales it to size x size.
+// Each destination pixel averages the source pixels it covers (box filter).
+func cropToSquare(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0 := y0 + y*side/size
+		sy1 := max(y0+(y+1)*side/size, sy0+1)
+		for x := 0; x < size; x++ {
+			sx0 := x0 + x*side/size
+			sx1 := max(x0+(x+1)*side/size, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"real-time-forum/config"
+)
+
+// mentionRegex finds @username at the start of the text or after a character that cannot be
+// part of a word or an email address, so "alice@example.com" is not a mention of "example"
+var mentionRegex = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_.@])@([a-zA-Z0-9_]+)`)
+
+// ExtractMentions returns the usernames mentioned in text, in order of first appearance and
+// without duplicates (case-insensitive). Handles that cannot be a valid username are skipped and
+// at most Config.MaxMentionsPerItem names are returned.
+func ExtractMentions(text string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+		username := match[1]
+		if len(username) < config.Config.MinUsernameLen || len(username) > config.Config.MaxUsernameLen {
+			continue
+		}
+		key := strings.ToLower(username)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, username)
+		if len(usernames) >= config.Config.MaxMentionsPerItem {
+			break
+		}
+	}
+	return usernames
+}
@@ -65,18 +65,20 @@ func isValidSortOption(sort string, contentType ContentType) bool {
 }
 
 // buildPostOrderClause builds ORDER BY clause for posts
+// The trailing ID keeps the order total so keyset cursors never skip or repeat rows; times are
+// ordered as julian days like BuildKeysetClause compares them
 func buildPostOrderClause(sortBy string) string {
 	switch sortBy {
 	case "likes":
-		return "ORDER BY like_count DESC, p.created_at DESC"
+		return "ORDER BY like_count DESC, julianday(p.created_at) DESC, p.post_id DESC"
 	case "comments":
-		return "ORDER BY comment_count DESC, p.created_at DESC"
+		return "ORDER BY comment_count DESC, julianday(p.created_at) DESC, p.post_id DESC"
 	case "oldest":
-		return "ORDER BY p.created_at ASC"
+		return "ORDER BY julianday(p.created_at) ASC, p.post_id ASC"
 	case "newest":
 		fallthrough
 	default:
-		return "ORDER BY p.created_at DESC"
+		return "ORDER BY julianday(p.created_at) DESC, p.post_id DESC"
 	}
 }
 
@@ -84,13 +86,13 @@ func buildPostOrderClause(sortBy string) string {
 func buildCommentOrderClause(sortBy string) string {
 	switch sortBy {
 	case "newest":
-		return "ORDER BY c.created_at DESC"
+		return "ORDER BY julianday(c.created_at) DESC, c.comment_id DESC"
 	case "likes":
-		return "ORDER BY like_count DESC, c.created_at ASC"
+		return "ORDER BY like_count DESC, julianday(c.created_at) ASC, c.comment_id ASC"
 	case "oldest":
 		fallthrough
 	default:
-		return "ORDER BY c.created_at ASC" // Default: conversation order
+		return "ORDER BY julianday(c.created_at) ASC, c.comment_id ASC" // Default: conversation order
 	}
 }
 
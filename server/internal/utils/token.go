@@ -1,8 +1,12 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,3 +36,45 @@ func GenerateSessionToken() (string, error) {
 func CalculateSessionExpiry() time.Time {
 	return time.Now().Add(config.Config.SessionDuration) // fix later for not having magic numbers
 }
+
+// GenerateSecureToken creates a URL-safe token from crypto/rand for links sent by email
+func GenerateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest stored in place of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// recoveryCodeAlphabet leaves out characters that are easy to confuse (0/o, 1/l/i)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCode creates a two-factor recovery code such as "k7m2p-q9x4c"
+func GenerateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, v := range b {
+		if i == 5 {
+			code.WriteByte('-')
+		}
+		// 256 is not a multiple of the alphabet size; the bias is negligible for 50 bits of code
+		code.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// NormalizeRecoveryCode makes recovery codes case-insensitive and ignores separators
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -128,6 +128,25 @@ func ValidateGender(gender string) error {
 	return errors.New("gender must be one of: Male, Female, or Other")
 }
 
+// ValidateBio checks the optional free-text profile bio
+func ValidateBio(bio string) error {
+	if len(bio) > config.Config.MaxBioLength {
+		return errors.New("bio must be " + strconv.Itoa(config.Config.MaxBioLength) + " characters or less")
+	}
+	return nil
+}
+
+// ValidateLocation checks the optional profile location
+func ValidateLocation(location string) error {
+	if len(location) > config.Config.MaxLocationLength {
+		return errors.New("location must be " + strconv.Itoa(config.Config.MaxLocationLength) + " characters or less")
+	}
+	if strings.ContainsAny(location, "\r\n") {
+		return errors.New("location must be a single line")
+	}
+	return nil
+}
+
 func ValidateUserInput(username, email, password, gender, firstName, lastName string, age int) error {
 	if err := ValidateUsername(username); err != nil {
 		return err
@@ -172,6 +191,25 @@ func ValidatePostContent(content string) error {
 	return nil
 }
 
+// ValidatePostTitle checks the optional post title
+func ValidatePostTitle(title string) error {
+	if len(title) > config.Config.MaxPostTitleLength {
+		return errors.New("post title must be " + strconv.Itoa(config.Config.MaxPostTitleLength) + " characters or less")
+	}
+	if strings.ContainsAny(title, "\r\n") {
+		return errors.New("post title must be a single line")
+	}
+	return nil
+}
+
+// ValidatePostDraftContent checks draft content, which may still be incomplete (no minimum length)
+func ValidatePostDraftContent(content string) error {
+	if len(content) > config.Config.MaxPostContentLength {
+		return errors.New("post content must be " + strconv.Itoa(config.Config.MaxPostContentLength) + " characters or less")
+	}
+	return nil
+}
+
 func ValidateCommentContent(content string) error {
 	// Content validation using configuration
 	if len(content) < config.Config.MinCommentLength || len(content) > config.Config.MaxCommentLength {
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"log"
 
+	"real-time-forum/config"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/ratelimit"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to clients
@@ -17,6 +19,10 @@ type Hub struct {
 
 	// Unregister requests from clients
 	Unregister chan *Client
+
+	// Per-user limits on incoming frames
+	typingLimiter *ratelimit.Limiter
+	sendLimiter   *ratelimit.Limiter
 }
 
 // NewHub creates a new Hub instance
@@ -25,6 +31,14 @@ func NewHub() *Hub {
 		Clients:    make(map[string]*Client),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		typingLimiter: ratelimit.NewLimiter(ratelimit.Policy{
+			Requests: config.Config.WSTypingRateLimitRequests,
+			Window:   config.Config.WSTypingRateLimitWindow,
+		}),
+		sendLimiter: ratelimit.NewLimiter(ratelimit.Policy{
+			Requests: config.Config.WSSendRateLimitRequests,
+			Window:   config.Config.WSSendRateLimitWindow,
+		}),
 	}
 }
 
@@ -55,6 +69,10 @@ func (h *Hub) Run() {
 
 // HandleMessage processes incoming messages from clients based on event type
 func (h *Hub) HandleMessage(sender *Client, msg models.WebSocketMessage) {
+	if !h.allowFrame(sender, msg.Event) {
+		return
+	}
+
 	switch msg.Event {
 	case models.EventTypeTypingStart:
 		h.handleTypingIndicator(sender, msg.Payload, true)
@@ -66,6 +84,20 @@ func (h *Hub) HandleMessage(sender *Client, msg models.WebSocketMessage) {
 	}
 }
 
+// allowFrame applies the per-user frame limits. Typing indicators over the limit are dropped
+// silently since they are only cosmetic; other frames get an error back.
+func (h *Hub) allowFrame(sender *Client, event string) bool {
+	if event == models.EventTypeTypingStart || event == models.EventTypeTypingStop {
+		return h.typingLimiter.Allow(sender.UserID).Allowed
+	}
+
+	if !h.sendLimiter.Allow(sender.UserID).Allowed {
+		sender.SendError("Rate limit exceeded. Please try again later.")
+		return false
+	}
+	return true
+}
+
 // BroadcastUserStatus broadcasts a user's online/offline status to all connected users
 func (h *Hub) BroadcastUserStatus(userID, username, status string) {
 	payload := models.UserStatusPayload{
@@ -114,6 +146,24 @@ func (h *Hub) SendMessageToUser(userID string, event string, payload interface{}
 	return true
 }
 
+// Broadcast sends an event to every connected user
+func (h *Hub) Broadcast(event string, payload interface{}) {
+	for _, client := range h.Clients {
+		client.SendMessage(event, payload)
+	}
+}
+
+// DisconnectUser closes the connection of a user if they are online.
+// The client's ReadPump then unregisters it, which broadcasts the offline status.
+func (h *Hub) DisconnectUser(userID string) {
+	client, ok := h.Clients[userID]
+	if !ok {
+		return
+	}
+
+	client.Conn.Close()
+}
+
 // handleTypingIndicator handles typing start/stop events
 func (h *Hub) handleTypingIndicator(sender *Client, payload interface{}, isTyping bool) {
 	// Parse the payload
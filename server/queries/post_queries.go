@@ -5,14 +5,19 @@ const (
 	BaseSelectFields = `p.post_id,
 		p.user_id,
 		u.username,
+		u.avatar_url,
 		p.content,
 		p.created_at,
 		p.updated_at,
+		p.title,
+		p.status,
+		p.publish_at,
 		COALESCE(like_counts.count, 0) as like_count,
 		COALESCE(dislike_counts.count, 0) as dislike_count,
 		COALESCE(comment_counts.count, 0) as comment_count,
 		GROUP_CONCAT(DISTINCT c.category_id || ':' || c.category_name) as categories,
-		ur.reaction_type as user_reaction`
+		ur.reaction_type as user_reaction,
+		ub.post_id IS NOT NULL as is_bookmarked`
 
 	// Base JOINs for posts -
 	BaseJoins = `JOIN users u ON p.user_id = u.user_id
@@ -30,6 +35,12 @@ const (
 		LEFT JOIN post_categories pc ON p.post_id = pc.post_id
 		LEFT JOIN categories c ON pc.category_id = c.category_id`
 
+	// Bookmarked posts filtering JOIN -
+	BookmarkedPostsJoin = `JOIN bookmarks b ON p.post_id = b.post_id
+		JOIN users u ON p.user_id = u.user_id
+		LEFT JOIN post_categories pc ON p.post_id = pc.post_id
+		LEFT JOIN categories c ON pc.category_id = c.category_id`
+
 	// Commented posts filtering JOIN -
 	CommentedPostsJoin = `JOIN comments com ON p.post_id = com.post_id
 		JOIN users u ON p.user_id = u.user_id
@@ -52,12 +63,16 @@ const (
 		LEFT JOIN (
 			SELECT post_id, COUNT(*) as count
 			FROM comments
+			WHERE deleted_at IS NULL
 			GROUP BY post_id
 		) comment_counts ON p.post_id = comment_counts.post_id`
 
 	// User reaction JOIN - UPDATED to use post_reactions table
 	UserReactionJoin = `LEFT JOIN post_reactions ur ON p.post_id = ur.post_id AND ur.user_id = ?`
 
+	// User bookmark JOIN - takes the current user ID right after UserReactionJoin's
+	UserBookmarkJoin = `LEFT JOIN bookmarks ub ON p.post_id = ub.post_id AND ub.user_id = ?`
+
 	// Common clauses -
 	GroupByPost          = `GROUP BY p.post_id`
 	OrderByCreated       = `ORDER BY p.created_at DESC`
@@ -65,8 +80,12 @@ const (
 	OrderByCommentedDate = `ORDER BY MAX(com.created_at) DESC`
 	LimitOffset          = `LIMIT ? OFFSET ?`
 
-	// Base WHERE clause for dynamic filtering -
-	BaseWhere = `WHERE 1=1`
+	// Published posts whose scheduled time (if any) has passed.
+	// julianday() compares the stored timestamps correctly whatever their UTC offset.
+	PublishedCondition = `p.status = 'published' AND (p.publish_at IS NULL OR julianday(p.publish_at) <= julianday('now'))`
+
+	// Base WHERE clause for dynamic filtering - soft-deleted, draft and scheduled posts are always excluded
+	BaseWhere = `WHERE p.deleted_at IS NULL AND ` + PublishedCondition
 )
 
 // Static queries -
@@ -76,7 +95,8 @@ var (
 		` + BaseJoins + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
-		WHERE p.post_id = ?
+		` + UserBookmarkJoin + `
+		WHERE p.post_id = ? AND p.deleted_at IS NULL AND (` + PublishedCondition + ` OR p.user_id = ?)
 		` + GroupByPost
 
 	GetAllPostsQuery = `SELECT ` + BaseSelectFields + `
@@ -84,6 +104,8 @@ var (
 		` + BaseJoins + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
+		` + UserBookmarkJoin + `
+		` + BaseWhere + `
 		` + GroupByPost + `
 		` + OrderByCreated + `
 		` + LimitOffset
@@ -93,7 +115,8 @@ var (
 		` + CategoryFilterJoin + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
-		WHERE pc.category_id = ?
+		` + UserBookmarkJoin + `
+		WHERE pc.category_id = ? AND p.deleted_at IS NULL AND ` + PublishedCondition + `
 		` + GroupByPost + `
 		` + OrderByCreated + `
 		` + LimitOffset
@@ -103,7 +126,8 @@ var (
 		` + BaseJoins + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
-		WHERE p.user_id = ?
+		` + UserBookmarkJoin + `
+		WHERE p.user_id = ? AND p.deleted_at IS NULL AND ` + PublishedCondition + `
 		` + GroupByPost + `
 		` + OrderByCreated + `
 		` + LimitOffset
@@ -113,7 +137,8 @@ var (
 		` + LikedPostsJoin + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
-		WHERE r.user_id = ? AND r.reaction_type = 1
+		` + UserBookmarkJoin + `
+		WHERE r.user_id = ? AND r.reaction_type = 1 AND p.deleted_at IS NULL AND ` + PublishedCondition + `
 		` + GroupByPost + `
 		` + OrderByLikedDate + `
 		` + LimitOffset
@@ -123,7 +148,8 @@ var (
 		` + CommentedPostsJoin + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
-		WHERE com.user_id = ?
+		` + UserBookmarkJoin + `
+		WHERE com.user_id = ? AND com.deleted_at IS NULL AND p.deleted_at IS NULL AND ` + PublishedCondition + `
 		` + GroupByPost + `
 		` + OrderByCommentedDate + `
 		` + LimitOffset
@@ -138,6 +164,7 @@ func BuildPostsQuery(joins, whereClause, orderClause string) string {
 		` + joins + `
 		` + ReactionCountJoins + `
 		` + UserReactionJoin + `
+		` + UserBookmarkJoin + `
 		` + whereClause + `
 		` + GroupByPost + `
 		` + orderClause + `
@@ -145,30 +172,43 @@ func BuildPostsQuery(joins, whereClause, orderClause string) string {
 }
 
 // GetAllPostsWithSortQuery returns a dynamic query for all posts with custom sorting
-func GetAllPostsWithSortQuery(orderClause string) string {
-	return BuildPostsQuery(BaseJoins, BaseWhere, orderClause)
+// keysetClause is an optional " AND (...)" cursor condition (see utils.BuildKeysetClause)
+func GetAllPostsWithSortQuery(orderClause, keysetClause string) string {
+	return BuildPostsQuery(BaseJoins, BaseWhere+keysetClause, orderClause)
 }
 
 // GetPostsByCategoryWithSortQuery returns a dynamic query for category posts with custom sorting
-func GetPostsByCategoryWithSortQuery(orderClause string) string {
-	whereClause := `WHERE pc.category_id = ?`
-	return BuildPostsQuery(CategoryFilterJoin, whereClause, orderClause)
+func GetPostsByCategoryWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE pc.category_id = ? AND p.deleted_at IS NULL AND ` + PublishedCondition
+	return BuildPostsQuery(CategoryFilterJoin, whereClause+keysetClause, orderClause)
 }
 
 // GetPostsByUserWithSortQuery returns a dynamic query for user posts with custom sorting
-func GetPostsByUserWithSortQuery(orderClause string) string {
-	whereClause := `WHERE p.user_id = ?`
-	return BuildPostsQuery(BaseJoins, whereClause, orderClause)
+func GetPostsByUserWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE p.user_id = ? AND p.deleted_at IS NULL AND ` + PublishedCondition
+	return BuildPostsQuery(BaseJoins, whereClause+keysetClause, orderClause)
 }
 
 // GetPostsLikedByUserWithSortQuery returns a dynamic query for liked posts with custom sorting
-func GetPostsLikedByUserWithSortQuery(orderClause string) string {
-	whereClause := `WHERE r.user_id = ? AND r.reaction_type = 1`
-	return BuildPostsQuery(LikedPostsJoin, whereClause, orderClause)
+func GetPostsLikedByUserWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE r.user_id = ? AND r.reaction_type = 1 AND p.deleted_at IS NULL AND ` + PublishedCondition
+	return BuildPostsQuery(LikedPostsJoin, whereClause+keysetClause, orderClause)
+}
+
+// GetPostsBookmarkedByUserWithSortQuery returns a dynamic query for bookmarked posts with custom sorting
+func GetPostsBookmarkedByUserWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE b.user_id = ? AND p.deleted_at IS NULL AND ` + PublishedCondition
+	return BuildPostsQuery(BookmarkedPostsJoin, whereClause+keysetClause, orderClause)
 }
 
 // GetPostsCommentedByUserWithSortQuery returns a dynamic query for commented posts with custom sorting
-func GetPostsCommentedByUserWithSortQuery(orderClause string) string {
-	whereClause := `WHERE com.user_id = ?`
-	return BuildPostsQuery(CommentedPostsJoin, whereClause, orderClause)
+func GetPostsCommentedByUserWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE com.user_id = ? AND com.deleted_at IS NULL AND p.deleted_at IS NULL AND ` + PublishedCondition
+	return BuildPostsQuery(CommentedPostsJoin, whereClause+keysetClause, orderClause)
+}
+
+// GetDraftsByUserWithSortQuery returns a dynamic query for the user's drafts and not yet published scheduled posts
+func GetDraftsByUserWithSortQuery(orderClause, keysetClause string) string {
+	whereClause := `WHERE p.user_id = ? AND p.deleted_at IS NULL AND NOT (` + PublishedCondition + `)`
+	return BuildPostsQuery(BaseJoins, whereClause+keysetClause, orderClause)
 }
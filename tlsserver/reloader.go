@@ -0,0 +1,112 @@
+// Package tlsserver serves HTTPS directly from the backend and frontend servers: certificates are
+// reloaded when the files change, HTTP/2 is negotiated via ALPN and plain HTTP can be redirected
+// to HTTPS.
+//
+// It is a module of its own, required by both server/ and client/ through a replace directive,
+// so the frontend can share it without depending on the backend module (SQLite and cgo).
+package tlsserver
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader holds the current certificate and swaps it when the files on disk change,
+// so renewed certificates (certbot, cert-manager, ...) are picked up without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewCertReloader loads the certificate and key; it fails if they cannot be used
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.certificate, nil
+}
+
+// Watch checks the files every interval and reloads them after a change. A broken pair (e.g.
+// the certificate was replaced but the key not yet) is logged and the old certificate kept.
+func (cr *CertReloader) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed, err := cr.changed()
+		if err != nil {
+			log.Printf("TLS certificate check failed: %v", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		if err := cr.reload(); err != nil {
+			log.Printf("TLS certificate reload failed, keeping the current one: %v", err)
+			continue
+		}
+		log.Printf("Reloaded TLS certificate from %s", cr.certFile)
+	}
+}
+
+// changed reports whether either file was modified since the last successful load
+func (cr *CertReloader) changed() (bool, error) {
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		return false, err
+	}
+
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return !certModTime.Equal(cr.certModTime) || !keyModTime.Equal(cr.keyModTime), nil
+}
+
+// reload reads the certificate and key and makes them current
+func (cr *CertReloader) reload() error {
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	cr.certificate = &certificate
+	cr.certModTime = certModTime
+	cr.keyModTime = keyModTime
+	return nil
+}
+
+// modTimes returns the modification times of the certificate and key files
+func (cr *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}